@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// CreateAccessRequestHandler records the calling user's request to join a group
+func (api *API) CreateAccessRequestHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+	userID := callerID(ctx)
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	createRequest := models.CreateAccessRequest{}
+	err = json.Unmarshal(body, &createRequest)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+
+	validationErrs := createRequest.Validate(ctx)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	accessRequest := createRequest.NewAccessRequest(uuid.NewString(), group.ID, userID, time.Now().UTC())
+	if err = api.AccessRequests.CreateAccessRequest(ctx, accessRequest); err != nil {
+		return nil, handleAccessRequestsError(ctx, err)
+	}
+
+	jsonResponse, responseErr := accessRequest.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusCreated, nil), nil
+}
+
+// ListAccessRequestsHandler lists a group's access requests, optionally only those with the status in the query
+func (api *API) ListAccessRequestsHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "", models.AccessRequestPending, models.AccessRequestApproved, models.AccessRequestRejected:
+	default:
+		responseErr := models.NewValidationError(ctx, models.InvalidFilterQuery, models.InvalidFilterQueryDescription)
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+	}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	requests, err := api.AccessRequests.ListAccessRequests(ctx, group.ID, status)
+	if err != nil {
+		return nil, handleAccessRequestsError(ctx, err)
+	}
+
+	requestsList := models.AccessRequestsList{AccessRequests: requests}
+	jsonResponse, responseErr := requestsList.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
+
+// DecideAccessRequestHandler approves or rejects a pending access request, adding the user to the group on approval
+func (api *API) DecideAccessRequestHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+	requestID := vars["request_id"]
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	decision := models.AccessRequestDecision{}
+	err = json.Unmarshal(body, &decision)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+
+	validationErrs := decision.Validate(ctx)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+	decision.DecidedBy = callerID(ctx)
+	decision.Decided = time.Now().UTC()
+
+	// the decision is recorded before it is carried out, so that only one decision on the request is ever carried out
+	accessRequest, err := api.AccessRequests.DecideAccessRequest(ctx, group.ID, requestID, decision)
+	if err != nil {
+		return nil, handleAccessRequestsError(ctx, err)
+	}
+
+	if decision.Status == models.AccessRequestApproved {
+		_, err = api.AddUserToGroup(ctx, group, accessRequest.UserID)
+		if err != nil {
+			api.reopenAccessRequest(ctx, group, requestID)
+			cognitoErr := models.NewCognitoError(ctx, err, "Cognito AddUserToGroup request from decide access request endpoint")
+			if cognitoErr.Code == models.UserNotFoundError || cognitoErr.Code == models.NotFoundError {
+				return nil, models.NewErrorResponse(http.StatusBadRequest, nil, cognitoErr)
+			}
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+		}
+		// an approved membership is permanent, even if the user previously had a temporary one
+		api.clearMembershipExpiry(ctx, group, accessRequest.UserID)
+	}
+
+	dplogs.Info(ctx, "access request decided", dplogs.Data{
+		"group_id":   group.ID,
+		"request_id": requestID,
+		"user_id":    accessRequest.UserID,
+		"status":     accessRequest.Status,
+		"decided_by": accessRequest.DecidedBy,
+	})
+
+	jsonResponse, responseErr := accessRequest.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
+
+// reopenAccessRequest returns an approved access request to pending when the user could not be added to the group, so
+// that it can be decided again. The request has already failed, so a failure is only logged.
+func (api *API) reopenAccessRequest(ctx context.Context, group models.Group, requestID string) {
+	if err := api.AccessRequests.ReopenAccessRequest(ctx, group.ID, requestID); err != nil {
+		dplogs.Error(ctx, "failed to reopen access request", err, dplogs.Data{"group_id": group.ID, "request_id": requestID})
+	}
+}
+
+func handleAccessRequestsError(ctx context.Context, err error) *models.ErrorResponse {
+	switch {
+	case errors.Is(err, store.ErrAccessRequestNotFound):
+		responseErr := models.NewValidationError(ctx, models.NotFoundError, models.AccessRequestNotFoundDescription)
+		return models.NewErrorResponse(http.StatusNotFound, nil, responseErr)
+	case errors.Is(err, store.ErrAccessRequestDecided):
+		responseErr := models.NewValidationError(ctx, models.AccessRequestDecidedError, models.AccessRequestDecidedDescription)
+		return models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+	case errors.Is(err, store.ErrAccessRequestExists):
+		responseErr := models.NewValidationError(ctx, models.AccessRequestExistsError, models.AccessRequestExistsDescription)
+		return models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+	default:
+		return models.NewErrorResponse(http.StatusInternalServerError,
+			nil,
+			models.NewError(ctx, err, models.InternalError, models.AccessRequestsFailedDescription),
+		)
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorisation "github.com/ONSdigital/dp-authorisation/v2/authorisation/mock"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	permsdk "github.com/ONSdigital/dp-permissions-api/sdk"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const accessRequestsEndPoint = "http://localhost:25600/v1/groups/efgh5678/access-requests"
+
+func TestIdentifyCaller(t *testing.T) {
+	Convey("Given a handler that needs to know who is calling it", t, func() {
+		auth := &authorisation.MiddlewareMock{
+			ParseFunc: func(token string) (*permsdk.EntityData, error) {
+				return &permsdk.EntityData{UserID: strings.TrimSuffix(token, ".jwt")}, nil
+			},
+		}
+		handler := identifyCaller(auth, func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+			return models.NewSuccessResponse([]byte(callerID(ctx)), http.StatusOK, nil), nil
+		})
+
+		callHandler := func(authToken string) (*models.SuccessResponse, *models.ErrorResponse) {
+			r := httptest.NewRequest(http.MethodPost, accessRequestsEndPoint, http.NoBody)
+			r.Header.Set(AccessTokenHeaderName, authToken)
+			return handler(ctx, httptest.NewRecorder(), r)
+		}
+
+		Convey("A user's ID is passed to the handler", func() {
+			successResponse, errorResponse := callHandler("Bearer abcd1234.jwt")
+			So(errorResponse, ShouldBeNil)
+			So(string(successResponse.Body), ShouldEqual, "abcd1234")
+		})
+
+		Convey("A caller without a user JWT is refused", func() {
+			successResponse, errorResponse := callHandler("Bearer service-token")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusUnauthorized)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.InvalidCallerDescription)
+		})
+	})
+}
+
+func TestIdentifyCallerOrService(t *testing.T) {
+	Convey("Given a handler that records who made a change", t, func() {
+		auth := &authorisation.MiddlewareMock{
+			ParseFunc: func(token string) (*permsdk.EntityData, error) {
+				return &permsdk.EntityData{UserID: strings.TrimSuffix(token, ".jwt")}, nil
+			},
+		}
+		handler := identifyCallerOrService(auth, func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+			return models.NewSuccessResponse([]byte(callerID(ctx)), http.StatusOK, nil), nil
+		})
+
+		callHandler := func(authToken string) (*models.SuccessResponse, *models.ErrorResponse) {
+			r := httptest.NewRequest(http.MethodPut, accessRequestsEndPoint+"/request-1", http.NoBody)
+			r.Header.Set(AccessTokenHeaderName, authToken)
+			return handler(ctx, httptest.NewRecorder(), r)
+		}
+
+		Convey("A user's ID is passed to the handler", func() {
+			successResponse, errorResponse := callHandler("Bearer abcd1234.jwt")
+			So(errorResponse, ShouldBeNil)
+			So(string(successResponse.Body), ShouldEqual, "abcd1234")
+		})
+
+		Convey("A caller with a service token is identified as a service", func() {
+			successResponse, errorResponse := callHandler("Bearer service-token")
+			So(errorResponse, ShouldBeNil)
+			So(string(successResponse.Body), ShouldEqual, ServiceCallerID)
+		})
+	})
+}
+
+func TestAccessRequestHandlers(t *testing.T) {
+	Convey("Given a group", t, func() {
+		api, w, m := apiMockSetup()
+		members := map[string]bool{}
+		m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			if *input.GroupName != "efgh5678" {
+				return nil, &types.ResourceNotFoundException{Message: aws.String("Group not found.")}
+			}
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName}}, nil
+		}
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			members[*input.Username] = true
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			users := []types.UserType{}
+			for member := range members {
+				users = append(users, types.UserType{Username: aws.String(member), Enabled: true, UserStatus: types.UserStatusTypeConfirmed})
+			}
+			return &cognitoidentityprovider.ListUsersInGroupOutput{Users: users}, nil
+		}
+
+		requestAccess := func(groupID, userID, justification string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]string{"justification": justification})
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, accessRequestsEndPoint, bytes.NewReader(body)), map[string]string{"id": groupID})
+			return api.CreateAccessRequestHandler(context.WithValue(ctx, callerIDKey{}, userID), w, r)
+		}
+		listRequests := func(query string) (*models.SuccessResponse, *models.ErrorResponse) {
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, accessRequestsEndPoint+query, http.NoBody), map[string]string{"id": "efgh5678"})
+			return api.ListAccessRequestsHandler(ctx, w, r)
+		}
+		decide := func(requestID, status string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]string{"status": status, "note": "Checked with their manager"})
+			r := httptest.NewRequest(http.MethodPut, accessRequestsEndPoint+"/"+requestID, bytes.NewReader(body))
+			r = mux.SetURLVars(r, map[string]string{"id": "efgh5678", "request_id": requestID})
+			return api.DecideAccessRequestHandler(context.WithValue(ctx, callerIDKey{}, "owner-user"), w, r)
+		}
+
+		Convey("When a user asks to join it", func() {
+			successResponse, errorResponse := requestAccess("efgh5678", "abcd1234", "I publish the census datasets")
+			So(errorResponse, ShouldBeNil)
+			So(successResponse.Status, ShouldEqual, http.StatusCreated)
+
+			var accessRequest models.AccessRequest
+			So(json.Unmarshal(successResponse.Body, &accessRequest), ShouldBeNil)
+
+			Convey("Then a pending request is recorded for the caller", func() {
+				So(accessRequest.ID, ShouldNotBeEmpty)
+				So(accessRequest.UserID, ShouldEqual, "abcd1234")
+				So(accessRequest.Status, ShouldEqual, models.AccessRequestPending)
+
+				successResponse, errorResponse := listRequests("?status=pending")
+				So(errorResponse, ShouldBeNil)
+				var requestsList models.AccessRequestsList
+				So(json.Unmarshal(successResponse.Body, &requestsList), ShouldBeNil)
+				So(requestsList.Count, ShouldEqual, 1)
+				So(requestsList.AccessRequests[0].ID, ShouldEqual, accessRequest.ID)
+			})
+
+			Convey("Then asking again while it is pending is refused", func() {
+				successResponse, errorResponse := requestAccess("efgh5678", "abcd1234", "Still waiting")
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+				So(errorResponse.Errors[0].(*models.Error).Code, ShouldEqual, models.AccessRequestExistsError)
+			})
+
+			Convey("Then approving it adds the user to the group and records the decision", func() {
+				successResponse, errorResponse := decide(accessRequest.ID, models.AccessRequestApproved)
+				So(errorResponse, ShouldBeNil)
+				So(members["abcd1234"], ShouldBeTrue)
+
+				var decided models.AccessRequest
+				So(json.Unmarshal(successResponse.Body, &decided), ShouldBeNil)
+				So(decided.Status, ShouldEqual, models.AccessRequestApproved)
+				So(decided.DecidedBy, ShouldEqual, "owner-user")
+				So(decided.DecisionNote, ShouldEqual, "Checked with their manager")
+				So(decided.Decided, ShouldNotBeNil)
+
+				Convey("And it cannot be decided again", func() {
+					successResponse, errorResponse := decide(accessRequest.ID, models.AccessRequestRejected)
+					So(successResponse, ShouldBeNil)
+					So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+					So(errorResponse.Errors[0].(*models.Error).Code, ShouldEqual, models.AccessRequestDecidedError)
+				})
+			})
+
+			Convey("Then approving it when the user cannot be added leaves it pending", func() {
+				m.AdminAddUserToGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+					return nil, &types.InternalErrorException{Message: aws.String("Something went wrong")}
+				}
+				successResponse, errorResponse := decide(accessRequest.ID, models.AccessRequestApproved)
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+
+				stored, err := api.AccessRequests.GetAccessRequest(ctx, "efgh5678", accessRequest.ID)
+				So(err, ShouldBeNil)
+				So(stored, ShouldResemble, accessRequest)
+			})
+
+			Convey("Then a rejection made while the approval is being carried out is refused", func() {
+				m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+					_, errorResponse := decide(accessRequest.ID, models.AccessRequestRejected)
+					So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+					members[*input.Username] = true
+					return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+				}
+				_, errorResponse := decide(accessRequest.ID, models.AccessRequestApproved)
+				So(errorResponse, ShouldBeNil)
+
+				stored, err := api.AccessRequests.GetAccessRequest(ctx, "efgh5678", accessRequest.ID)
+				So(err, ShouldBeNil)
+				So(stored.Status, ShouldEqual, models.AccessRequestApproved)
+			})
+
+			Convey("Then rejecting it leaves the user out of the group", func() {
+				_, errorResponse := decide(accessRequest.ID, models.AccessRequestRejected)
+				So(errorResponse, ShouldBeNil)
+				So(members["abcd1234"], ShouldBeFalse)
+
+				successResponse, errorResponse := listRequests("?status=rejected")
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldContainSubstring, `"count":1`)
+			})
+
+			Convey("Then the request is removed when the group is deleted", func() {
+				m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+					return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+				}
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, updateGroupEndPoint, http.NoBody), map[string]string{"id": "efgh5678"})
+				_, errorResponse := api.DeleteGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				requests, err := api.AccessRequests.ListAccessRequests(ctx, "efgh5678", "")
+				So(err, ShouldBeNil)
+				So(requests, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a user asks to join without a justification a 400 is returned", func() {
+			successResponse, errorResponse := requestAccess("efgh5678", "abcd1234", " ")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.MissingJustificationDescription)
+		})
+
+		Convey("When a user asks to join a group that does not exist a 404 is returned", func() {
+			successResponse, errorResponse := requestAccess("missing-group", "abcd1234", "I publish the census datasets")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("When requests are listed with an unknown status a 400 is returned", func() {
+			successResponse, errorResponse := listRequests("?status=cancelled")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("When a request that does not exist is decided a 404 is returned", func() {
+			successResponse, errorResponse := decide("missing-request", models.AccessRequestApproved)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.AccessRequestNotFoundDescription)
+		})
+	})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// AntiEnumeration is a hardened mode hiding whether an account exists from the sign in and password reset responses
+type AntiEnumeration struct {
+	Enabled bool
+	// MinResponseTime pads responses so that their timing does not reveal which path a request took
+	MinResponseTime time.Duration
+}
+
+// padResponseTime waits until at least the minimum response time has passed since start, when anti-enumeration
+// mode is enabled. It is intended to be deferred at the start of a handler.
+func (api *API) padResponseTime(ctx context.Context, start time.Time) {
+	if !api.AntiEnumeration.Enabled {
+		return
+	}
+
+	remaining := api.AntiEnumeration.MinResponseTime - time.Since(start)
+	if remaining <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
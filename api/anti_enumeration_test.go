@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/smithy-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingAuditor struct {
+	events []AuditEvent
+}
+
+func (a *recordingAuditor) Audit(_ context.Context, event AuditEvent) {
+	a.events = append(a.events, event)
+}
+
+func TestAntiEnumeration_TokensHandler(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given anti-enumeration mode is enabled", t, func() {
+		api, w, m := apiMockSetup()
+		auditor := &recordingAuditor{}
+		api.Auditor = auditor
+		api.AntiEnumeration = AntiEnumeration{Enabled: true}
+
+		signInFailures := []struct {
+			description string
+			cognitoErr  *smithy.GenericAPIError
+		}{
+			{"incorrect password", &smithy.GenericAPIError{Code: "NotAuthorizedException", Message: models.SignInFailedDescription, Fault: clientError}},
+			{"too many attempts", &smithy.GenericAPIError{Code: "NotAuthorizedException", Message: models.SignInAttemptsExceededDescription, Fault: clientError}},
+			{"unknown user", &smithy.GenericAPIError{Code: "UserNotFoundException", Message: "User does not exist.", Fault: clientError}},
+			{"unconfirmed user", &smithy.GenericAPIError{Code: "UserNotConfirmedException", Message: "User is not confirmed.", Fault: clientError}},
+			{"password reset required", &smithy.GenericAPIError{Code: "PasswordResetRequiredException", Message: "Password reset required.", Fault: clientError}},
+		}
+
+		for _, tt := range signInFailures {
+			Convey("When sign in fails with "+tt.description, func() {
+				m.InitiateAuthFunc = func(_ context.Context, _ *cognitoidentityprovider.InitiateAuthInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+					return nil, tt.cognitoErr
+				}
+
+				body, _ := json.Marshal(map[string]interface{}{"email": "email@ons.gov.uk", "password": "password"})
+				r := httptest.NewRequest(http.MethodPost, signInEndPoint, bytes.NewReader(body))
+				successResponse, errorResponse := api.TokensHandler(ctx, w, r)
+
+				Convey("Then the response is the same as for an incorrect password", func() {
+					So(successResponse, ShouldBeNil)
+					So(errorResponse.Status, ShouldEqual, http.StatusUnauthorized)
+					So(errorResponse.Headers, ShouldContainKey, WWWAuthenticateName)
+					So(len(errorResponse.Errors), ShouldEqual, 1)
+					castErr := errorResponse.Errors[0].(*models.Error)
+					So(castErr.Code, ShouldEqual, models.NotAuthorisedError)
+					So(castErr.Description, ShouldEqual, models.SignInFailedDescription)
+				})
+
+				Convey("Then the real cause is audited", func() {
+					So(len(auditor.events), ShouldEqual, 1)
+					So(auditor.events[0].Action, ShouldEqual, AuditActionSignIn)
+					So(auditor.events[0].Email, ShouldEqual, "email@ons.gov.uk")
+					So(auditor.events[0].Outcome, ShouldEqual, AuditOutcomeFailure)
+					So(auditor.events[0].Cause, ShouldContainSubstring, tt.cognitoErr.Message)
+				})
+			})
+		}
+	})
+}
+
+func TestAntiEnumeration_PasswordResetHandler(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given anti-enumeration mode is enabled", t, func() {
+		api, w, m := apiMockSetup()
+		auditor := &recordingAuditor{}
+		api.Auditor = auditor
+		api.AntiEnumeration = AntiEnumeration{Enabled: true}
+
+		resetFailures := []struct {
+			description string
+			cognitoErr  *smithy.GenericAPIError
+		}{
+			{"unknown user", &smithy.GenericAPIError{Code: "UserNotFoundException", Message: "User does not exist.", Fault: clientError}},
+			{"limit exceeded", &smithy.GenericAPIError{Code: "LimitExceededException", Message: "Attempt limit exceeded.", Fault: clientError}},
+			{"internal error", &smithy.GenericAPIError{Code: "InternalErrorException", Message: "Something went wrong.", Fault: serverError}},
+		}
+
+		for _, tt := range resetFailures {
+			Convey("When the password reset fails with "+tt.description, func() {
+				m.ForgotPasswordFunc = func(_ context.Context, _ *cognitoidentityprovider.ForgotPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ForgotPasswordOutput, error) {
+					return nil, tt.cognitoErr
+				}
+
+				body, _ := json.Marshal(map[string]interface{}{"email": "email@ons.gov.uk"})
+				r := httptest.NewRequest(http.MethodPost, requestResetEndPoint, bytes.NewReader(body))
+				successResponse, errorResponse := api.PasswordResetHandler(ctx, w, r)
+
+				Convey("Then the request is accepted and the real cause is audited", func() {
+					So(errorResponse, ShouldBeNil)
+					So(successResponse.Status, ShouldEqual, http.StatusAccepted)
+					So(len(auditor.events), ShouldEqual, 1)
+					So(auditor.events[0].Action, ShouldEqual, AuditActionPasswordReset)
+					So(auditor.events[0].Outcome, ShouldEqual, AuditOutcomeFailure)
+					So(auditor.events[0].Cause, ShouldContainSubstring, tt.cognitoErr.Message)
+				})
+			})
+		}
+	})
+}
+
+func TestPadResponseTime(t *testing.T) {
+	ctx := context.Background()
+	minResponseTime := 50 * time.Millisecond
+
+	Convey("Responses are padded to the minimum response time in anti-enumeration mode", t, func() {
+		api := &API{AntiEnumeration: AntiEnumeration{Enabled: true, MinResponseTime: minResponseTime}}
+
+		start := time.Now()
+		api.padResponseTime(ctx, start)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, minResponseTime)
+	})
+
+	Convey("Responses are not padded when anti-enumeration mode is disabled", t, func() {
+		api := &API{AntiEnumeration: AntiEnumeration{MinResponseTime: time.Hour}}
+
+		start := time.Now()
+		api.padResponseTime(ctx, start)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+
+	Convey("Padding stops when the request context is cancelled", t, func() {
+		api := &API{AntiEnumeration: AntiEnumeration{Enabled: true, MinResponseTime: time.Hour}}
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		start := time.Now()
+		api.padResponseTime(cancelledCtx, start)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
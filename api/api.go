@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-identity-api/v2/cognito"
 	"github.com/ONSdigital/dp-identity-api/v2/jwks"
 	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/ONSdigital/dp-identity-api/v2/validation"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
 	"github.com/gorilla/mux"
 )
@@ -27,6 +30,7 @@ var (
 		3 * time.Second,
 		10 * time.Second,
 	}
+	DefaultGroupsReportConcurrency = 5
 )
 
 // API provides a struct to wrap the api around
@@ -42,6 +46,24 @@ type API struct {
 	APIRequestFilter    map[string]map[string]string
 	JWKSManager         jwks.Manager
 	BlockPlusAddressing bool
+	PasswordPolicy      *validation.PasswordPolicy
+	PasswordHistory     *store.PasswordHistory
+	RateLimitStore      store.RateLimitStore
+	RateLimits          RateLimits
+	IdempotencyStore    store.IdempotencyStore
+	IdempotencyWindow   time.Duration
+	// IdempotencyInProgressTTL is how long an idempotency key is held for a request that has not completed
+	IdempotencyInProgressTTL time.Duration
+	AntiEnumeration          AntiEnumeration
+	Auditor                  Auditor
+	GroupMetadata            store.GroupMetadataStore
+	GroupOwners              store.GroupOwnerStore
+	MembershipExpiry         store.MembershipExpiryStore
+	AccessRequests           store.AccessRequestStore
+	GroupHierarchy           store.GroupHierarchyStore
+	GroupNameHistory         store.GroupNameHistoryStore
+	// GroupsReportConcurrency is how many groups' members the groups report fetches at once
+	GroupsReportConcurrency int
 }
 
 type baseHandler func(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.SuccessResponse, *models.ErrorResponse)
@@ -93,16 +115,26 @@ func Setup(ctx context.Context,
 				"active=false": "status=\"Disabled\"",
 			},
 		},
-		JWKSManager: jwksManager,
+		JWKSManager:      jwksManager,
+		PasswordPolicy:   validation.DefaultPasswordPolicy(),
+		Auditor:          LogAuditor{},
+		GroupMetadata:    store.NewInMemoryGroupMetadataStore(),
+		GroupOwners:      store.NewInMemoryGroupOwnerStore(),
+		MembershipExpiry: store.NewInMemoryMembershipExpiryStore(),
+		AccessRequests:   store.NewInMemoryAccessRequestStore(),
+		GroupHierarchy:   store.NewInMemoryGroupHierarchyStore(),
+		GroupNameHistory: store.NewInMemoryGroupNameHistoryStore(),
+
+		GroupsReportConcurrency: DefaultGroupsReportConcurrency,
 	}
 
-	r.HandleFunc("/v1/tokens", contextAndErrors(api.TokensHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/tokens", contextAndErrors(api.rateLimited(api.TokensHandler))).Methods(http.MethodPost)
 	r.HandleFunc("/v1/tokens", auth.Require(UsersUpdatePermission, contextAndErrors(api.SignOutAllUsersHandler))).
 		Methods(http.MethodDelete)
 	// self used in paths rather than identifier as the identifier is JWT tokens passed in the request headers
 	r.HandleFunc("/v1/tokens/self", contextAndErrors(api.SignOutHandler)).Methods(http.MethodDelete)
 	r.HandleFunc("/v1/tokens/self", contextAndErrors(api.RefreshHandler)).Methods(http.MethodPut)
-	r.HandleFunc("/v1/users", auth.Require(UsersCreatePermission, contextAndErrors(api.CreateUserHandler))).
+	r.HandleFunc("/v1/users", auth.Require(UsersCreatePermission, contextAndErrors(api.idempotent(auth, api.CreateUserHandler)))).
 		Methods(http.MethodPost)
 	r.HandleFunc("/v1/users", auth.Require(UsersReadPermission, contextAndErrors(api.ListUsersHandler))).
 		Methods(http.MethodGet)
@@ -114,32 +146,64 @@ func Setup(ctx context.Context,
 		Methods(http.MethodPost)
 	r.HandleFunc("/v1/users/{id}/groups", auth.Require(UsersReadPermission, contextAndErrors(api.ListUserGroupsHandler))).
 		Methods(http.MethodGet)
+	// self used in paths rather than identifier as the identifier is the access token passed in the request headers
+	// registered before the {id} route so that the signed in user's verification is not treated as an admin update
+	r.HandleFunc("/v1/users/self/email", contextAndErrors(api.VerifyUserEmailHandler)).
+		Methods(http.MethodPut)
+	r.HandleFunc("/v1/users/{id}/email", auth.Require(UsersUpdatePermission, contextAndErrors(api.UpdateUserEmailHandler))).
+		Methods(http.MethodPut)
 	// self used in paths rather than identifier as the identifier is a Cognito Session string in change password requests
 	// the user id is not yet available from the previous responses
 	r.HandleFunc("/v1/users/self/password", contextAndErrors(api.ChangePasswordHandler)).
 		Methods(http.MethodPut)
-	r.HandleFunc("/v1/password-reset", contextAndErrors(api.PasswordResetHandler)).
+	r.HandleFunc("/v1/password-reset", contextAndErrors(api.rateLimited(api.PasswordResetHandler))).
 		Methods(http.MethodPost)
 	r.HandleFunc("/v1/groups", auth.Require(GroupsReadPermission, contextAndErrors(api.ListGroupsHandler))).
 		Methods(http.MethodGet)
-	r.HandleFunc("/v1/groups", auth.Require(GroupsCreatePermission, contextAndErrors(api.CreateGroupHandler))).
+	r.HandleFunc("/v1/groups", auth.Require(GroupsCreatePermission, contextAndErrors(api.idempotent(auth, api.CreateGroupHandler)))).
 		Methods(http.MethodPost)
 	r.HandleFunc("/v1/groups/{id}", auth.Require(GroupsReadPermission, contextAndErrors(api.GetGroupHandler))).
 		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups-precedence", auth.Require(GroupsReadPermission, contextAndErrors(api.GroupPrecedenceReportHandler))).
+		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups-precedence/renumber", auth.Require(GroupsEditPermission, contextAndErrors(api.RenumberGroupPrecedenceHandler))).
+		Methods(http.MethodPost)
 	r.HandleFunc("/v1/groups-report", auth.Require(UsersReadPermission, contextAndErrors(api.ListGroupsUsersHandler))).
 		Methods(http.MethodGet)
 	r.HandleFunc("/v1/groups/{id}", auth.Require(GroupsEditPermission, contextAndErrors(api.UpdateGroupHandler))).
 		Methods(http.MethodPut)
 	r.HandleFunc("/v1/groups/{id}", auth.Require(GroupsDeletePermission, contextAndErrors(api.DeleteGroupHandler))).
 		Methods(http.MethodDelete)
-	r.HandleFunc("/v1/groups/{id}/members", auth.Require(GroupsEditPermission, contextAndErrors(api.AddUserToGroupHandler))).
+	// group owners may change their own group's members without the groups edit permission
+	r.HandleFunc("/v1/groups/{id}/members", api.requireGroupOwnerOr(auth, GroupsEditPermission, contextAndErrors(api.AddUserToGroupHandler))).
 		Methods(http.MethodPost)
-	r.HandleFunc("/v1/groups/{id}/members", auth.Require(GroupsEditPermission, contextAndErrors(api.SetGroupUsersHandler))).
+	r.HandleFunc("/v1/groups/{id}/members", api.requireGroupOwnerOr(auth, GroupsEditPermission, contextAndErrors(api.SetGroupUsersHandler))).
 		Methods(http.MethodPut)
 	r.HandleFunc("/v1/groups/{id}/members", auth.Require(GroupsReadPermission, contextAndErrors(api.ListUsersInGroupHandler))).
 		Methods(http.MethodGet)
-	r.HandleFunc("/v1/groups/{id}/members/{user_id}", auth.Require(GroupsEditPermission, contextAndErrors(api.RemoveUserFromGroupHandler))).
+	r.HandleFunc("/v1/groups/{id}/members/{user_id}", api.requireGroupOwnerOr(auth, GroupsEditPermission, contextAndErrors(api.RemoveUserFromGroupHandler))).
+		Methods(http.MethodDelete)
+	r.HandleFunc("/v1/groups/{id}/owners", auth.Require(GroupsReadPermission, contextAndErrors(api.ListGroupOwnersHandler))).
+		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups/{id}/owners", auth.Require(GroupsEditPermission, contextAndErrors(api.AddGroupOwnerHandler))).
+		Methods(http.MethodPost)
+	r.HandleFunc("/v1/groups/{id}/owners/{user_id}", auth.Require(GroupsEditPermission, contextAndErrors(api.RemoveGroupOwnerHandler))).
 		Methods(http.MethodDelete)
+	r.HandleFunc("/v1/groups/{id}/renames", auth.Require(GroupsReadPermission, contextAndErrors(api.ListGroupRenamesHandler))).
+		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups/{id}/children", auth.Require(GroupsReadPermission, contextAndErrors(api.ListChildGroupsHandler))).
+		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups/{id}/children", auth.Require(GroupsEditPermission, contextAndErrors(api.AddChildGroupHandler))).
+		Methods(http.MethodPost)
+	r.HandleFunc("/v1/groups/{id}/children/{child_id}", auth.Require(GroupsEditPermission, contextAndErrors(api.RemoveChildGroupHandler))).
+		Methods(http.MethodDelete)
+	// any signed in user can ask to join a group, and the group's owners or admins decide
+	r.HandleFunc("/v1/groups/{id}/access-requests", contextAndErrors(identifyCaller(auth, api.CreateAccessRequestHandler))).
+		Methods(http.MethodPost)
+	r.HandleFunc("/v1/groups/{id}/access-requests", api.requireGroupOwnerOr(auth, GroupsEditPermission, contextAndErrors(api.ListAccessRequestsHandler))).
+		Methods(http.MethodGet)
+	r.HandleFunc("/v1/groups/{id}/access-requests/{request_id}", api.requireGroupOwnerOr(auth, GroupsEditPermission, contextAndErrors(identifyCallerOrService(auth, api.DecideAccessRequestHandler)))).
+		Methods(http.MethodPut)
 	r.HandleFunc("/v1/jwt-keys", contextAndErrors(api.CognitoPoolJWKSHandler)).
 		Methods(http.MethodGet)
 	return api, nil
@@ -156,11 +220,7 @@ func writeErrorResponse(ctx context.Context, w http.ResponseWriter, errorRespons
 		}
 	}
 	w.WriteHeader(errorResponse.Status)
-	if errorResponse.Status == http.StatusInternalServerError {
-		jsonResponse, err = json.Marshal(models.Error{Code: models.InternalError, Description: models.InternalErrorDescription})
-	} else {
-		jsonResponse, err = json.Marshal(errorResponse)
-	}
+	jsonResponse, err = errorResponseBody(errorResponse)
 	if err != nil {
 		responseErr := models.NewError(ctx, err, models.JSONMarshalError, models.ErrorMarshalFailedDescription)
 		http.Error(w, responseErr.Description, http.StatusInternalServerError)
@@ -175,6 +235,14 @@ func writeErrorResponse(ctx context.Context, w http.ResponseWriter, errorRespons
 	}
 }
 
+// errorResponseBody returns the body written for an error response, which hides the details of internal errors
+func errorResponseBody(errorResponse *models.ErrorResponse) ([]byte, error) {
+	if errorResponse.Status == http.StatusInternalServerError {
+		return json.Marshal(models.Error{Code: models.InternalError, Description: models.InternalErrorDescription})
+	}
+	return json.Marshal(errorResponse)
+}
+
 func writeSuccessResponse(ctx context.Context, w http.ResponseWriter, successResponse *models.SuccessResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	// process custom headers
@@ -185,6 +253,16 @@ func writeSuccessResponse(ctx context.Context, w http.ResponseWriter, successRes
 	}
 	w.WriteHeader(successResponse.Status)
 
+	if successResponse.Stream != nil {
+		// the status has already been sent, so a failure part way through aborts the connection rather than ending the
+		// response, so that the client cannot mistake what was written for the whole of it
+		if err := successResponse.Stream(w); err != nil {
+			models.NewError(ctx, err, models.WriteResponseError, models.WriteResponseFailedDescription)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
 	_, err := w.Write(successResponse.Body)
 	if err != nil {
 		responseErr := models.NewError(ctx, err, models.WriteResponseError, models.WriteResponseFailedDescription)
@@ -207,6 +285,20 @@ func handleBodyUnmarshalError(ctx context.Context, err error) *models.ErrorRespo
 	)
 }
 
+// parseBoolQuery returns the value of a boolean query parameter, false when it is not set
+func parseBoolQuery(ctx context.Context, req *http.Request, name string) (bool, *models.ErrorResponse) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		validationErr := models.NewValidationError(ctx, models.InvalidFilterQuery, models.InvalidFilterQueryDescription)
+		return false, models.NewErrorResponse(http.StatusBadRequest, nil, validationErr)
+	}
+	return parsed, nil
+}
+
 func initialiseRoleGroups(ctx context.Context, cognitoClient cognito.Client, userPoolID string) error {
 	adminGroup := models.NewAdminRoleGroup()
 	adminGroupCreateInput := adminGroup.BuildCreateGroupRequest(userPoolID)
@@ -58,6 +58,8 @@ func TestSetup(t *testing.T) {
 			So(hasRoute(api.Router, "/v1/users/{id}/groups", http.MethodGet), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/users/{id}", http.MethodPut), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/users/self/password", http.MethodPut), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/users/{id}/email", http.MethodPut), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/users/self/email", http.MethodPut), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/password-reset", http.MethodPost), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/groups", http.MethodGet), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/groups-report", http.MethodGet), ShouldBeTrue)
@@ -68,6 +70,18 @@ func TestSetup(t *testing.T) {
 			So(hasRoute(api.Router, "/v1/groups/{id}/members", http.MethodGet), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/groups/{id}/members", http.MethodPut), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/groups/{id}/members/{user_id}", http.MethodDelete), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/owners", http.MethodGet), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/owners", http.MethodPost), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/owners/{user_id}", http.MethodDelete), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/access-requests", http.MethodPost), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/access-requests", http.MethodGet), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/access-requests/{request_id}", http.MethodPut), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/children", http.MethodGet), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/children", http.MethodPost), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/children/{child_id}", http.MethodDelete), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups-precedence", http.MethodGet), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups/{id}/renames", http.MethodGet), ShouldBeTrue)
+			So(hasRoute(api.Router, "/v1/groups-precedence/renumber", http.MethodPost), ShouldBeTrue)
 			So(hasRoute(api.Router, "/v1/jwt-keys", http.MethodGet), ShouldBeTrue)
 		})
 
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// AuditEvent records the outcome of a security sensitive request, including detail that may be hidden from the client
+type AuditEvent struct {
+	Action   string
+	Email    string
+	ClientIP string
+	Outcome  string
+	Cause    string
+}
+
+const (
+	AuditActionSignIn        = "sign_in"
+	AuditActionPasswordReset = "password_reset"
+	AuditOutcomeSuccess      = "success"
+	AuditOutcomeFailure      = "failure"
+)
+
+// Auditor writes audit events to the audit trail
+type Auditor interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// LogAuditor is an Auditor writing audit events to the service log
+type LogAuditor struct{}
+
+// Audit writes the event to the service log
+func (LogAuditor) Audit(ctx context.Context, event AuditEvent) {
+	log.Info(ctx, "audit event", log.Data{
+		"action":    event.Action,
+		"email":     event.Email,
+		"client_ip": event.ClientIP,
+		"outcome":   event.Outcome,
+		"cause":     event.Cause,
+	})
+}
+
+// audit sends an event to the API's auditor, when one is set
+func (api *API) audit(ctx context.Context, event AuditEvent) {
+	if api.Auditor != nil {
+		api.Auditor.Audit(ctx, event)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+type callerIDKey struct{}
+
+// ServiceCallerID identifies a caller authorised with a service token, which does not belong to a user
+const ServiceCallerID = "service"
+
+// parseCallerID returns the user ID from the JWT in the request's Authorization header, verified by the authorisation
+// middleware. It returns an empty string when there is no valid JWT, including for service tokens.
+func parseCallerID(req *http.Request, auth authorisation.Middleware) string {
+	authToken := strings.TrimPrefix(req.Header.Get(AccessTokenHeaderName), "Bearer ")
+	if !strings.Contains(authToken, ".") {
+		return ""
+	}
+
+	entityData, err := auth.Parse(authToken)
+	if err != nil {
+		return ""
+	}
+	return entityData.UserID
+}
+
+// identifyCaller wraps a handler that needs to know which user is calling it, refusing requests without a valid JWT.
+// The handler gets the caller's user ID from its context with callerID.
+func identifyCaller(auth authorisation.Middleware, handler baseHandler) baseHandler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+		userID := parseCallerID(req, auth)
+		if userID == "" {
+			responseErr := models.NewValidationError(ctx, models.InvalidTokenError, models.InvalidCallerDescription)
+			return nil, models.NewErrorResponse(http.StatusUnauthorized, nil, responseErr)
+		}
+		return handler(context.WithValue(ctx, callerIDKey{}, userID), w, req)
+	}
+}
+
+// identifyCallerOrService wraps a handler that has already been authorised by auth.Require, identifying the calling
+// user from their JWT or, when there is none, identifying a service token with ServiceCallerID
+func identifyCallerOrService(auth authorisation.Middleware, handler baseHandler) baseHandler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+		userID := parseCallerID(req, auth)
+		if userID == "" {
+			userID = ServiceCallerID
+		}
+		return handler(context.WithValue(ctx, callerIDKey{}, userID), w, req)
+	}
+}
+
+// callerID returns the user ID of the caller identified by identifyCaller or identifyCallerOrService
+func callerID(ctx context.Context) string {
+	userID, _ := ctx.Value(callerIDKey{}).(string)
+	return userID
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+var (
+	ETagHeaderName    = "ETag"
+	IfMatchHeaderName = "If-Match"
+)
+
+// newETag returns a strong entity tag for a representation of a resource
+func newETag(representation []byte) string {
+	sum := sha256.Sum256(representation)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// membershipETag returns the entity tag for a group's membership. It depends only on which users are members, so
+// the order Cognito lists them in, or the order they are sorted into for the response, does not change it.
+func membershipETag(users []models.UserParams) string {
+	userIDs := make([]string, 0, len(users))
+	for i := range users {
+		userIDs = append(userIDs, users[i].ID)
+	}
+	sort.Strings(userIDs)
+	return newETag([]byte(strings.Join(userIDs, "\n")))
+}
+
+// etagHeaders returns the response headers for a resource's entity tag
+func etagHeaders(etag string) map[string]string {
+	return map[string]string{ETagHeaderName: etag}
+}
+
+// hasIfMatch reports whether the request is conditional on the resource being unchanged
+func hasIfMatch(req *http.Request) bool {
+	return req.Header.Get(IfMatchHeaderName) != ""
+}
+
+// ifMatch reports whether the resource's current entity tag satisfies the request's If-Match header. Requests
+// without the header are unconditional. Weak tags never match, as If-Match requires strong comparison.
+//
+// The check is best-effort. Cognito has no conditional updates, so the resource is read, compared and then updated in
+// separate requests, and a change made by another request in between is not detected. It stops an update based on a
+// stale read, not two updates racing each other.
+func ifMatch(req *http.Request, currentETag string) bool {
+	if !hasIfMatch(req) {
+		return true
+	}
+	for _, etag := range strings.Split(strings.Join(req.Header.Values(IfMatchHeaderName), ","), ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" || etag == currentETag {
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionFailed is the response to a request whose If-Match header no longer matches the resource
+func preconditionFailed(ctx context.Context) *models.ErrorResponse {
+	responseErr := models.NewValidationError(ctx, models.PreconditionFailedError, models.ResourceModifiedDescription)
+	return models.NewErrorResponse(http.StatusPreconditionFailed, nil, responseErr)
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIfMatch(t *testing.T) {
+	Convey("The If-Match header is compared with the current entity tag", t, func() {
+		currentETag := newETag([]byte("current"))
+
+		ifMatchTests := []struct {
+			ifMatch  []string
+			expected bool
+		}{
+			{nil, true},
+			{[]string{currentETag}, true},
+			{[]string{"*"}, true},
+			{[]string{`"stale", ` + currentETag}, true},
+			{[]string{`"stale"`, currentETag}, true},
+			{[]string{`"stale"`}, false},
+			{[]string{"W/" + currentETag}, false},
+		}
+
+		for _, tt := range ifMatchTests {
+			r := httptest.NewRequest(http.MethodPut, userEndPoint, http.NoBody)
+			for _, etag := range tt.ifMatch {
+				r.Header.Add(IfMatchHeaderName, etag)
+			}
+			So(ifMatch(r, currentETag), ShouldEqual, tt.expected)
+		}
+	})
+}
+
+func TestMembershipETag(t *testing.T) {
+	Convey("The membership entity tag depends on the members and not their order", t, func() {
+		etag := membershipETag([]models.UserParams{{ID: "user-1"}, {ID: "user-2"}})
+
+		So(membershipETag([]models.UserParams{{ID: "user-2"}, {ID: "user-1"}}), ShouldEqual, etag)
+		So(membershipETag([]models.UserParams{{ID: "user-1"}}), ShouldNotEqual, etag)
+	})
+}
+
+func TestUpdateUserHandler_IfMatch(t *testing.T) {
+	Convey("Given a user that has been read", t, func() {
+		api, w, m := apiMockSetup()
+		forename := "Bob"
+		m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			return &cognitoidentityprovider.AdminGetUserOutput{
+				UserAttributes: []types.AttributeType{
+					{Name: aws.String("given_name"), Value: aws.String(forename)},
+					{Name: aws.String("family_name"), Value: aws.String("Smith")},
+					{Name: aws.String("email"), Value: aws.String("email@ons.gov.uk")},
+				},
+				Enabled:    true,
+				UserStatus: types.UserStatusTypeConfirmed,
+				Username:   aws.String("abcd1234"),
+			}, nil
+		}
+		m.AdminEnableUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminEnableUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminEnableUserOutput, error) {
+			return &cognitoidentityprovider.AdminEnableUserOutput{}, nil
+		}
+		updated := false
+		m.AdminUpdateUserAttributesFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminUpdateUserAttributesInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminUpdateUserAttributesOutput, error) {
+			updated = true
+			return &cognitoidentityprovider.AdminUpdateUserAttributesOutput{}, nil
+		}
+
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, userEndPoint, http.NoBody), map[string]string{"id": "abcd1234"})
+		getResponse, errorResponse := api.GetUserHandler(ctx, w, r)
+		So(errorResponse, ShouldBeNil)
+		etag := getResponse.Headers[ETagHeaderName]
+		So(etag, ShouldNotBeEmpty)
+
+		updateUser := func(ifMatchETag string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]interface{}{"forename": "Robert", "lastname": "Smith", "active": true})
+			r := httptest.NewRequest(http.MethodPut, userEndPoint, bytes.NewReader(body))
+			r.Header.Set(IfMatchHeaderName, ifMatchETag)
+			return api.UpdateUserHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "abcd1234"}))
+		}
+
+		Convey("When it is updated with a matching If-Match header", func() {
+			successResponse, errorResponse := updateUser(etag)
+
+			Convey("Then the update is made and the entity tag the get user endpoint now returns is returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(updated, ShouldBeTrue)
+				So(successResponse.Headers[ETagHeaderName], ShouldEqual, newETag(successResponse.Body))
+
+				forename = "Robert"
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, userEndPoint, http.NoBody), map[string]string{"id": "abcd1234"})
+				getResponse, errorResponse := api.GetUserHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(getResponse.Headers[ETagHeaderName], ShouldNotEqual, etag)
+				successResponse, errorResponse = updateUser(getResponse.Headers[ETagHeaderName])
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Headers[ETagHeaderName], ShouldEqual, getResponse.Headers[ETagHeaderName])
+			})
+		})
+
+		Convey("When it has been changed by someone else before being updated", func() {
+			forename = "Roberta"
+			successResponse, errorResponse := updateUser(etag)
+
+			Convey("Then the update is refused with a 412", func() {
+				So(successResponse, ShouldBeNil)
+				So(updated, ShouldBeFalse)
+				So(errorResponse.Status, ShouldEqual, http.StatusPreconditionFailed)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.PreconditionFailedError)
+				So(castErr.Description, ShouldEqual, models.ResourceModifiedDescription)
+			})
+		})
+	})
+}
+
+func TestUpdateGroupHandler_IfMatch(t *testing.T) {
+	Convey("Given a group that has been read", t, func() {
+		api, w, m := apiMockSetup()
+		description := "Test group"
+		created := time.Now()
+		m.GetGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{
+				GroupName:    aws.String("test-group"),
+				Description:  aws.String(description),
+				Precedence:   aws.Int32(20),
+				CreationDate: &created,
+			}}, nil
+		}
+		updated := false
+		m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+			updated = true
+			return &cognitoidentityprovider.UpdateGroupOutput{}, nil
+		}
+
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, updateGroupEndPoint, http.NoBody), map[string]string{"id": "test-group"})
+		getResponse, errorResponse := api.GetGroupHandler(ctx, w, r)
+		So(errorResponse, ShouldBeNil)
+		etag := getResponse.Headers[ETagHeaderName]
+		So(etag, ShouldNotBeEmpty)
+
+		updateGroup := func(ifMatchETag string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]interface{}{"name": "Renamed group", "precedence": 20})
+			r := httptest.NewRequest(http.MethodPut, updateGroupEndPoint, bytes.NewReader(body))
+			r.Header.Set(IfMatchHeaderName, ifMatchETag)
+			return api.UpdateGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+		}
+
+		Convey("When it is updated with a matching If-Match header the update is made", func() {
+			_, errorResponse := updateGroup(etag)
+			So(errorResponse, ShouldBeNil)
+			So(updated, ShouldBeTrue)
+		})
+
+		Convey("When it has been changed by someone else the update is refused with a 412", func() {
+			description = "Changed group"
+			successResponse, errorResponse := updateGroup(etag)
+			So(successResponse, ShouldBeNil)
+			So(updated, ShouldBeFalse)
+			So(errorResponse.Status, ShouldEqual, http.StatusPreconditionFailed)
+		})
+	})
+}
+
+func TestSetGroupUsersHandler_IfMatch(t *testing.T) {
+	Convey("Given a group's members have been read", t, func() {
+		api, w, m := apiMockSetup()
+		members := []string{"user-1", "user-2"}
+		m.GetGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: aws.String("test-group")}}, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			users := []types.UserType{}
+			for _, member := range members {
+				users = append(users, types.UserType{Username: aws.String(member), Enabled: true, UserStatus: types.UserStatusTypeConfirmed})
+			}
+			return &cognitoidentityprovider.ListUsersInGroupOutput{Users: users}, nil
+		}
+		changed := false
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			changed = true
+			members = append(members, *input.Username)
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		m.AdminRemoveUserFromGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminRemoveUserFromGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+			changed = true
+			return &cognitoidentityprovider.AdminRemoveUserFromGroupOutput{}, nil
+		}
+
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, getUsersInGroupEndPoint+"?sort=forename:desc", http.NoBody), map[string]string{"id": "test-group"})
+		getResponse, errorResponse := api.ListUsersInGroupHandler(ctx, w, r)
+		So(errorResponse, ShouldBeNil)
+		etag := getResponse.Headers[ETagHeaderName]
+		So(etag, ShouldNotBeEmpty)
+
+		setGroupUsers := func(ifMatchETag string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal([]map[string]string{{"user_id": "user-1"}, {"user_id": "user-2"}, {"user_id": "user-3"}})
+			r := httptest.NewRequest(http.MethodPut, addUserToGroupEndPoint, bytes.NewReader(body))
+			r.Header.Set(IfMatchHeaderName, ifMatchETag)
+			return api.SetGroupUsersHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+		}
+
+		Convey("When the members are replaced with a matching If-Match header", func() {
+			successResponse, errorResponse := setGroupUsers(etag)
+
+			Convey("Then the changes are made and the new membership entity tag returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(changed, ShouldBeTrue)
+				newMembers := []models.UserParams{{ID: "user-1"}, {ID: "user-2"}, {ID: "user-3"}}
+				So(successResponse.Headers[ETagHeaderName], ShouldEqual, membershipETag(newMembers))
+			})
+		})
+
+		Convey("When a colleague has changed the members since they were read", func() {
+			members = []string{"user-1", "user-2", "user-4"}
+			successResponse, errorResponse := setGroupUsers(etag)
+
+			Convey("Then the colleague's change is not undone", func() {
+				So(successResponse, ShouldBeNil)
+				So(changed, ShouldBeFalse)
+				So(errorResponse.Status, ShouldEqual, http.StatusPreconditionFailed)
+			})
+		})
+	})
+}
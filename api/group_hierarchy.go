@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+)
+
+// ListChildGroupsHandler lists the groups nested directly in a group
+func (api *API) ListChildGroupsHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	return api.childGroupsResponse(ctx, group)
+}
+
+// AddChildGroupHandler nests a group in another, so that the child group's members inherit the parent group
+func (api *API) AddChildGroupHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	var bodyJSON map[string]string
+	err = json.Unmarshal(body, &bodyJSON)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+	child := models.Group{ID: bodyJSON["group_id"]}
+
+	if child.ID == "" {
+		responseErr := models.NewValidationError(ctx, models.InvalidGroupIDError, models.MissingGroupIDErrorDescription)
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+	}
+
+	if group.IsRoleGroup() || child.IsRoleGroup() {
+		responseErr := models.NewValidationError(ctx, models.ProtectedGroupError, models.RoleGroupNestingDescription)
+		return nil, models.NewErrorResponse(http.StatusForbidden, nil, responseErr)
+	}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+	if errResponse := api.checkGroupExists(ctx, child); errResponse != nil {
+		if errResponse.Status == http.StatusNotFound {
+			responseErr := models.NewValidationError(ctx, models.InvalidGroupIDError, models.ChildGroupNotFoundDescription)
+			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+		}
+		return nil, errResponse
+	}
+
+	if err = api.GroupHierarchy.AddChildGroup(ctx, group.ID, child.ID); err != nil {
+		return nil, handleGroupHierarchyError(ctx, err)
+	}
+
+	return api.childGroupsResponse(ctx, group)
+}
+
+// RemoveChildGroupHandler stops a group being nested in another
+func (api *API) RemoveChildGroupHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+	childID := vars["child_id"]
+
+	removed, err := api.GroupHierarchy.RemoveChildGroup(ctx, group.ID, childID)
+	if err != nil {
+		return nil, handleGroupHierarchyError(ctx, err)
+	}
+	if !removed {
+		responseErr := models.NewValidationError(ctx, models.NotFoundError, models.NotChildGroupDescription)
+		return nil, models.NewErrorResponse(http.StatusNotFound, nil, responseErr)
+	}
+
+	return api.childGroupsResponse(ctx, group)
+}
+
+// getInheritedGroups returns the groups that the given groups are nested in, directly or indirectly, that are not
+// already amongst them. Groups missing from the user pool are skipped.
+func (api *API) getInheritedGroups(ctx context.Context, groups []types.GroupType) ([]types.GroupType, error) {
+	groupIDs := make([]string, 0, len(groups))
+	for i := range groups {
+		groupIDs = append(groupIDs, aws.ToString(groups[i].GroupName))
+	}
+
+	ancestorIDs, err := api.GroupHierarchy.ListAncestorGroups(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var inherited []types.GroupType
+	for _, ancestorID := range ancestorIDs {
+		ancestor := models.Group{ID: ancestorID}
+		output, err := api.CognitoClient.GetGroup(ctx, ancestor.BuildGetGroupRequest(api.UserPoolID))
+		if err != nil {
+			var notFoundErr *types.ResourceNotFoundException
+			if errors.As(err, &notFoundErr) {
+				dplogs.Warn(ctx, "nested in a group that is not in the user pool", dplogs.Data{"group_id": ancestorID})
+				continue
+			}
+			return nil, err
+		}
+		inherited = append(inherited, *output.Group)
+	}
+	return inherited, nil
+}
+
+func (api *API) childGroupsResponse(ctx context.Context, group models.Group) (*models.SuccessResponse, *models.ErrorResponse) {
+	groupIDs, err := api.GroupHierarchy.ListChildGroups(ctx, group.ID)
+	if err != nil {
+		return nil, handleGroupHierarchyError(ctx, err)
+	}
+
+	children := models.NewChildGroups(groupIDs)
+	jsonResponse, responseErr := children.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
+
+func handleGroupHierarchyError(ctx context.Context, err error) *models.ErrorResponse {
+	if errors.Is(err, store.ErrGroupCycle) {
+		responseErr := models.NewValidationError(ctx, models.GroupCycleError, models.GroupCycleDescription)
+		return models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+	}
+	return models.NewErrorResponse(http.StatusInternalServerError,
+		nil,
+		models.NewError(ctx, err, models.InternalError, models.GroupHierarchyFailedDescription),
+	)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const childGroupsEndPoint = "http://localhost:25600/v1/groups/economics/children"
+
+func TestChildGroupsHandlers(t *testing.T) {
+	Convey("Given an economics group and the teams within it", t, func() {
+		api, w, m := apiMockSetup()
+		m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			if *input.GroupName == "missing-group" {
+				return nil, &types.ResourceNotFoundException{Message: aws.String("Group not found.")}
+			}
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName}}, nil
+		}
+
+		addChild := func(groupID, childID string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]string{"group_id": childID})
+			r := httptest.NewRequest(http.MethodPost, childGroupsEndPoint, bytes.NewReader(body))
+			return api.AddChildGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": groupID}))
+		}
+
+		Convey("When a team is nested in the economics group", func() {
+			successResponse, errorResponse := addChild("economics", "prices")
+
+			Convey("Then the group's children are returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldEqual, `{"groups":["prices"],"count":1}`)
+
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, childGroupsEndPoint, http.NoBody), map[string]string{"id": "economics"})
+				successResponse, errorResponse = api.ListChildGroupsHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldEqual, `{"groups":["prices"],"count":1}`)
+			})
+
+			Convey("Then nesting the economics group in the team is rejected as a cycle", func() {
+				successResponse, errorResponse := addChild("prices", "economics")
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+				So(errorResponse.Errors[0].(*models.Error).Code, ShouldEqual, models.GroupCycleError)
+			})
+
+			Convey("Then the team can be removed from the group once", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, childGroupsEndPoint+"/prices", http.NoBody),
+					map[string]string{"id": "economics", "child_id": "prices"})
+				successResponse, errorResponse := api.RemoveChildGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldEqual, `{"groups":[],"count":0}`)
+
+				successResponse, errorResponse = api.RemoveChildGroupHandler(ctx, w, r)
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+				So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.NotChildGroupDescription)
+			})
+
+			Convey("Then deleting the team removes it from the group", func() {
+				m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+					return &cognitoidentityprovider.ListUsersInGroupOutput{}, nil
+				}
+				m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+					return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+				}
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, updateGroupEndPoint, http.NoBody), map[string]string{"id": "prices"})
+				_, errorResponse := api.DeleteGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				children, err := api.GroupHierarchy.ListChildGroups(ctx, "economics")
+				So(err, ShouldBeNil)
+				So(children, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a group is nested in itself a 409 is returned", func() {
+			successResponse, errorResponse := addChild("economics", "economics")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+		})
+
+		Convey("When a role group is nested a 403 is returned", func() {
+			successResponse, errorResponse := addChild("economics", models.AdminRoleGroup)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusForbidden)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.RoleGroupNestingDescription)
+		})
+
+		Convey("When a group that does not exist is nested a 400 is returned", func() {
+			successResponse, errorResponse := addChild("economics", "missing-group")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.ChildGroupNotFoundDescription)
+		})
+
+		Convey("When a group is nested in a group that does not exist a 404 is returned", func() {
+			successResponse, errorResponse := addChild("missing-group", "prices")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestListUserGroupsHandler_Effective(t *testing.T) {
+	Convey("Given a user in a team nested in the economics group", t, func() {
+		api, w, m := apiMockSetup()
+		So(api.GroupHierarchy.AddChildGroup(ctx, "economics", "prices"), ShouldBeNil)
+		m.ListGroupsForUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminListGroupsForUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminListGroupsForUserOutput, error) {
+			return &cognitoidentityprovider.AdminListGroupsForUserOutput{Groups: []types.GroupType{{GroupName: aws.String("prices")}}}, nil
+		}
+		m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName}}, nil
+		}
+
+		listUserGroups := func(query string) models.ListUserGroups {
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, userListGroupsEndPoint+query, http.NoBody), map[string]string{"id": "abcd1234"})
+			successResponse, errorResponse := api.ListUserGroupsHandler(ctx, w, r)
+			So(errorResponse, ShouldBeNil)
+
+			var userGroups models.ListUserGroups
+			So(json.Unmarshal(successResponse.Body, &userGroups), ShouldBeNil)
+			return userGroups
+		}
+
+		Convey("Only the groups they are a member of are listed by default", func() {
+			userGroups := listUserGroups("")
+			So(userGroups.Count, ShouldEqual, 1)
+			So(*userGroups.Groups[0].ID, ShouldEqual, "prices")
+		})
+
+		Convey("The economics group is listed as inherited when effective groups are asked for", func() {
+			userGroups := listUserGroups("?effective=true")
+			So(userGroups.Count, ShouldEqual, 2)
+			So(*userGroups.Groups[0].ID, ShouldEqual, "prices")
+			So(userGroups.Groups[0].Inherited, ShouldBeFalse)
+			So(*userGroups.Groups[1].ID, ShouldEqual, "economics")
+			So(userGroups.Groups[1].Inherited, ShouldBeTrue)
+		})
+
+		Convey("An invalid effective value is rejected", func() {
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, userListGroupsEndPoint+"?effective=maybe", http.NoBody), map[string]string{"id": "abcd1234"})
+			successResponse, errorResponse := api.ListUserGroupsHandler(ctx, w, r)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestGetTeamsReportLines_ExpandNested(t *testing.T) {
+	Convey("Given group_2 is nested in group_0", t, func() {
+		api, _, m := apiMockSetup()
+		So(api.GroupHierarchy.AddChildGroup(ctx, "group_0", "group_2"), ShouldBeNil)
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			l, _ := strconv.Atoi((*input.GroupName)[len(*input.GroupName)-1:])
+			return listGroupsUsers(l + 1), nil
+		}
+		groupsList := listGroups(3)
+
+		Convey("The report lists group_0's own members unless nested membership is expanded", func() {
+			lines, err := api.GetTeamsReportLines(ctx, &groupsList, false)
+			So(err, ShouldBeNil)
+			So(*lines, ShouldHaveLength, 6)
+		})
+
+		Convey("Expanding nested membership adds group_2's members to group_0 once each", func() {
+			lines, err := api.GetTeamsReportLines(ctx, &groupsList, true)
+			So(err, ShouldBeNil)
+			So(*lines, ShouldHaveLength, 8)
+
+			var group0Emails []string
+			for _, line := range *lines {
+				if line.GroupName == "group 0 description" {
+					group0Emails = append(group0Emails, line.UserEmail)
+				}
+			}
+			So(group0Emails, ShouldResemble, []string{"user_0.email@domain.test", "user_1.email@domain.test", "user_2.email@domain.test"})
+		})
+	})
+}
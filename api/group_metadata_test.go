@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingGroupMetadataStore is a GroupMetadataStore that cannot save metadata
+type failingGroupMetadataStore struct {
+	store.GroupMetadataStore
+}
+
+func (failingGroupMetadataStore) SetGroupMetadata(_ context.Context, _ string, _ models.GroupMetadata) error {
+	return errors.New("store unavailable")
+}
+
+func TestGroupMetadata(t *testing.T) {
+	Convey("Given a group without metadata", t, func() {
+		api, w, m := apiMockSetup()
+		created := time.Now()
+		group := types.GroupType{
+			GroupName:    aws.String("test-group"),
+			Description:  aws.String("Test group"),
+			Precedence:   aws.Int32(20),
+			CreationDate: &created,
+		}
+		m.GetGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &group}, nil
+		}
+		m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+			return &cognitoidentityprovider.UpdateGroupOutput{}, nil
+		}
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			return &cognitoidentityprovider.ListGroupsOutput{Groups: []types.GroupType{group}}, nil
+		}
+
+		updateGroup := func(body map[string]interface{}) (*models.SuccessResponse, *models.ErrorResponse) {
+			jsonBody, _ := json.Marshal(body)
+			r := httptest.NewRequest(http.MethodPut, updateGroupEndPoint, bytes.NewReader(jsonBody))
+			return api.UpdateGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+		}
+
+		Convey("When its description, owner and contact email are set", func() {
+			successResponse, errorResponse := updateGroup(map[string]interface{}{
+				"name":          "Test group",
+				"precedence":    20,
+				"description":   "Publishes the release calendar",
+				"owner":         "Digital Publishing",
+				"contact_email": "publishing@ons.gov.uk",
+			})
+			So(errorResponse, ShouldBeNil)
+			So(successResponse.Status, ShouldEqual, http.StatusOK)
+
+			expectedMetadata := models.GroupMetadata{
+				Description:  "Publishes the release calendar",
+				Owner:        "Digital Publishing",
+				ContactEmail: "publishing@ons.gov.uk",
+			}
+
+			Convey("Then they are returned when the group is read", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, updateGroupEndPoint, http.NoBody), map[string]string{"id": "test-group"})
+				successResponse, errorResponse := api.GetGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				var groupResponse models.Group
+				So(json.Unmarshal(successResponse.Body, &groupResponse), ShouldBeNil)
+				So(groupResponse.GroupMetadata, ShouldResemble, expectedMetadata)
+			})
+
+			Convey("Then they are returned when the groups are listed", func() {
+				r := httptest.NewRequest(http.MethodGet, getListGroupsEndPoint, http.NoBody)
+				successResponse, errorResponse := api.ListGroupsHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				var groupsResponse models.ListUserGroups
+				So(json.Unmarshal(successResponse.Body, &groupsResponse), ShouldBeNil)
+				So(groupsResponse.Groups[0].GroupMetadata, ShouldResemble, expectedMetadata)
+			})
+
+			Convey("Then an update without metadata fields leaves them unchanged", func() {
+				successResponse, errorResponse := updateGroup(map[string]interface{}{"name": "Renamed group", "precedence": 20})
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusOK)
+
+				metadata, err := api.GroupMetadata.GetGroupMetadata(ctx, "test-group")
+				So(err, ShouldBeNil)
+				So(metadata, ShouldResemble, expectedMetadata)
+			})
+
+			Convey("Then an update can clear a single field", func() {
+				successResponse, errorResponse := updateGroup(map[string]interface{}{"name": "Test group", "precedence": 20, "owner": ""})
+				So(errorResponse, ShouldBeNil)
+
+				var updateResponse map[string]interface{}
+				So(json.Unmarshal(successResponse.Body, &updateResponse), ShouldBeNil)
+				So(updateResponse["owner"], ShouldEqual, "")
+				So(updateResponse["contact_email"], ShouldEqual, "publishing@ons.gov.uk")
+			})
+
+			Convey("Then they are removed when the group is deleted", func() {
+				m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+					return &cognitoidentityprovider.ListUsersInGroupOutput{}, nil
+				}
+				m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+					return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+				}
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, updateGroupEndPoint, http.NoBody), map[string]string{"id": "test-group"})
+				_, errorResponse := api.DeleteGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				metadata, err := api.GroupMetadata.GetGroupMetadata(ctx, "test-group")
+				So(err, ShouldBeNil)
+				So(metadata, ShouldResemble, models.GroupMetadata{})
+			})
+		})
+
+		Convey("When the metadata cannot be saved the group is not updated", func() {
+			api.GroupMetadata = failingGroupMetadataStore{api.GroupMetadata}
+			updateCalled := false
+			m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+				updateCalled = true
+				return &cognitoidentityprovider.UpdateGroupOutput{}, nil
+			}
+
+			successResponse, errorResponse := updateGroup(map[string]interface{}{"name": "Test group", "precedence": 20, "owner": "Digital Publishing"})
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			So(updateCalled, ShouldBeFalse)
+		})
+
+		Convey("When Cognito fails to update the group its previous metadata is restored", func() {
+			previousMetadata := models.GroupMetadata{Owner: "Digital Publishing"}
+			So(api.GroupMetadata.SetGroupMetadata(ctx, "test-group", previousMetadata), ShouldBeNil)
+			m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+				return nil, errors.New("internal error")
+			}
+
+			successResponse, errorResponse := updateGroup(map[string]interface{}{"name": "Test group", "precedence": 20, "owner": "Dissemination"})
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+
+			metadata, err := api.GroupMetadata.GetGroupMetadata(ctx, "test-group")
+			So(err, ShouldBeNil)
+			So(metadata, ShouldResemble, previousMetadata)
+		})
+
+		Convey("When a group is created and its metadata cannot be saved the group is deleted again", func() {
+			api.GroupMetadata = failingGroupMetadataStore{api.GroupMetadata}
+			m.CreateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.CreateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.CreateGroupOutput, error) {
+				return &cognitoidentityprovider.CreateGroupOutput{}, nil
+			}
+			var deletedGroup string
+			m.DeleteGroupFunc = func(_ context.Context, input *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+				deletedGroup = *input.GroupName
+				return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+			}
+
+			body, _ := json.Marshal(map[string]interface{}{"id": "new-group", "name": "New group", "precedence": 22, "owner": "Digital Publishing"})
+			r := httptest.NewRequest(http.MethodPost, createGroupEndPoint, bytes.NewReader(body))
+			successResponse, errorResponse := api.CreateGroupHandler(ctx, w, r)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			So(deletedGroup, ShouldEqual, "new-group")
+		})
+
+		Convey("When an invalid contact email is set the update is refused", func() {
+			successResponse, errorResponse := updateGroup(map[string]interface{}{"name": "Test group", "contact_email": "not-an-email"})
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.InvalidGroupContactEmail)
+		})
+	})
+}
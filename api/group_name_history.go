@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+)
+
+// ListGroupRenamesHandler lists the former names of a group, oldest first
+func (api *API) ListGroupRenamesHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	renames, err := api.GroupNameHistory.ListGroupRenames(ctx, group.ID)
+	if err != nil {
+		return nil, handleGroupNameHistoryError(ctx, err)
+	}
+
+	history := models.NewGroupRenameHistory(renames)
+	jsonResponse, responseErr := history.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
+
+// getGroupName returns the group's current name, or an empty string if the group cannot be found
+func (api *API) getGroupName(ctx context.Context, group models.Group) (string, *models.ErrorResponse) {
+	output, err := api.CognitoClient.GetGroup(ctx, group.BuildGetGroupRequest(api.UserPoolID))
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito GetGroup request reading the group name")
+		if cognitoErr.Code == models.NotFoundError {
+			return "", nil
+		}
+		return "", models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+	return aws.ToString(output.Group.Description), nil
+}
+
+// recordGroupRename adds the group's old name to its history when the new name is different once both are cleaned,
+// returning the rename so that it can be forgotten if the group is not renamed, or nil if there was none
+func (api *API) recordGroupRename(ctx context.Context, groupID, oldName, newName string) (*models.GroupRename, *models.ErrorResponse) {
+	if oldName == "" || models.CleanString(oldName) == models.CleanString(newName) {
+		return nil, nil
+	}
+
+	rename := models.GroupRename{GroupID: groupID, Name: oldName, Renamed: time.Now().UTC()}
+	if err := api.GroupNameHistory.AddGroupRename(ctx, rename); err != nil {
+		return nil, handleGroupNameHistoryError(ctx, err)
+	}
+	return &rename, nil
+}
+
+// forgetGroupRename removes a rename recorded for a group update that then failed. The update has already failed, so
+// a failure to remove the rename is only logged.
+func (api *API) forgetGroupRename(ctx context.Context, rename *models.GroupRename) {
+	if rename == nil {
+		return
+	}
+	if err := api.GroupNameHistory.RemoveGroupRename(ctx, *rename); err != nil {
+		dplogs.Error(ctx, "failed to remove rename of group that was not updated", err, dplogs.Data{"group_id": rename.GroupID})
+	}
+}
+
+// findGroupsByName returns the groups whose current name matches the given one once both are cleaned. If there are
+// none, it returns the group that most recently gave up the name, along with the former name it was found by.
+func (api *API) findGroupsByName(ctx context.Context, groups []types.GroupType, name string) ([]types.GroupType, map[string]string, error) {
+	cleanName := models.CleanString(name)
+	var found []types.GroupType
+	for i := range groups {
+		if models.CleanString(aws.ToString(groups[i].Description)) == cleanName {
+			found = append(found, groups[i])
+		}
+	}
+	if len(found) > 0 {
+		return found, nil, nil
+	}
+
+	rename, err := api.GroupNameHistory.FindGroupRename(ctx, name)
+	if err != nil || rename == nil {
+		return nil, nil, err
+	}
+	for i := range groups {
+		if aws.ToString(groups[i].GroupName) == rename.GroupID {
+			return []types.GroupType{groups[i]}, map[string]string{rename.GroupID: rename.Name}, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func handleGroupNameHistoryError(ctx context.Context, err error) *models.ErrorResponse {
+	return models.NewErrorResponse(http.StatusInternalServerError,
+		nil,
+		models.NewError(ctx, err, models.InternalError, models.GroupNameHistoryFailedDescription),
+	)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingGroupNameHistoryStore is a GroupNameHistoryStore that cannot save renames
+type failingGroupNameHistoryStore struct {
+	store.GroupNameHistoryStore
+}
+
+func (failingGroupNameHistoryStore) AddGroupRename(_ context.Context, _ models.GroupRename) error {
+	return errors.New("store unavailable")
+}
+
+func TestGroupRenameHistory(t *testing.T) {
+	Convey("Given a group called Prices Team", t, func() {
+		api, w, m := apiMockSetup()
+		group := types.GroupType{GroupName: aws.String("test-group"), Description: aws.String("Prices Team"), Precedence: aws.Int32(20)}
+		otherGroup := types.GroupType{GroupName: aws.String("other-group"), Description: aws.String("Trade"), Precedence: aws.Int32(21)}
+		m.GetGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &group}, nil
+		}
+		m.UpdateGroupFunc = func(_ context.Context, input *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+			group.Description = input.Description
+			return &cognitoidentityprovider.UpdateGroupOutput{}, nil
+		}
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			return &cognitoidentityprovider.ListGroupsOutput{Groups: []types.GroupType{group, otherGroup}}, nil
+		}
+
+		renameGroup := func(name string) {
+			body, _ := json.Marshal(map[string]interface{}{"name": name, "precedence": 20})
+			r := httptest.NewRequest(http.MethodPut, updateGroupEndPoint, bytes.NewReader(body))
+			_, errorResponse := api.UpdateGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+			So(errorResponse, ShouldBeNil)
+		}
+		listGroupsByName := func(name string) models.ListUserGroups {
+			r := httptest.NewRequest(http.MethodGet, getListGroupsEndPoint+"?name="+name, http.NoBody)
+			successResponse, errorResponse := api.ListGroupsHandler(ctx, w, r)
+			So(errorResponse, ShouldBeNil)
+
+			var groupsList models.ListUserGroups
+			So(json.Unmarshal(successResponse.Body, &groupsList), ShouldBeNil)
+			return groupsList
+		}
+
+		Convey("When it is renamed to Inflation", func() {
+			renameGroup("Inflation")
+
+			Convey("Then its old name is kept in its rename history", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, updateGroupEndPoint+"/renames", http.NoBody), map[string]string{"id": "test-group"})
+				successResponse, errorResponse := api.ListGroupRenamesHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				var history models.GroupRenameHistory
+				So(json.Unmarshal(successResponse.Body, &history), ShouldBeNil)
+				So(history.Count, ShouldEqual, 1)
+				So(history.Renames[0].Name, ShouldEqual, "Prices Team")
+			})
+
+			Convey("Then it is found by its new name without a hint", func() {
+				groupsList := listGroupsByName("inflation")
+				So(groupsList.Count, ShouldEqual, 1)
+				So(*groupsList.Groups[0].ID, ShouldEqual, "test-group")
+				So(groupsList.Groups[0].RenamedFrom, ShouldBeEmpty)
+			})
+
+			Convey("Then it is found by its old name with a hint", func() {
+				groupsList := listGroupsByName("prices-team")
+				So(groupsList.Count, ShouldEqual, 1)
+				So(*groupsList.Groups[0].Name, ShouldEqual, "Inflation")
+				So(groupsList.Groups[0].RenamedFrom, ShouldEqual, "Prices Team")
+			})
+
+			Convey("Then a name no group has had finds nothing", func() {
+				groupsList := listGroupsByName("Economics")
+				So(groupsList.Count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When Cognito fails to rename it no rename is kept", func() {
+			m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+				return nil, errors.New("internal error")
+			}
+			body, _ := json.Marshal(map[string]interface{}{"name": "Inflation", "precedence": 20})
+			r := httptest.NewRequest(http.MethodPut, updateGroupEndPoint, bytes.NewReader(body))
+			_, errorResponse := api.UpdateGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+
+			renames, err := api.GroupNameHistory.ListGroupRenames(ctx, "test-group")
+			So(err, ShouldBeNil)
+			So(renames, ShouldBeEmpty)
+		})
+
+		Convey("When its old name cannot be kept it is not renamed", func() {
+			api.GroupNameHistory = failingGroupNameHistoryStore{api.GroupNameHistory}
+			body, _ := json.Marshal(map[string]interface{}{"name": "Inflation", "precedence": 20})
+			r := httptest.NewRequest(http.MethodPut, updateGroupEndPoint, bytes.NewReader(body))
+			_, errorResponse := api.UpdateGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "test-group"}))
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			So(*group.Description, ShouldEqual, "Prices Team")
+		})
+
+		Convey("When only the punctuation of its name changes no rename is recorded", func() {
+			renameGroup("Prices-Team!")
+
+			renames, err := api.GroupNameHistory.ListGroupRenames(ctx, "test-group")
+			So(err, ShouldBeNil)
+			So(renames, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+// requireGroupOwnerOr wraps a group handler so it can be called by the owners of the group in the request path as well
+// as by callers with the permission. Everyone else is authorised by auth.Require as usual.
+func (api *API) requireGroupOwnerOr(auth authorisation.Middleware, permission string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	requirePermission := auth.Require(permission, handlerFunc)
+	return func(w http.ResponseWriter, req *http.Request) {
+		if api.isGroupOwner(req, auth) {
+			handlerFunc(w, req)
+			return
+		}
+		requirePermission(w, req)
+	}
+}
+
+// isGroupOwner reports whether the request's access token belongs to an owner of the group in the request path. Any
+// failure to identify the caller is reported as false, leaving auth.Require to respond to the request.
+func (api *API) isGroupOwner(req *http.Request, auth authorisation.Middleware) bool {
+	ctx := req.Context()
+	group := models.Group{ID: mux.Vars(req)["id"]}
+	if api.GroupOwners == nil || group.ID == "" || group.IsRoleGroup() {
+		return false
+	}
+
+	userID := parseCallerID(req, auth)
+	if userID == "" {
+		return false
+	}
+
+	isOwner, err := api.GroupOwners.IsGroupOwner(ctx, group.ID, userID)
+	if err != nil {
+		dplogs.Error(ctx, "failed to check group ownership", err, dplogs.Data{"group_id": group.ID})
+		return false
+	}
+	if isOwner {
+		dplogs.Info(ctx, "group request authorised by group ownership",
+			dplogs.Data{"group_id": group.ID, "user_id": userID, "method": req.Method, "path": req.URL.Path})
+	}
+	return isOwner
+}
+
+// ListGroupOwnersHandler lists the owners of a group
+func (api *API) ListGroupOwnersHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	return api.groupOwnersResponse(ctx, group)
+}
+
+// AddGroupOwnerHandler makes a user an owner of a group
+func (api *API) AddGroupOwnerHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+
+	if group.IsRoleGroup() {
+		responseErr := models.NewValidationError(ctx, models.ProtectedGroupError, models.RoleGroupOwnersDescription)
+		return nil, models.NewErrorResponse(http.StatusForbidden, nil, responseErr)
+	}
+
+	if errResponse := api.checkGroupExists(ctx, group); errResponse != nil {
+		return nil, errResponse
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	var bodyJSON map[string]string
+	err = json.Unmarshal(body, &bodyJSON)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+	userID := bodyJSON["user_id"]
+
+	validationErrs := group.ValidateAddRemoveUser(ctx, userID)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+
+	user := models.UserParams{ID: userID}
+	_, err = api.CognitoClient.AdminGetUser(ctx, user.BuildAdminGetUserRequest(api.UserPoolID))
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "AdminGetUser request from add group owner endpoint")
+		if cognitoErr.Code == models.UserNotFoundError {
+			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, cognitoErr)
+		}
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+
+	if err = api.GroupOwners.AddGroupOwner(ctx, group.ID, userID); err != nil {
+		return nil, handleGroupOwnersError(ctx, err)
+	}
+
+	return api.groupOwnersResponse(ctx, group)
+}
+
+// RemoveGroupOwnerHandler stops a user being an owner of a group
+func (api *API) RemoveGroupOwnerHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	vars := mux.Vars(req)
+	group := models.Group{ID: vars["id"]}
+	userID := vars["user_id"]
+
+	validationErrs := group.ValidateAddRemoveUser(ctx, userID)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+
+	removed, err := api.GroupOwners.RemoveGroupOwner(ctx, group.ID, userID)
+	if err != nil {
+		return nil, handleGroupOwnersError(ctx, err)
+	}
+	if !removed {
+		responseErr := models.NewValidationError(ctx, models.NotFoundError, models.NotGroupOwnerDescription)
+		return nil, models.NewErrorResponse(http.StatusNotFound, nil, responseErr)
+	}
+
+	return api.groupOwnersResponse(ctx, group)
+}
+
+// checkGroupExists returns a 404 response when the group is not in the user pool
+func (api *API) checkGroupExists(ctx context.Context, group models.Group) *models.ErrorResponse {
+	_, err := api.CognitoClient.GetGroup(ctx, group.BuildGetGroupRequest(api.UserPoolID))
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito GetGroup request checking the group exists")
+		if cognitoErr.Code == models.NotFoundError {
+			return models.NewErrorResponse(http.StatusNotFound, nil, cognitoErr)
+		}
+		return models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+	return nil
+}
+
+func (api *API) groupOwnersResponse(ctx context.Context, group models.Group) (*models.SuccessResponse, *models.ErrorResponse) {
+	userIDs, err := api.GroupOwners.ListGroupOwners(ctx, group.ID)
+	if err != nil {
+		return nil, handleGroupOwnersError(ctx, err)
+	}
+
+	owners := models.NewGroupOwners(userIDs)
+	jsonResponse, responseErr := owners.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
+
+func handleGroupOwnersError(ctx context.Context, err error) *models.ErrorResponse {
+	return models.NewErrorResponse(http.StatusInternalServerError,
+		nil,
+		models.NewError(ctx, err, models.InternalError, models.GroupOwnersFailedDescription),
+	)
+}
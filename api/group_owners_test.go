@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authorisation "github.com/ONSdigital/dp-authorisation/v2/authorisation/mock"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	permsdk "github.com/ONSdigital/dp-permissions-api/sdk"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const groupOwnersEndPoint = "http://localhost:25600/v1/groups/efgh5678/owners"
+
+func TestRequireGroupOwnerOr(t *testing.T) {
+	Convey("Given a membership handler that requires the groups edit permission or ownership of the group", t, func() {
+		api, _, _ := apiMockSetup()
+		So(api.GroupOwners.AddGroupOwner(ctx, "efgh5678", "owner-user"), ShouldBeNil)
+
+		auth := &authorisation.MiddlewareMock{
+			RequireFunc: func(_ string, _ http.HandlerFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				}
+			},
+			ParseFunc: func(token string) (*permsdk.EntityData, error) {
+				if token == "invalid.jwt" {
+					return nil, errors.New("invalid token")
+				}
+				return &permsdk.EntityData{UserID: token[:len(token)-len(".jwt")]}, nil
+			},
+		}
+		handler := api.requireGroupOwnerOr(auth, GroupsEditPermission, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		callHandler := func(groupID, authToken string) int {
+			r := httptest.NewRequest(http.MethodPost, addUserToGroupEndPoint, http.NoBody)
+			r.Header.Set(AccessTokenHeaderName, authToken)
+			w := httptest.NewRecorder()
+			handler(w, mux.SetURLVars(r, map[string]string{"id": groupID}))
+			return w.Code
+		}
+
+		Convey("An owner of the group is allowed", func() {
+			So(callHandler("efgh5678", "Bearer owner-user.jwt"), ShouldEqual, http.StatusOK)
+		})
+
+		Convey("An owner of the group is passed to auth.Require for other groups", func() {
+			So(callHandler("other-group", "Bearer owner-user.jwt"), ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("Anyone else is passed to auth.Require", func() {
+			So(callHandler("efgh5678", "Bearer other-user.jwt"), ShouldEqual, http.StatusForbidden)
+			So(callHandler("efgh5678", "Bearer invalid.jwt"), ShouldEqual, http.StatusForbidden)
+			So(callHandler("efgh5678", "Bearer service-token"), ShouldEqual, http.StatusForbidden)
+			So(callHandler("efgh5678", ""), ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("Ownership of a role group is ignored", func() {
+			So(api.GroupOwners.AddGroupOwner(ctx, models.AdminRoleGroup, "owner-user"), ShouldBeNil)
+			So(callHandler(models.AdminRoleGroup, "Bearer owner-user.jwt"), ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
+
+func TestGroupOwnersHandlers(t *testing.T) {
+	Convey("Given a group and a user", t, func() {
+		api, w, m := apiMockSetup()
+		m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			if *input.GroupName != "efgh5678" {
+				return nil, &types.ResourceNotFoundException{Message: aws.String("Group not found.")}
+			}
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName}}, nil
+		}
+		m.AdminGetUserFunc = func(_ context.Context, input *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			if *input.Username != "abcd1234" {
+				return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+			}
+			return &cognitoidentityprovider.AdminGetUserOutput{Username: input.Username}, nil
+		}
+
+		addOwner := func(groupID, userID string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal(map[string]string{"user_id": userID})
+			r := httptest.NewRequest(http.MethodPost, groupOwnersEndPoint, bytes.NewReader(body))
+			return api.AddGroupOwnerHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": groupID}))
+		}
+
+		Convey("When the user is made an owner", func() {
+			successResponse, errorResponse := addOwner("efgh5678", "abcd1234")
+
+			Convey("Then the group's owners are returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusOK)
+				So(string(successResponse.Body), ShouldEqual, `{"owners":["abcd1234"],"count":1}`)
+			})
+
+			Convey("Then they are listed as an owner", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, groupOwnersEndPoint, http.NoBody), map[string]string{"id": "efgh5678"})
+				successResponse, errorResponse := api.ListGroupOwnersHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldEqual, `{"owners":["abcd1234"],"count":1}`)
+			})
+
+			Convey("Then they can be removed as an owner once", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, groupOwnersEndPoint+"/abcd1234", http.NoBody),
+					map[string]string{"id": "efgh5678", "user_id": "abcd1234"})
+				successResponse, errorResponse := api.RemoveGroupOwnerHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldEqual, `{"owners":[],"count":0}`)
+
+				successResponse, errorResponse = api.RemoveGroupOwnerHandler(ctx, w, r)
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+				So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.NotGroupOwnerDescription)
+			})
+		})
+
+		Convey("When an owner is added to a group that does not exist a 404 is returned", func() {
+			successResponse, errorResponse := addOwner("missing-group", "abcd1234")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("When a user that does not exist is made an owner a 400 is returned", func() {
+			successResponse, errorResponse := addOwner("efgh5678", "missing-user")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("When the user is made an owner of a role group a 403 is returned", func() {
+			successResponse, errorResponse := addOwner(models.AdminRoleGroup, "abcd1234")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusForbidden)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.ProtectedGroupError)
+			So(castErr.Description, ShouldEqual, models.RoleGroupOwnersDescription)
+		})
+
+		Convey("When the group is deleted its owners are removed", func() {
+			So(api.GroupOwners.AddGroupOwner(ctx, "efgh5678", "abcd1234"), ShouldBeNil)
+			m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+				return &cognitoidentityprovider.ListUsersInGroupOutput{}, nil
+			}
+			m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+				return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+			}
+
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, updateGroupEndPoint, http.NoBody), map[string]string{"id": "efgh5678"})
+			_, errorResponse := api.DeleteGroupHandler(ctx, w, r)
+			So(errorResponse, ShouldBeNil)
+
+			owners, err := api.GroupOwners.ListGroupOwners(ctx, "efgh5678")
+			So(err, ShouldBeNil)
+			So(owners, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// GroupPrecedenceReportHandler reports the precedences that are shared by several groups or not used by any
+func (api *API) GroupPrecedenceReportHandler(ctx context.Context, _ http.ResponseWriter, _ *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	listOfGroups, err := api.GetListGroups(ctx)
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListGroups request from group precedence report endpoint")
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+
+	report := models.NewGroupPrecedenceReport(listOfGroups.Groups)
+	return groupPrecedenceResponse(ctx, &report)
+}
+
+// RenumberGroupPrecedenceHandler gives every group other than the role groups its own precedence, keeping their current
+// order. The first failed update stops the renumbering, leaving the groups already updated with their new precedence.
+func (api *API) RenumberGroupPrecedenceHandler(ctx context.Context, _ http.ResponseWriter, _ *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	listOfGroups, err := api.GetListGroups(ctx)
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListGroups request from renumber group precedence endpoint")
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+
+	changes, ok := models.PlanPrecedenceRenumbering(listOfGroups.Groups)
+	if !ok {
+		responseErr := models.NewValidationError(ctx, models.InvalidGroupPrecedence, models.TooManyGroupsToRenumberDescription)
+		return nil, models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+	}
+
+	groupsByID := make(map[string]types.GroupType, len(listOfGroups.Groups))
+	for i := range listOfGroups.Groups {
+		groupsByID[aws.ToString(listOfGroups.Groups[i].GroupName)] = listOfGroups.Groups[i]
+	}
+	for _, change := range changes {
+		input := models.BuildUpdateGroupPrecedenceInput(api.UserPoolID, groupsByID[change.GroupID], change.To)
+		if _, err = api.CognitoClient.UpdateGroup(ctx, input); err != nil {
+			cognitoErr := models.NewCognitoError(ctx, err, "Cognito UpdateGroup request from renumber group precedence endpoint")
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+		}
+		group := groupsByID[change.GroupID]
+		group.Precedence = aws.Int32(change.To)
+		groupsByID[change.GroupID] = group
+	}
+	dplogs.Info(ctx, "group precedences renumbered", dplogs.Data{"changes": len(changes)})
+
+	renumbered := make([]types.GroupType, 0, len(groupsByID))
+	for _, group := range groupsByID {
+		renumbered = append(renumbered, group)
+	}
+	report := models.NewGroupPrecedenceReport(renumbered)
+	report.Changes = changes
+	return groupPrecedenceResponse(ctx, &report)
+}
+
+// allocatePrecedence sets the precedence of a group being created to the lowest one not used by another group. Groups
+// created at the same time can still be given the same precedence.
+func allocatePrecedence(ctx context.Context, createGroup *models.CreateUpdateGroup) *models.ErrorResponse {
+	if createGroup.Precedence != nil {
+		responseErr := models.NewValidationError(ctx, models.InvalidGroupPrecedence, models.AutoPrecedenceConflictDescription)
+		return models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+	}
+
+	precedence, ok := models.NextFreePrecedence(createGroup.GroupsList.Groups)
+	if !ok {
+		responseErr := models.NewValidationError(ctx, models.InvalidGroupPrecedence, models.NoFreePrecedenceDescription)
+		return models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+	}
+	createGroup.Precedence = &precedence
+	return nil
+}
+
+func groupPrecedenceResponse(ctx context.Context, report *models.GroupPrecedenceReport) (*models.SuccessResponse, *models.ErrorResponse) {
+	jsonResponse, responseErr := report.BuildSuccessfulJSONResponse(ctx)
+	if responseErr != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const groupsPrecedenceEndPoint = "http://localhost:25600/v1/groups-precedence"
+
+func TestGroupPrecedenceHandlers(t *testing.T) {
+	Convey("Given groups that share a precedence", t, func() {
+		api, w, m := apiMockSetup()
+		groups := map[string]types.GroupType{
+			"group-a": {GroupName: aws.String("group-a"), Description: aws.String("Group A"), Precedence: aws.Int32(12)},
+			"group-b": {GroupName: aws.String("group-b"), Description: aws.String("Group B"), Precedence: aws.Int32(10)},
+			"group-c": {GroupName: aws.String("group-c"), Description: aws.String("Group C"), Precedence: aws.Int32(12)},
+		}
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			output := &cognitoidentityprovider.ListGroupsOutput{}
+			for _, group := range groups {
+				output.Groups = append(output.Groups, group)
+			}
+			return output, nil
+		}
+		var updates []*cognitoidentityprovider.UpdateGroupInput
+		m.UpdateGroupFunc = func(_ context.Context, input *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+			updates = append(updates, input)
+			return &cognitoidentityprovider.UpdateGroupOutput{}, nil
+		}
+
+		Convey("When the precedences are reported the duplicate is listed", func() {
+			r := httptest.NewRequest(http.MethodGet, groupsPrecedenceEndPoint, http.NoBody)
+			successResponse, errorResponse := api.GroupPrecedenceReportHandler(ctx, w, r)
+			So(errorResponse, ShouldBeNil)
+
+			var report models.GroupPrecedenceReport
+			So(json.Unmarshal(successResponse.Body, &report), ShouldBeNil)
+			So(report.Duplicates, ShouldResemble, []models.PrecedenceDuplicate{{Precedence: 12, Groups: []string{"group-a", "group-c"}}})
+			So(report.Changes, ShouldBeEmpty)
+		})
+
+		Convey("When the groups are renumbered", func() {
+			r := httptest.NewRequest(http.MethodPost, groupsPrecedenceEndPoint+"/renumber", http.NoBody)
+			successResponse, errorResponse := api.RenumberGroupPrecedenceHandler(ctx, w, r)
+			So(errorResponse, ShouldBeNil)
+
+			Convey("Then only the groups that change are updated, keeping their names", func() {
+				So(updates, ShouldHaveLength, 1)
+				So(*updates[0].GroupName, ShouldEqual, "group-a")
+				So(*updates[0].Precedence, ShouldEqual, 11)
+				So(*updates[0].Description, ShouldEqual, "Group A")
+			})
+
+			Convey("Then the changes are reported and no duplicates remain", func() {
+				var report models.GroupPrecedenceReport
+				So(json.Unmarshal(successResponse.Body, &report), ShouldBeNil)
+				So(report.Duplicates, ShouldBeEmpty)
+				So(report.Changes, ShouldHaveLength, 1)
+				So(report.Unused[0], ShouldEqual, 13)
+			})
+		})
+
+		Convey("When an update fails the renumbering stops with a 500", func() {
+			m.UpdateGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.UpdateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+				return nil, errors.New("cognito unavailable")
+			}
+			r := httptest.NewRequest(http.MethodPost, groupsPrecedenceEndPoint+"/renumber", http.NoBody)
+			successResponse, errorResponse := api.RenumberGroupPrecedenceHandler(ctx, w, r)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("When a group is created with an automatic precedence", func() {
+			var created *cognitoidentityprovider.CreateGroupInput
+			m.CreateGroupFunc = func(_ context.Context, input *cognitoidentityprovider.CreateGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.CreateGroupOutput, error) {
+				created = input
+				return &cognitoidentityprovider.CreateGroupOutput{}, nil
+			}
+			createGroup := func(body map[string]interface{}) (*models.SuccessResponse, *models.ErrorResponse) {
+				jsonBody, _ := json.Marshal(body)
+				r := httptest.NewRequest(http.MethodPost, createGroupEndPoint+"?auto_precedence=true", bytes.NewReader(jsonBody))
+				return api.CreateGroupHandler(ctx, w, r)
+			}
+
+			Convey("Then it is given the lowest unused precedence", func() {
+				successResponse, errorResponse := createGroup(map[string]interface{}{"name": "New group"})
+				So(errorResponse, ShouldBeNil)
+				So(*created.Precedence, ShouldEqual, 11)
+				So(string(successResponse.Body), ShouldContainSubstring, `"precedence":11`)
+			})
+
+			Convey("Then giving a precedence as well is rejected", func() {
+				successResponse, errorResponse := createGroup(map[string]interface{}{"name": "New group", "precedence": 20})
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.AutoPrecedenceConflictDescription)
+				So(created, ShouldBeNil)
+			})
+		})
+	})
+}
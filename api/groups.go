@@ -3,13 +3,14 @@ package api
 import (
 	"bytes"
 	"context"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
 
@@ -43,6 +44,11 @@ func (api *API) CreateGroupHandler(ctx context.Context, _ http.ResponseWriter, r
 		return nil, handleBodyUnmarshalError(ctx, err)
 	}
 
+	autoPrecedence, errResponse := parseBoolQuery(ctx, req, "auto_precedence")
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
 	// no groupname in body, set UUID
 	if createGroup.ID == nil {
 		UUID := uuid.NewString()
@@ -58,6 +64,12 @@ func (api *API) CreateGroupHandler(ctx context.Context, _ http.ResponseWriter, r
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
 
+	if autoPrecedence {
+		if errResponse = allocatePrecedence(ctx, &createGroup); errResponse != nil {
+			return nil, errResponse
+		}
+	}
+
 	validationErrs := createGroup.ValidateCreateUpdateGroupRequest(ctx, true)
 	if len(validationErrs) != 0 {
 		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
@@ -73,6 +85,18 @@ func (api *API) CreateGroupHandler(ctx context.Context, _ http.ResponseWriter, r
 		}
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
+	markChangesMade(ctx)
+
+	// a group deleted with this ID may have left owners or nesting behind if removing them failed. If they cannot be
+	// removed, or the metadata saved, the group is deleted again so that the request can be retried.
+	errResponse = api.deleteGroupAccess(ctx, *createGroup.ID)
+	if errResponse == nil {
+		_, errResponse = api.updateGroupMetadata(ctx, *createGroup.ID, &createGroup.GroupMetadataUpdate)
+	}
+	if errResponse != nil {
+		api.rollbackCreateGroup(ctx, *createGroup.ID)
+		return nil, errResponse
+	}
 
 	jsonResponse, responseErr := createGroup.BuildSuccessfulJSONResponse(ctx)
 	if responseErr != nil {
@@ -106,9 +130,40 @@ func (api *API) UpdateGroupHandler(ctx context.Context, _ http.ResponseWriter, r
 		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
 	}
 
+	if hasIfMatch(req) {
+		currentJSON, errResponse := api.getGroupJSON(ctx, id, "Cognito GetGroup request from update group endpoint")
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		if !ifMatch(req, newETag(currentJSON)) {
+			return nil, preconditionFailed(ctx)
+		}
+	}
+
+	// the current name is read so that it can be kept in the group's rename history. A missing group is left for the
+	// update to report.
+	oldName, errResponse := api.getGroupName(ctx, models.Group{ID: id})
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
+	// the rename and metadata are saved before the group is updated, so that a failure to save them leaves the group
+	// unchanged
+	rename, errResponse := api.recordGroupRename(ctx, id, oldName, *updateGroup.Name)
+	if errResponse != nil {
+		return nil, errResponse
+	}
+	previousMetadata, errResponse := api.updateGroupMetadata(ctx, id, &updateGroup.GroupMetadataUpdate)
+	if errResponse != nil {
+		api.forgetGroupRename(ctx, rename)
+		return nil, errResponse
+	}
+
 	input := updateGroup.BuildUpdateGroupInput(api.UserPoolID)
 	_, err = api.CognitoClient.UpdateGroup(ctx, input)
 	if err != nil {
+		api.forgetGroupRename(ctx, rename)
+		api.restoreGroupMetadata(ctx, id, previousMetadata)
 		cognitoErr := models.NewCognitoError(ctx, err, "Cognito UpdateGroup request from update a group endpoint")
 		if cognitoErr.Code == models.NotFoundError {
 			return nil, models.NewErrorResponse(http.StatusNotFound, nil, cognitoErr)
@@ -152,19 +207,36 @@ func (api *API) AddUserToGroupHandler(ctx context.Context, _ http.ResponseWriter
 	userID := bodyJSON["user_id"]
 
 	validationErrs := group.ValidateAddRemoveUser(ctx, userID)
+	expiresAt, err := models.ParseMembershipExpiry(ctx, bodyJSON["expires_at"], time.Now())
+	if err != nil {
+		validationErrs = append(validationErrs, err)
+	}
 	if len(validationErrs) != 0 {
 		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
 	}
 
+	// adding a user again replaces the expiry of their membership, so adding without one makes it permanent. The expiry
+	// is saved before the user is added so that a temporary membership is never left without one.
+	previousExpiresAt, err := api.replaceMembershipExpiry(ctx, group.ID, userID, expiresAt)
+	if err != nil {
+		return nil, handleMembershipExpiryError(ctx, err)
+	}
+
 	response, responseErr := api.AddUserToGroup(ctx, group, userID)
 
 	if responseErr != nil {
+		api.restoreMembershipExpiry(ctx, group, userID, previousExpiresAt)
 		cognitoErr := models.NewCognitoError(ctx, responseErr, "Cognito AddUserToGroup request from add user to group endpoint")
 		if cognitoErr.Code == models.UserNotFoundError || cognitoErr.Code == models.NotFoundError {
 			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, cognitoErr)
 		}
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
+
+	if errResponse := api.addMembershipExpiries(ctx, group, response.Users); errResponse != nil {
+		return nil, errResponse
+	}
+
 	jsonResponse, responseErr := response.BuildSuccessfulJSONResponse(ctx)
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
@@ -188,6 +260,9 @@ func (api *API) ListUsersInGroupHandler(ctx context.Context, _ http.ResponseWrit
 
 	listOfUsers := models.UsersList{}
 	listOfUsers.MapCognitoUsers(&listUsers)
+	if errResponse := api.addMembershipExpiries(ctx, group, listOfUsers.Users); errResponse != nil {
+		return nil, errResponse
+	}
 
 	if err = req.ParseForm(); err != nil {
 		dplogs.Error(ctx, "error parsing form", err)
@@ -201,7 +276,7 @@ func (api *API) ListUsersInGroupHandler(ctx context.Context, _ http.ResponseWrit
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, etagHeaders(membershipETag(users))), nil
 }
 
 func sortUsers(ctx context.Context, users []models.UserParams, sortBy []string) bool {
@@ -349,7 +424,20 @@ func (api *API) ListGroupsHandler(ctx context.Context, _ http.ResponseWriter, re
 		}
 	}
 
-	jsonResponse, responseErr := finalGroupsResponse.BuildListGroupsSuccessfulJSONResponse(ctx, listOfGroups)
+	var renamedFrom map[string]string
+	if name := req.Form.Get("name"); name != "" {
+		listOfGroups.Groups, renamedFrom, err = api.findGroupsByName(ctx, listOfGroups.Groups, name)
+		if err != nil {
+			return nil, handleGroupNameHistoryError(ctx, err)
+		}
+	}
+
+	metadata, err := api.GroupMetadata.ListGroupMetadata(ctx)
+	if err != nil {
+		return nil, handleGroupMetadataError(ctx, err)
+	}
+
+	jsonResponse, responseErr := finalGroupsResponse.BuildListGroupsSuccessfulJSONResponse(ctx, listOfGroups, metadata, renamedFrom)
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
@@ -359,11 +447,20 @@ func (api *API) ListGroupsHandler(ctx context.Context, _ http.ResponseWriter, re
 // GetGroupHandler gets group details for given groups
 func (api *API) GetGroupHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
 	vars := mux.Vars(req)
-	group := models.Group{ID: vars["id"]}
-	groupGetRequest := group.BuildGetGroupRequest(api.UserPoolID)
-	groupGetResponse, err := api.CognitoClient.GetGroup(ctx, groupGetRequest)
+	jsonResponse, errResponse := api.getGroupJSON(ctx, vars["id"], "Cognito GetGroup request from Get group endpoint")
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, etagHeaders(newETag(jsonResponse))), nil
+}
+
+// getGroupJSON reads the group from Cognito and its metadata, and returns it as the get group endpoint does
+func (api *API) getGroupJSON(ctx context.Context, groupID, errContext string) ([]byte, *models.ErrorResponse) {
+	group := models.Group{ID: groupID}
+	groupGetResponse, err := api.CognitoClient.GetGroup(ctx, group.BuildGetGroupRequest(api.UserPoolID))
 	if err != nil {
-		cognitoErr := models.NewCognitoError(ctx, err, "Cognito GetGroup request from Get group endpoint")
+		cognitoErr := models.NewCognitoError(ctx, err, errContext)
 		if cognitoErr.Code == models.NotFoundError {
 			return nil, models.NewErrorResponse(http.StatusNotFound, nil, cognitoErr)
 		}
@@ -372,20 +469,117 @@ func (api *API) GetGroupHandler(ctx context.Context, _ http.ResponseWriter, req
 
 	group.MapCognitoDetails(*groupGetResponse.Group)
 
+	group.GroupMetadata, err = api.GroupMetadata.GetGroupMetadata(ctx, group.ID)
+	if err != nil {
+		return nil, handleGroupMetadataError(ctx, err)
+	}
+
 	jsonResponse, responseErr := group.BuildSuccessfulJSONResponse(ctx)
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
+	return jsonResponse, nil
+}
 
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+// updateGroupMetadata saves any metadata fields in a create or update request, then sets all of the fields on the
+// request so the response shows the group's full metadata. The metadata it replaced is returned so that it can be
+// restored, or nil if there were no metadata fields.
+func (api *API) updateGroupMetadata(ctx context.Context, groupID string, update *models.GroupMetadataUpdate) (*models.GroupMetadata, *models.ErrorResponse) {
+	if !update.IsSet() {
+		return nil, nil
+	}
+	previous, err := api.GroupMetadata.GetGroupMetadata(ctx, groupID)
+	if err != nil {
+		return nil, handleGroupMetadataError(ctx, err)
+	}
+	metadata := update.Apply(previous)
+	if err = api.GroupMetadata.SetGroupMetadata(ctx, groupID, metadata); err != nil {
+		return nil, handleGroupMetadataError(ctx, err)
+	}
+	*update = models.GroupMetadataUpdate{
+		Description:  &metadata.Description,
+		Owner:        &metadata.Owner,
+		ContactEmail: &metadata.ContactEmail,
+	}
+	return &previous, nil
 }
 
-// DeleteGroupHandler deletes the group for the given group id
+// restoreGroupMetadata puts back the metadata replaced for a group update that Cognito then failed to make. The
+// update has already failed, so a failure to restore the metadata is only logged.
+func (api *API) restoreGroupMetadata(ctx context.Context, groupID string, previous *models.GroupMetadata) {
+	if previous == nil {
+		return
+	}
+	if err := api.GroupMetadata.SetGroupMetadata(ctx, groupID, *previous); err != nil {
+		dplogs.Error(ctx, "failed to restore metadata of group that was not updated", err, dplogs.Data{"group_id": groupID})
+	}
+}
+
+// rollbackCreateGroup deletes a group that was created in Cognito but could not be set up in the data store. The
+// request has already failed, so a failure to delete the group is only logged.
+func (api *API) rollbackCreateGroup(ctx context.Context, groupID string) {
+	group := models.Group{ID: groupID}
+	if _, err := api.CognitoClient.DeleteGroup(ctx, group.BuildDeleteGroupRequest(api.UserPoolID)); err != nil {
+		dplogs.Error(ctx, "failed to delete group that could not be set up", err, dplogs.Data{"group_id": groupID})
+	}
+}
+
+func handleGroupMetadataError(ctx context.Context, err error) *models.ErrorResponse {
+	return models.NewErrorResponse(http.StatusInternalServerError,
+		nil,
+		models.NewError(ctx, err, models.InternalError, models.GroupMetadataFailedDescription),
+	)
+}
+
+// DeleteGroupHandler deletes the group for the given group id. The role groups cannot be deleted. A group with members
+// is only deleted with ?force=true, or once its members have been moved to the group given by ?transfer_to.
 func (api *API) DeleteGroupHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
 	vars := mux.Vars(req)
 	group := models.Group{ID: vars["id"]}
+
+	if group.IsRoleGroup() {
+		responseErr := models.NewValidationError(ctx, models.ProtectedGroupError, models.ProtectedGroupDescription)
+		return nil, models.NewErrorResponse(http.StatusForbidden, nil, responseErr)
+	}
+
+	force, errResponse := parseBoolQuery(ctx, req, "force")
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
+	transferTo := models.Group{ID: req.URL.Query().Get("transfer_to")}
+	if transferTo.ID == group.ID {
+		responseErr := models.NewValidationError(ctx, models.InvalidGroupIDError, models.TransferToSameGroupDescription)
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+	}
+	// role groups grant permissions across the API, so members are not moved into them as a side effect of a delete
+	if transferTo.IsRoleGroup() {
+		responseErr := models.NewValidationError(ctx, models.ProtectedGroupError, models.TransferToRoleGroupDescription)
+		return nil, models.NewErrorResponse(http.StatusForbidden, nil, responseErr)
+	}
+
+	members, err := api.getUsersInAGroup(ctx, group)
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request from delete group endpoint")
+		if cognitoErr.Code == models.NotFoundError {
+			return nil, models.NewErrorResponse(http.StatusNotFound, nil, cognitoErr)
+		}
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+
+	if len(members) > 0 {
+		if transferTo.ID != "" {
+			if errResponse = api.transferGroupMembers(ctx, group, members, transferTo); errResponse != nil {
+				return nil, errResponse
+			}
+		} else if !force {
+			responseErr := models.NewValidationError(ctx, models.GroupNotEmptyError, models.GroupNotEmptyDescription)
+			return nil, models.NewErrorResponse(http.StatusConflict, nil, responseErr)
+		}
+	}
+
 	groupDeleteRequest := group.BuildDeleteGroupRequest(api.UserPoolID)
-	_, err := api.CognitoClient.DeleteGroup(ctx, groupDeleteRequest)
+	_, err = api.CognitoClient.DeleteGroup(ctx, groupDeleteRequest)
 	if err != nil {
 		cognitoErr := models.NewCognitoError(ctx, err, "Cognito DeleteGroup request from Delete group endpoint")
 		if cognitoErr.Code == models.NotFoundError {
@@ -393,15 +587,98 @@ func (api *API) DeleteGroupHandler(ctx context.Context, _ http.ResponseWriter, r
 		}
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
+
+	if errResponse = api.deleteGroupAccess(ctx, group.ID); errResponse != nil {
+		return nil, errResponse
+	}
+
+	if err = api.GroupMetadata.DeleteGroupMetadata(ctx, group.ID); err != nil {
+		// the group has gone, so leftover metadata is only logged
+		dplogs.Error(ctx, "failed to delete metadata of deleted group", err, dplogs.Data{"group_id": group.ID})
+	}
+	if err = api.MembershipExpiry.DeleteGroupMembershipExpiries(ctx, group.ID); err != nil {
+		dplogs.Error(ctx, "failed to delete membership expiries of deleted group", err, dplogs.Data{"group_id": group.ID})
+	}
+	if err = api.AccessRequests.DeleteGroupAccessRequests(ctx, group.ID); err != nil {
+		dplogs.Error(ctx, "failed to delete access requests of deleted group", err, dplogs.Data{"group_id": group.ID})
+	}
+	if err = api.GroupNameHistory.DeleteGroupRenames(ctx, group.ID); err != nil {
+		dplogs.Error(ctx, "failed to delete name history of deleted group", err, dplogs.Data{"group_id": group.ID})
+	}
 	return models.NewSuccessResponse(nil, http.StatusNoContent, nil), nil
 }
 
-// SetGroupUsersHandler adds a user to the specified group
+// deleteGroupAccess removes the owners and nesting of a group that no longer exists in Cognito, so that a group
+// created later with the same ID cannot inherit its owners or pass on and inherit its memberships
+func (api *API) deleteGroupAccess(ctx context.Context, groupID string) *models.ErrorResponse {
+	if err := api.GroupOwners.DeleteGroupOwners(ctx, groupID); err != nil {
+		return handleGroupOwnersError(ctx, err)
+	}
+	if err := api.GroupHierarchy.DeleteGroupHierarchy(ctx, groupID); err != nil {
+		return handleGroupHierarchyError(ctx, err)
+	}
+	return nil
+}
+
+// transferGroupMembers adds the members of a group being deleted to another group, skipping any already in it. A
+// temporary member keeps their expiry time in the other group, which is saved before they are added so that a failure
+// cannot leave them with a permanent membership. The first failure stops the transfer so that the group is not deleted
+// while members would lose access.
+func (api *API) transferGroupMembers(ctx context.Context, source models.Group, members []types.UserType, target models.Group) *models.ErrorResponse {
+	targetMembers, err := api.getUsersInAGroup(ctx, target)
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request for transfer group from delete group endpoint")
+		if cognitoErr.Code == models.NotFoundError {
+			return models.NewErrorResponse(http.StatusBadRequest, nil, cognitoErr)
+		}
+		return models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+
+	expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, source.ID)
+	if err != nil {
+		return handleMembershipExpiryError(ctx, err)
+	}
+
+	alreadyMember := make(map[string]bool, len(targetMembers))
+	for _, user := range targetMembers {
+		alreadyMember[*user.Username] = true
+	}
+
+	for _, user := range members {
+		// an existing membership of the other group is left as it is, whether or not it is temporary
+		if alreadyMember[*user.Username] {
+			continue
+		}
+		expiresAt, temporary := expiries[*user.Username]
+		if temporary {
+			if err = api.MembershipExpiry.SetMembershipExpiry(ctx, target.ID, *user.Username, expiresAt); err != nil {
+				return handleMembershipExpiryError(ctx, err)
+			}
+		}
+		_, err = api.CognitoClient.AdminAddUserToGroup(ctx, target.BuildAddUserToGroupRequest(api.UserPoolID, *user.Username))
+		if err != nil {
+			if temporary {
+				api.clearMembershipExpiry(ctx, target, *user.Username)
+			}
+			cognitoErr := models.NewCognitoError(ctx, err, "Cognito AdminAddUserToGroup request for transfer group from delete group endpoint")
+			return models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+		}
+	}
+	return nil
+}
+
+// SetGroupUsersHandler replaces the members of the specified group, returning the users added and removed. With
+// ?dry_run=true the changes are worked out and returned without being made.
 func (api *API) SetGroupUsersHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
 	vars := mux.Vars(req)
 
 	group := models.Group{ID: vars["id"]}
 
+	dryRun, errResponse := parseBoolQuery(ctx, req, "dry_run")
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
 	groupGetRequest := group.BuildGetGroupRequest(api.UserPoolID)
 	_, err := api.CognitoClient.GetGroup(ctx, groupGetRequest)
 	if err != nil {
@@ -437,7 +714,20 @@ func (api *API) SetGroupUsersHandler(ctx context.Context, _ http.ResponseWriter,
 		listOfUsers.Users = append(listOfUsers.Users, models.UserParams{}.MapCognitoDetails(userType))
 	}
 
-	setResponse, setErr := api.SetGroupUsers(ctx, group, listOfUsers)
+	if hasIfMatch(req) {
+		members, err := api.getUsersInAGroup(ctx, group)
+		if err != nil {
+			cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request from set group membership endpoint")
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+		}
+		currentMembers := models.UsersList{}
+		currentMembers.MapCognitoUsers(&members)
+		if !ifMatch(req, membershipETag(currentMembers.Users)) {
+			return nil, preconditionFailed(ctx)
+		}
+	}
+
+	setResponse, setErr := api.SetGroupUsers(ctx, group, listOfUsers, dryRun)
 	if setErr != nil {
 		return nil, setErr
 	}
@@ -446,13 +736,18 @@ func (api *API) SetGroupUsersHandler(ctx context.Context, _ http.ResponseWriter,
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
-}
 
-func (api *API) SetGroupUsers(ctx context.Context, group models.Group, users models.UsersList) (*models.UsersList, *models.ErrorResponse) {
-	keep := false
-	successResponse := &models.UsersList{}
+	var headers map[string]string
+	if setResponse.UsersList != nil {
+		headers = etagHeaders(membershipETag(setResponse.Users))
+	}
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, headers), nil
+}
 
+// SetGroupUsers works out the users to add to and remove from the group so that its members are the given users. Unless
+// it is a dry run the changes are then made, each user's outcome is recorded on the diff and the resulting members are
+// listed. A failure for one user does not stop the remaining changes.
+func (api *API) SetGroupUsers(ctx context.Context, group models.Group, users models.UsersList, dryRun bool) (*models.GroupMembershipDiff, *models.ErrorResponse) {
 	listUsers, err := api.getUsersInAGroup(ctx, group)
 	if err != nil {
 		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request from set group membership endpoint")
@@ -462,33 +757,63 @@ func (api *API) SetGroupUsers(ctx context.Context, group models.Group, users mod
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
 
-	for _, s1 := range listUsers {
-		keep = false
-		for i := range users.Users {
-			s2 := &users.Users[i]
-			if s2.ID == *s1.Username {
-				keep = true
-			}
-		}
-		if !keep {
-			successResponse, _ = api.RemoveUserFromGroup(ctx, group, *s1.Username)
-		}
+	currentUserIDs := make([]string, 0, len(listUsers))
+	for _, user := range listUsers {
+		currentUserIDs = append(currentUserIDs, *user.Username)
 	}
-
+	requestedUserIDs := make([]string, 0, len(users.Users))
 	for i := range users.Users {
-		s1 := &users.Users[i] // Use reference instead of copying
-		keep = false
-		for _, s2 := range listUsers {
-			if *s2.Username == s1.ID {
-				keep = true
-			}
+		requestedUserIDs = append(requestedUserIDs, users.Users[i].ID)
+	}
+
+	diff := models.NewGroupMembershipDiff(currentUserIDs, requestedUserIDs)
+	diff.DryRun = dryRun
+	if dryRun {
+		return diff, nil
+	}
+
+	for i := range diff.ToRemove {
+		change := &diff.ToRemove[i]
+		userRemoveFromGroupInput := group.BuildRemoveUserFromGroupRequest(api.UserPoolID, change.UserID)
+		_, err = api.CognitoClient.AdminRemoveUserFromGroup(ctx, userRemoveFromGroupInput)
+		setMembershipChangeOutcome(ctx, change, err, "Cognito AdminRemoveUserFromGroup request from set group membership endpoint")
+		if err == nil {
+			api.clearMembershipExpiry(ctx, group, change.UserID)
 		}
-		if !keep {
-			successResponse, _ = api.AddUserToGroup(ctx, group, s1.ID)
+	}
+
+	for i := range diff.ToAdd {
+		change := &diff.ToAdd[i]
+		userAddToGroupInput := group.BuildAddUserToGroupRequest(api.UserPoolID, change.UserID)
+		_, err = api.CognitoClient.AdminAddUserToGroup(ctx, userAddToGroupInput)
+		setMembershipChangeOutcome(ctx, change, err, "Cognito AdminAddUserToGroup request from set group membership endpoint")
+		if err == nil {
+			api.clearMembershipExpiry(ctx, group, change.UserID)
 		}
 	}
 
-	return successResponse, nil
+	listUsers, err = api.getUsersInAGroup(ctx, group)
+	if err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request from set group membership endpoint")
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
+	}
+	diff.UsersList = &models.UsersList{}
+	diff.MapCognitoUsers(&listUsers)
+	if errResponse := api.addMembershipExpiries(ctx, group, diff.Users); errResponse != nil {
+		return nil, errResponse
+	}
+
+	return diff, nil
+}
+
+// setMembershipChangeOutcome records whether a user was added to or removed from a group
+func setMembershipChangeOutcome(ctx context.Context, change *models.GroupMembershipChange, err error, errContext string) {
+	if err != nil {
+		change.Status = models.MembershipChangeFailed
+		change.Error = models.NewCognitoError(ctx, err, errContext)
+		return
+	}
+	change.Status = models.MembershipChangeSucceeded
 }
 
 // AddUserToGroup adds a user to the specified group
@@ -516,6 +841,7 @@ func (api *API) RemoveUserFromGroup(ctx context.Context, group models.Group, use
 	if err != nil {
 		return nil, err
 	}
+	api.clearMembershipExpiry(ctx, group, userID)
 
 	listUsers, err := api.getUsersInAGroup(ctx, group)
 	if err != nil {
@@ -530,48 +856,43 @@ func (api *API) RemoveUserFromGroup(ctx context.Context, group models.Group, use
 
 // ListGroupsUsersHandler produces a user requested report of all groups with members including groups that act as roles
 // output by default is json but if request header accept == text/csv then the output is csv format
-// each line consists of the group description and user email
+// each line consists of the group description and user email, with the optional columns given in ?columns=
+// ?group_id= limits the report to the groups, ?active= to active or inactive users
+// with ?nested=true each group also lists the members of the groups nested in it
+// the report is streamed as each group's members are fetched, so only a failure on the first group gets an error status
 func (api *API) ListGroupsUsersHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
-	var (
-		GroupsUsersList *[]models.ListGroupUsersType
-	)
-	listOfGroups, err := api.GetListGroups(ctx)
-	if err != nil {
-		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, err)
+	query, errResponse := parseGroupsReportQuery(ctx, req)
+	if errResponse != nil {
+		return nil, errResponse
 	}
-	GroupsUsersList, err = api.GetTeamsReportLines(ctx, listOfGroups)
+	listOfGroups, err := api.GetListGroups(ctx)
 	if err != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, err)
 	}
 
-	if req.Header.Get("Accept") == "text/csv" {
-		header := map[string]string{"Content-type": "text/csv"}
-		return models.NewSuccessResponse(api.ListGroupsUsersCSV(GroupsUsersList).Bytes(), http.StatusOK, header), nil
-	}
-
-	jsonResponse, err := json.Marshal(GroupsUsersList)
+	report := api.newTeamsReport(listOfGroups, query)
+	first, more, err := report.nextGroup(ctx)
 	if err != nil {
+		report.close()
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, err)
 	}
 
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+	format := negotiateGroupsReportFormat(req.Header.Get("Accept"))
+	response := models.NewSuccessResponse(nil, http.StatusOK, format.headers())
+	response.Stream = streamGroupsReport(ctx, report, first, more, format.newWriter(query.columns))
+	return response, nil
 }
 
 // ListGroupsUsersCSV converts the groupsUsersList output to csv
 func (api *API) ListGroupsUsersCSV(groupsUsersList *[]models.ListGroupUsersType) *bytes.Buffer {
-	var csvHeader = models.ListGroupUsersType{
-		GroupName: "Group",
-		UserEmail: "User",
-	}
 	buf := new(bytes.Buffer)
-	w := csv.NewWriter(buf)
+	w := newCSVReportWriter(nil)(buf)
 
-	rows := [][]string{{csvHeader.GroupName, csvHeader.UserEmail}}
-	for _, record := range *groupsUsersList {
-		rows = append(rows, []string{record.GroupName, record.UserEmail})
+	err := w.Begin()
+	if err == nil {
+		err = w.WriteGroup(groupsReportGroup{Lines: *groupsUsersList})
 	}
-
-	if err := w.WriteAll(rows); err != nil {
+	if err != nil {
 		dplogs.Error(context.Background(), "failed to write CSV rows", err)
 	}
 	return buf
@@ -579,31 +900,53 @@ func (api *API) ListGroupsUsersCSV(groupsUsersList *[]models.ListGroupUsersType)
 
 // GetTeamsReportLines  from the listOfGroups for each group gets the list of members and produces output
 // group description user email for each group member
-func (api *API) GetTeamsReportLines(ctx context.Context, listOfGroups *cognitoidentityprovider.ListGroupsOutput) (*[]models.ListGroupUsersType, error) {
-	var GroupsUsersList []models.ListGroupUsersType
-	for _, ListGroup := range listOfGroups.Groups {
-		inputGroup := models.Group{ID: *ListGroup.GroupName}
-		listUsers, err := api.getUsersInAGroup(ctx, inputGroup)
+// when expandNested is true the members of the groups nested in each group are included once each
+func (api *API) GetTeamsReportLines(ctx context.Context, listOfGroups *cognitoidentityprovider.ListGroupsOutput, expandNested bool) (*[]models.ListGroupUsersType, error) {
+	GroupsUsersList := []models.ListGroupUsersType{}
+	report := api.newTeamsReport(listOfGroups, groupsReportQuery{expandNested: expandNested})
+	defer report.close()
+	for {
+		group, more, err := report.nextGroup(ctx)
 		if err != nil {
 			return nil, err
 		}
-		for _, user := range listUsers {
-			for _, attribute := range user.Attributes {
-				if strings.EqualFold(*attribute.Name, "email") {
-					GroupsUsersList = append(GroupsUsersList, models.ListGroupUsersType{
-						GroupName: *ListGroup.Description,
-						UserEmail: *attribute.Value,
-					})
-				}
-			}
+		if !more {
+			return &GroupsUsersList, nil
 		}
+		GroupsUsersList = append(GroupsUsersList, group.Lines...)
 	}
+}
 
-	if GroupsUsersList == nil {
-		GroupsUsersList = []models.ListGroupUsersType{}
+// addNestedMembers adds the members of the groups nested in the group to its members, skipping users already listed.
+// Nested groups missing from the user pool are skipped.
+func (api *API) addNestedMembers(ctx context.Context, groupID string, members []types.UserType, getMembers func(string) ([]types.UserType, error)) ([]types.UserType, error) {
+	descendantIDs, err := api.GroupHierarchy.ListDescendantGroups(ctx, groupID)
+	if err != nil {
+		return nil, err
 	}
 
-	return &GroupsUsersList, nil
+	listed := make(map[string]bool, len(members))
+	for i := range members {
+		listed[aws.ToString(members[i].Username)] = true
+	}
+	expanded := append([]types.UserType{}, members...)
+	for _, descendantID := range descendantIDs {
+		descendantMembers, err := getMembers(descendantID)
+		if err != nil {
+			var notFoundErr *types.ResourceNotFoundException
+			if errors.As(err, &notFoundErr) {
+				continue
+			}
+			return nil, err
+		}
+		for i := range descendantMembers {
+			if !listed[aws.ToString(descendantMembers[i].Username)] {
+				listed[aws.ToString(descendantMembers[i].Username)] = true
+				expanded = append(expanded, descendantMembers[i])
+			}
+		}
+	}
+	return expanded, nil
 }
 
 // sortGroups sorts groups in alphabetical order based on the specified sorting criteria
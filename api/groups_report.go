@@ -0,0 +1,601 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// groupsReportCSVHeaders are the CSV headers of the optional groups report columns
+var groupsReportCSVHeaders = map[string]string{
+	models.ReportColumnUserID:     "User ID",
+	models.ReportColumnForename:   "Forename",
+	models.ReportColumnLastname:   "Lastname",
+	models.ReportColumnActive:     "Active",
+	models.ReportColumnGroupID:    "Group ID",
+	models.ReportColumnPrecedence: "Precedence",
+	models.ReportColumnJoined:     "Joined",
+}
+
+// The media types of the groups report formats that can be requested with the Accept header
+const (
+	groupsReportJSON   = "application/json"
+	groupsReportCSV    = "text/csv"
+	groupsReportNDJSON = "application/x-ndjson"
+	groupsReportXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// groupsReportFormat is a format the groups report can be written in
+type groupsReportFormat struct {
+	mediaType string
+	// extension is the extension of the report's attachment filename, the report is not an attachment when it is empty
+	extension string
+	newWriter func(columns map[string]bool) func(w io.Writer) groupsReportWriter
+}
+
+// groupsReportFormats are the formats of the groups report, the first is used when no other is accepted
+var groupsReportFormats = []groupsReportFormat{
+	{mediaType: groupsReportJSON, newWriter: func(map[string]bool) func(w io.Writer) groupsReportWriter { return newJSONReportWriter }},
+	{mediaType: groupsReportCSV, extension: "csv", newWriter: newCSVReportWriter},
+	{mediaType: groupsReportNDJSON, extension: "ndjson", newWriter: func(map[string]bool) func(w io.Writer) groupsReportWriter { return newNDJSONReportWriter }},
+	{mediaType: groupsReportXLSX, extension: "xlsx", newWriter: newXLSXReportWriter},
+}
+
+// negotiateGroupsReportFormat returns the groups report format most preferred by an Accept header, going by the
+// quality values and then the order of the media types
+func negotiateGroupsReportFormat(accept string) groupsReportFormat {
+	format, bestQuality := groupsReportFormats[0], 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		for _, f := range groupsReportFormats {
+			if f.mediaType == mediaType && quality > bestQuality {
+				format, bestQuality = f, quality
+			}
+		}
+	}
+	return format
+}
+
+// headers returns the response headers of a report in the format
+func (f groupsReportFormat) headers() map[string]string {
+	if f.extension == "" {
+		return nil
+	}
+	return map[string]string{
+		"Content-type":        f.mediaType,
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": "groups-report." + f.extension}),
+	}
+}
+
+// groupsReportQuery selects the groups, members and optional columns of the groups report
+type groupsReportQuery struct {
+	// groupIDs limits the report to the groups, all groups are reported when it is empty
+	groupIDs map[string]bool
+	// active limits the report to active or inactive users when it is set
+	active       *bool
+	columns      map[string]bool
+	expandNested bool
+}
+
+// parseGroupsReportQuery reads the groups report query parameters. group_id may be repeated or comma separated, as
+// may columns.
+func parseGroupsReportQuery(ctx context.Context, req *http.Request) (groupsReportQuery, *models.ErrorResponse) {
+	query := groupsReportQuery{groupIDs: map[string]bool{}, columns: map[string]bool{}}
+
+	var errResponse *models.ErrorResponse
+	query.expandNested, errResponse = parseBoolQuery(ctx, req, "nested")
+	if errResponse != nil {
+		return query, errResponse
+	}
+
+	if req.URL.Query().Get("active") != "" {
+		active, errResponse := parseBoolQuery(ctx, req, "active")
+		if errResponse != nil {
+			return query, errResponse
+		}
+		query.active = &active
+	}
+
+	for _, groupID := range splitQueryValues(req, "group_id") {
+		query.groupIDs[groupID] = true
+	}
+
+	for _, column := range splitQueryValues(req, "columns") {
+		if !slices.Contains(models.ReportColumns, column) {
+			validationErr := models.NewValidationError(ctx, models.InvalidFilterQuery, models.InvalidReportColumnDescription)
+			return query, models.NewErrorResponse(http.StatusBadRequest, nil, validationErr)
+		}
+		query.columns[column] = true
+	}
+
+	return query, nil
+}
+
+// splitQueryValues returns the values of a query parameter that may be repeated or comma separated
+func splitQueryValues(req *http.Request, name string) []string {
+	var values []string
+	for _, value := range req.URL.Query()[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// groupsReportGroup is a group in the groups report with the lines of its members
+type groupsReportGroup struct {
+	ID    string
+	Name  string
+	Lines []models.ListGroupUsersType
+}
+
+// teamsReport produces the groups report a group at a time, so that it can be written out as the memberships are
+// fetched rather than held in memory. The memberships are fetched by a pool of workers, which run a limited number of
+// groups ahead of the group being written, and the groups are returned in their original order.
+type teamsReport struct {
+	api             *API
+	groups          []types.GroupType
+	query           groupsReportQuery
+	concurrency     int
+	backoffSchedule []time.Duration
+
+	// membersByGroup keeps the members of nested groups when nested membership is expanded, as a nested group's
+	// members are reported for each of its ancestors
+	mu             sync.Mutex
+	membersByGroup map[string][]types.UserType
+	// nestedFetches lets workers reporting groups that share a nested group wait for one fetch of its members
+	nestedFetches singleflight.Group
+
+	results []chan groupsReportResult
+	ahead   chan struct{}
+	cancel  context.CancelFunc
+	next    int
+}
+
+// groupsReportResult is the outcome of fetching a group's members for the report
+type groupsReportResult struct {
+	group groupsReportGroup
+	err   error
+}
+
+// newTeamsReport returns the report of the groups selected by the query. It must be closed once it is finished with.
+func (api *API) newTeamsReport(listOfGroups *cognitoidentityprovider.ListGroupsOutput, query groupsReportQuery) *teamsReport {
+	groups := listOfGroups.Groups
+	if len(query.groupIDs) > 0 {
+		groups = nil
+		for _, group := range listOfGroups.Groups {
+			if query.groupIDs[aws.ToString(group.GroupName)] {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	report := &teamsReport{
+		api:             api,
+		groups:          groups,
+		query:           query,
+		concurrency:     max(api.GroupsReportConcurrency, 1),
+		backoffSchedule: DefaultBackOffSchedule,
+	}
+	if query.expandNested {
+		report.membersByGroup = map[string][]types.UserType{}
+	}
+	return report
+}
+
+// start starts the workers that fetch the groups' members
+func (r *teamsReport) start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.results = make([]chan groupsReportResult, len(r.groups))
+	for i := range r.results {
+		r.results[i] = make(chan groupsReportResult, 1)
+	}
+	// limits how many fetched groups can be waiting to be written
+	r.ahead = make(chan struct{}, 2*r.concurrency)
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range r.groups {
+			select {
+			case r.ahead <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < min(r.concurrency, len(r.groups)); w++ {
+		go func() {
+			for i := range jobs {
+				group, err := r.fetchGroup(ctx, r.groups[i])
+				r.results[i] <- groupsReportResult{group: group, err: err}
+			}
+		}()
+	}
+}
+
+// close stops the workers, which is needed when the report is not read to the end
+func (r *teamsReport) close() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// nextGroup returns the next group in the report, or false once every group has been returned
+func (r *teamsReport) nextGroup(ctx context.Context) (groupsReportGroup, bool, error) {
+	if r.next >= len(r.groups) {
+		return groupsReportGroup{}, false, nil
+	}
+	if r.results == nil {
+		r.start(ctx)
+	}
+
+	select {
+	case result := <-r.results[r.next]:
+		r.next++
+		<-r.ahead
+		if result.err != nil {
+			return result.group, false, result.err
+		}
+		return result.group, true, nil
+	case <-ctx.Done():
+		return groupsReportGroup{}, false, ctx.Err()
+	}
+}
+
+// fetchGroup fetches the members of a group and returns its report lines
+func (r *teamsReport) fetchGroup(ctx context.Context, group types.GroupType) (groupsReportGroup, error) {
+	reportGroup := groupsReportGroup{ID: aws.ToString(group.GroupName), Name: aws.ToString(group.Description)}
+	members, err := r.getGroupMembers(ctx, reportGroup.ID)
+	if err != nil {
+		return reportGroup, err
+	}
+	if r.query.expandNested {
+		members, err = r.api.addNestedMembers(ctx, reportGroup.ID, members, func(groupID string) ([]types.UserType, error) {
+			return r.getNestedMembers(ctx, groupID)
+		})
+		if err != nil {
+			return reportGroup, err
+		}
+	}
+
+	var joined map[string]time.Time
+	if r.query.columns[models.ReportColumnJoined] {
+		joined, err = r.joinedTimes(ctx, reportGroup.ID)
+		if err != nil {
+			return reportGroup, err
+		}
+	}
+
+	reportGroup.Lines = []models.ListGroupUsersType{}
+	for _, member := range members {
+		if r.query.active != nil && member.Enabled != *r.query.active {
+			continue
+		}
+		user := models.UserParams{}.MapCognitoDetails(member)
+		if user.Email == "" {
+			continue
+		}
+		reportGroup.Lines = append(reportGroup.Lines, r.line(group, user, joined))
+	}
+	return reportGroup, nil
+}
+
+// getGroupMembers returns the members of a group being reported, using those kept from when it was reported as a
+// nested group if there are any
+func (r *teamsReport) getGroupMembers(ctx context.Context, groupID string) ([]types.UserType, error) {
+	r.mu.Lock()
+	members, ok := r.membersByGroup[groupID]
+	r.mu.Unlock()
+	if ok {
+		return members, nil
+	}
+	return r.fetchMembers(ctx, groupID)
+}
+
+// getNestedMembers returns the members of a nested group, keeping them for the other groups it is nested in. Workers
+// that need the group while its members are being fetched wait for that fetch rather than making their own.
+func (r *teamsReport) getNestedMembers(ctx context.Context, groupID string) ([]types.UserType, error) {
+	r.mu.Lock()
+	members, ok := r.membersByGroup[groupID]
+	r.mu.Unlock()
+	if ok {
+		return members, nil
+	}
+
+	fetched, err, _ := r.nestedFetches.Do(groupID, func() (interface{}, error) {
+		members, err := r.fetchMembers(ctx, groupID)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.membersByGroup[groupID] = members
+		r.mu.Unlock()
+		return members, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fetched.([]types.UserType), nil
+}
+
+// fetchMembers fetches the members of a group from Cognito. Requests throttled by Cognito are retried after each wait
+// in the backoff schedule.
+func (r *teamsReport) fetchMembers(ctx context.Context, groupID string) ([]types.UserType, error) {
+	members, err := r.api.getUsersInAGroup(ctx, models.Group{ID: groupID})
+	for _, backoff := range r.backoffSchedule {
+		if err == nil || models.NewCognitoError(ctx, err, "Cognito ListUsersInGroup request from groups report").Code != models.TooManyRequestsError {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		members, err = r.api.getUsersInAGroup(ctx, models.Group{ID: groupID})
+	}
+	return members, err
+}
+
+// joinedTimes returns when users joined the group by having an access request approved, keyed by user ID. Users
+// added to the group in other ways have no known joined time.
+func (r *teamsReport) joinedTimes(ctx context.Context, groupID string) (map[string]time.Time, error) {
+	approved, err := r.api.AccessRequests.ListAccessRequests(ctx, groupID, models.AccessRequestApproved)
+	if err != nil {
+		return nil, err
+	}
+	joined := map[string]time.Time{}
+	for _, request := range approved {
+		if request.Decided != nil && request.Decided.After(joined[request.UserID]) {
+			joined[request.UserID] = *request.Decided
+		}
+	}
+	return joined, nil
+}
+
+// line returns the report line of a group member with the requested columns
+func (r *teamsReport) line(group types.GroupType, user models.UserParams, joined map[string]time.Time) models.ListGroupUsersType {
+	line := models.ListGroupUsersType{
+		GroupName: aws.ToString(group.Description),
+		UserEmail: user.Email,
+	}
+	columns := r.query.columns
+	if columns[models.ReportColumnUserID] {
+		line.UserID = aws.String(user.ID)
+	}
+	if columns[models.ReportColumnForename] {
+		line.Forename = aws.String(user.Forename)
+	}
+	if columns[models.ReportColumnLastname] {
+		line.Lastname = aws.String(user.Lastname)
+	}
+	if columns[models.ReportColumnActive] {
+		line.Active = aws.Bool(user.Active)
+	}
+	if columns[models.ReportColumnGroupID] {
+		line.GroupID = group.GroupName
+	}
+	if columns[models.ReportColumnPrecedence] {
+		line.Precedence = group.Precedence
+	}
+	if joinedTime, ok := joined[user.ID]; ok {
+		line.Joined = &joinedTime
+	}
+	return line
+}
+
+// groupsReportWriter writes the groups report in one format, a group at a time
+type groupsReportWriter interface {
+	Begin() error
+	WriteGroup(group groupsReportGroup) error
+	End() error
+}
+
+// streamGroupsReport returns a function that writes the report, starting with a group that has already been fetched
+// so that a failure to fetch the first group could still be reported with an error status
+func streamGroupsReport(ctx context.Context, report *teamsReport, first groupsReportGroup, more bool, newWriter func(w io.Writer) groupsReportWriter) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		defer report.close()
+
+		reportWriter := newWriter(w)
+		if err := reportWriter.Begin(); err != nil {
+			return err
+		}
+
+		group := first
+		for more {
+			if err := reportWriter.WriteGroup(group); err != nil {
+				return err
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+
+			var err error
+			group, more, err = report.nextGroup(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return reportWriter.End()
+	}
+}
+
+// jsonReportWriter writes the groups report as a JSON array of lines
+type jsonReportWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func newJSONReportWriter(w io.Writer) groupsReportWriter {
+	return &jsonReportWriter{w: w}
+}
+
+func (jw *jsonReportWriter) Begin() error {
+	_, err := io.WriteString(jw.w, "[")
+	return err
+}
+
+func (jw *jsonReportWriter) WriteGroup(group groupsReportGroup) error {
+	for i := range group.Lines {
+		line, err := json.Marshal(group.Lines[i])
+		if err != nil {
+			return err
+		}
+		if jw.written {
+			line = append([]byte(","), line...)
+		}
+		if _, err := jw.w.Write(line); err != nil {
+			return err
+		}
+		jw.written = true
+	}
+	return nil
+}
+
+func (jw *jsonReportWriter) End() error {
+	_, err := io.WriteString(jw.w, "]")
+	return err
+}
+
+// ndjsonReportWriter writes the groups report as newline delimited JSON, a line at a time
+type ndjsonReportWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONReportWriter(w io.Writer) groupsReportWriter {
+	return &ndjsonReportWriter{encoder: json.NewEncoder(w)}
+}
+
+func (nw *ndjsonReportWriter) Begin() error {
+	return nil
+}
+
+func (nw *ndjsonReportWriter) WriteGroup(group groupsReportGroup) error {
+	for i := range group.Lines {
+		if err := nw.encoder.Encode(group.Lines[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nw *ndjsonReportWriter) End() error {
+	return nil
+}
+
+// csvReportWriter writes the groups report as CSV with a header row
+type csvReportWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVReportWriter(columns map[string]bool) func(w io.Writer) groupsReportWriter {
+	orderedColumns := orderReportColumns(columns)
+	return func(w io.Writer) groupsReportWriter {
+		return &csvReportWriter{w: csv.NewWriter(w), columns: orderedColumns}
+	}
+}
+
+func (cw *csvReportWriter) Begin() error {
+	header := []string{"Group", "User"}
+	for _, column := range cw.columns {
+		header = append(header, groupsReportCSVHeaders[column])
+	}
+	return cw.w.Write(header)
+}
+
+func (cw *csvReportWriter) WriteGroup(group groupsReportGroup) error {
+	for i := range group.Lines {
+		if err := cw.w.Write(cw.row(group.Lines[i])); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvReportWriter) End() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// row returns the CSV row of a report line, leaving unknown values blank
+func (cw *csvReportWriter) row(line models.ListGroupUsersType) []string {
+	row := []string{line.GroupName, line.UserEmail}
+	for _, column := range cw.columns {
+		row = append(row, reportColumnValue(line, column))
+	}
+	return row
+}
+
+// orderReportColumns returns the requested optional columns in the report's column order
+func orderReportColumns(columns map[string]bool) []string {
+	var orderedColumns []string
+	for _, column := range models.ReportColumns {
+		if columns[column] {
+			orderedColumns = append(orderedColumns, column)
+		}
+	}
+	return orderedColumns
+}
+
+// reportColumnValue returns the text of an optional column of a report line, which is blank when the value is unknown
+func reportColumnValue(line models.ListGroupUsersType, column string) string {
+	switch column {
+	case models.ReportColumnUserID:
+		return aws.ToString(line.UserID)
+	case models.ReportColumnForename:
+		return aws.ToString(line.Forename)
+	case models.ReportColumnLastname:
+		return aws.ToString(line.Lastname)
+	case models.ReportColumnActive:
+		if line.Active != nil {
+			return strconv.FormatBool(*line.Active)
+		}
+	case models.ReportColumnGroupID:
+		return aws.ToString(line.GroupID)
+	case models.ReportColumnPrecedence:
+		if line.Precedence != nil {
+			return strconv.Itoa(int(*line.Precedence))
+		}
+	case models.ReportColumnJoined:
+		if line.Joined != nil {
+			return line.Joined.Format(time.RFC3339)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListGroupsUsersHandlerQuery(t *testing.T) {
+	Convey("Given three groups where group_N has N+1 members and user_1 is inactive", t, func() {
+		api, _, m := apiMockSetup()
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			output := listGroups(3)
+			for i := range output.Groups {
+				output.Groups[i].Precedence = aws.Int32(int32(10 + i))
+			}
+			return &output, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			l, _ := strconv.Atoi((*input.GroupName)[len(*input.GroupName)-1:])
+			output := listGroupsUsers(l + 1)
+			for i := range output.Users {
+				output.Users[i].Enabled = aws.ToString(output.Users[i].Username) != "user_1"
+			}
+			return output, nil
+		}
+		handler := contextAndErrors(api.ListGroupsUsersHandler)
+
+		getReport := func(query, accept string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:25600/v1/groups-report"+query, http.NoBody)
+			if accept != "" {
+				r.Header.Set("Accept", accept)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			return w
+		}
+
+		reportLines := func(w *httptest.ResponseRecorder) []models.ListGroupUsersType {
+			var lines []models.ListGroupUsersType
+			So(json.Unmarshal(w.Body.Bytes(), &lines), ShouldBeNil)
+			return lines
+		}
+
+		Convey("When the report is limited to some groups", func() {
+			w := getReport("?group_id=group_0&group_id=group_2,unknown", "")
+
+			Convey("Then only those groups are reported", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				lines := reportLines(w)
+				So(lines, ShouldHaveLength, 4)
+				So(lines[0].GroupName, ShouldEqual, "group 0 description")
+				So(lines[1].GroupName, ShouldEqual, "group 2 description")
+			})
+		})
+
+		Convey("When the report is limited to inactive users", func() {
+			w := getReport("?active=false", "")
+
+			Convey("Then only the inactive user's memberships are reported", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				lines := reportLines(w)
+				So(lines, ShouldHaveLength, 2)
+				for _, line := range lines {
+					So(line.UserEmail, ShouldEqual, "user_1.email@domain.test")
+				}
+			})
+		})
+
+		Convey("When optional columns are requested", func() {
+			approved := time.Date(2024, 5, 13, 12, 0, 0, 0, time.UTC)
+			So(api.AccessRequests.CreateAccessRequest(ctx, models.AccessRequest{ID: "request-1", GroupID: "group_1", UserID: "user_1", Status: models.AccessRequestApproved, Decided: &approved}), ShouldBeNil)
+			w := getReport("?group_id=group_1&columns=user_id,active,precedence,joined", "")
+
+			Convey("Then the lines include them, with the joined time where it is known", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				lines := reportLines(w)
+				So(lines, ShouldHaveLength, 2)
+				So(*lines[0].UserID, ShouldEqual, "user_0")
+				So(*lines[0].Active, ShouldBeTrue)
+				So(*lines[0].Precedence, ShouldEqual, 11)
+				So(lines[0].Joined, ShouldBeNil)
+				So(lines[0].Forename, ShouldBeNil)
+				So(*lines[1].UserID, ShouldEqual, "user_1")
+				So(*lines[1].Active, ShouldBeFalse)
+				So(*lines[1].Joined, ShouldEqual, approved)
+			})
+		})
+
+		Convey("When optional columns are requested as CSV", func() {
+			w := getReport("?group_id=group_1&columns=precedence,user_id", "text/csv")
+
+			Convey("Then they are added after the group and user in the report's column order", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "text/csv")
+				So(w.Body.String(), ShouldEqual, "Group,User,User ID,Precedence\n"+
+					"group 1 description,user_0.email@domain.test,user_0,11\n"+
+					"group 1 description,user_1.email@domain.test,user_1,11\n")
+			})
+		})
+
+		Convey("When an unknown column is requested", func() {
+			w := getReport("?columns=user_id,password", "")
+
+			Convey("Then the request is refused", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, models.InvalidReportColumnDescription)
+			})
+		})
+
+		Convey("When the active filter is not a boolean", func() {
+			w := getReport("?active=maybe", "")
+
+			Convey("Then the request is refused", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+}
+
+func TestListGroupsUsersHandlerStreaming(t *testing.T) {
+	Convey("Given fetching the members of the third group fails", t, func() {
+		api, _, m := apiMockSetup()
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			output := listGroups(3)
+			return &output, nil
+		}
+		var mu sync.Mutex
+		var fetched []string
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			mu.Lock()
+			fetched = append(fetched, *input.GroupName)
+			mu.Unlock()
+			if *input.GroupName == "group_2" {
+				return nil, errors.New("cognito unavailable")
+			}
+			return listGroupsUsers(1), nil
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:25600/v1/groups-report", http.NoBody)
+		r.Header.Set("Accept", "text/csv")
+
+		Convey("When the report is requested", func() {
+			successResponse, errorResponse := api.ListGroupsUsersHandler(ctx, httptest.NewRecorder(), r)
+
+			Convey("Then the first group is fetched before the response is returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusOK)
+				mu.Lock()
+				So(fetched, ShouldContain, "group_0")
+				mu.Unlock()
+
+				Convey("And writing the report out returns the groups in order, stopping at the failure", func() {
+					body := new(strings.Builder)
+					err := successResponse.Stream(body)
+					So(err, ShouldNotBeNil)
+					So(body.String(), ShouldEqual, fmt.Sprintf("Group,User\n%s\n%s\n",
+						"group 0 description,user_0.email@domain.test",
+						"group 1 description,user_0.email@domain.test"))
+				})
+			})
+		})
+
+		Convey("When the report is served the connection is aborted at the failure, so the client sees it is incomplete", func() {
+			server := httptest.NewServer(contextAndErrors(api.ListGroupsUsersHandler))
+			defer server.Close()
+
+			request, err := http.NewRequest(http.MethodGet, server.URL+"/v1/groups-report", http.NoBody)
+			So(err, ShouldBeNil)
+			request.Header.Set("Accept", "text/csv")
+			response, err := server.Client().Do(request)
+			So(err, ShouldBeNil)
+			defer response.Body.Close()
+			So(response.StatusCode, ShouldEqual, http.StatusOK)
+
+			body, err := io.ReadAll(response.Body)
+			So(err, ShouldEqual, io.ErrUnexpectedEOF)
+			So(string(body), ShouldStartWith, "Group,User\n")
+		})
+	})
+}
+
+func TestTeamsReportConcurrency(t *testing.T) {
+	Convey("Given twelve groups whose members take less time to fetch for later groups", t, func() {
+		api, _, m := apiMockSetup()
+		api.GroupsReportConcurrency = 3
+		groupsList := listGroups(12)
+
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+
+			i, _ := strconv.Atoi(strings.TrimPrefix(*input.GroupName, "group_"))
+			time.Sleep(time.Duration(12-i) * time.Millisecond)
+			return listGroupsUsers(1), nil
+		}
+
+		Convey("When the report lines are requested", func() {
+			lines, err := api.GetTeamsReportLines(ctx, &groupsList, false)
+
+			Convey("Then the groups are fetched concurrently, no more than the concurrency level at once", func() {
+				So(err, ShouldBeNil)
+				So(maxInFlight, ShouldBeGreaterThan, 1)
+				So(maxInFlight, ShouldBeLessThanOrEqualTo, 3)
+			})
+
+			Convey("Then the lines are in the order of the groups", func() {
+				So(*lines, ShouldHaveLength, 12)
+				for i, line := range *lines {
+					So(line.GroupName, ShouldEqual, fmt.Sprintf("group %d description", i))
+				}
+			})
+		})
+	})
+
+	Convey("Given Cognito throttles requests for a group's members", t, func() {
+		api, _, m := apiMockSetup()
+		groupsList := listGroups(2)
+
+		var mu sync.Mutex
+		throttled := 0
+		throttle := 1
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if *input.GroupName == "group_1" && throttled < throttle {
+				throttled++
+				return nil, &types.TooManyRequestsException{Message: aws.String("Too many requests")}
+			}
+			return listGroupsUsers(1), nil
+		}
+
+		report := api.newTeamsReport(&groupsList, groupsReportQuery{})
+		report.backoffSchedule = []time.Duration{time.Millisecond, time.Millisecond}
+		defer report.close()
+
+		readReport := func() ([]groupsReportGroup, error) {
+			var groups []groupsReportGroup
+			for {
+				group, more, err := report.nextGroup(ctx)
+				if err != nil {
+					return groups, err
+				}
+				if !more {
+					return groups, nil
+				}
+				groups = append(groups, group)
+			}
+		}
+
+		Convey("When the throttling stops before the backoff schedule runs out", func() {
+			groups, err := readReport()
+
+			Convey("Then the request is retried and the group is reported", func() {
+				So(err, ShouldBeNil)
+				So(throttled, ShouldEqual, 1)
+				So(groups, ShouldHaveLength, 2)
+				So(groups[1].ID, ShouldEqual, "group_1")
+				So(groups[1].Lines, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the throttling outlasts the backoff schedule", func() {
+			throttle = 10
+			groups, err := readReport()
+
+			Convey("Then the report ends with the throttling error after the groups before it", func() {
+				So(err, ShouldNotBeNil)
+				So(throttled, ShouldEqual, 3)
+				So(groups, ShouldHaveLength, 1)
+				So(groups[0].ID, ShouldEqual, "group_0")
+			})
+		})
+	})
+}
+
+func TestTeamsReportNestedMembers(t *testing.T) {
+	Convey("Given group_2 is nested in group_0", t, func() {
+		api, _, m := apiMockSetup()
+		So(api.GroupHierarchy.AddChildGroup(ctx, "group_0", "group_2"), ShouldBeNil)
+		groupsList := listGroups(3)
+
+		var mu sync.Mutex
+		fetched := map[string]int{}
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			mu.Lock()
+			fetched[*input.GroupName]++
+			mu.Unlock()
+			return listGroupsUsers(1), nil
+		}
+
+		readReport := func(report *teamsReport) {
+			defer report.close()
+			for {
+				_, more, err := report.nextGroup(ctx)
+				So(err, ShouldBeNil)
+				if !more {
+					return
+				}
+			}
+		}
+
+		Convey("When nested membership is not expanded no members are kept", func() {
+			report := api.newTeamsReport(&groupsList, groupsReportQuery{})
+			readReport(report)
+			So(report.membersByGroup, ShouldBeNil)
+			So(fetched, ShouldResemble, map[string]int{"group_0": 1, "group_1": 1, "group_2": 1})
+		})
+
+		Convey("When nested membership is expanded only the nested group's members are kept", func() {
+			report := api.newTeamsReport(&groupsList, groupsReportQuery{expandNested: true})
+			readReport(report)
+			So(report.membersByGroup, ShouldHaveLength, 1)
+			So(report.membersByGroup, ShouldContainKey, "group_2")
+		})
+	})
+
+	Convey("Given a group nested in several groups that are reported at once", t, func() {
+		api, _, m := apiMockSetup()
+		api.GroupsReportConcurrency = 4
+		for _, parent := range []string{"group_0", "group_1", "group_2", "group_3"} {
+			So(api.GroupHierarchy.AddChildGroup(ctx, parent, "group_4"), ShouldBeNil)
+		}
+		groupsList := listGroups(4)
+
+		var mu sync.Mutex
+		fetched := map[string]int{}
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			mu.Lock()
+			fetched[*input.GroupName]++
+			mu.Unlock()
+			if *input.GroupName == "group_4" {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return listGroupsUsers(1), nil
+		}
+
+		Convey("When the report lines are requested with nested membership expanded", func() {
+			lines, err := api.GetTeamsReportLines(ctx, &groupsList, true)
+
+			Convey("Then the nested group's members are fetched once", func() {
+				So(err, ShouldBeNil)
+				So(*lines, ShouldHaveLength, 4)
+				So(fetched["group_4"], ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestListGroupsUsersHandlerFormats(t *testing.T) {
+	Convey("Given two groups with members", t, func() {
+		api, _, m := apiMockSetup()
+		m.ListGroupsFunc = func(_ context.Context, _ *cognitoidentityprovider.ListGroupsInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListGroupsOutput, error) {
+			output := listGroups(2)
+			return &output, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			l, _ := strconv.Atoi((*input.GroupName)[len(*input.GroupName)-1:])
+			return listGroupsUsers(l + 1), nil
+		}
+		handler := contextAndErrors(api.ListGroupsUsersHandler)
+
+		getReport := func(accept string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:25600/v1/groups-report", http.NoBody)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			return w
+		}
+
+		Convey("When the report is requested as newline delimited JSON", func() {
+			w := getReport(groupsReportNDJSON)
+
+			Convey("Then each line is a JSON object on its own line, in an attachment", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, groupsReportNDJSON)
+				So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=groups-report.ndjson")
+				So(w.Body.String(), ShouldEqual, `{"group":"group 0 description","user":"user_0.email@domain.test"}`+"\n"+
+					`{"group":"group 1 description","user":"user_0.email@domain.test"}`+"\n"+
+					`{"group":"group 1 description","user":"user_1.email@domain.test"}`+"\n")
+			})
+		})
+
+		Convey("When the report is requested as an Excel workbook", func() {
+			w := getReport(groupsReportXLSX)
+
+			Convey("Then a workbook is returned in an attachment", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, groupsReportXLSX)
+				So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=groups-report.xlsx")
+				So(w.Body.Bytes()[:2], ShouldResemble, []byte("PK"))
+			})
+		})
+
+		Convey("When the report is requested as CSV", func() {
+			w := getReport(groupsReportCSV)
+
+			Convey("Then it is returned in an attachment", func() {
+				So(w.Header().Get("Content-Type"), ShouldEqual, groupsReportCSV)
+				So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=groups-report.csv")
+			})
+		})
+
+		Convey("When several formats are accepted", func() {
+			w := getReport("text/html, application/json;q=0.5, application/x-ndjson;q=0.8, text/csv;q=0.1")
+
+			Convey("Then the most preferred format is returned", func() {
+				So(w.Header().Get("Content-Type"), ShouldEqual, groupsReportNDJSON)
+			})
+		})
+
+		Convey("When no known format is accepted", func() {
+			w := getReport("text/html")
+
+			Convey("Then the report is JSON and not an attachment", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, groupsReportJSON)
+				So(w.Header().Get("Content-Disposition"), ShouldBeEmpty)
+			})
+		})
+	})
+}
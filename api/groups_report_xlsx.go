@@ -0,0 +1,246 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+const (
+	// xlsxMaxSheetNameLength is the longest sheet name that spreadsheet applications accept
+	xlsxMaxSheetNameLength = 31
+	xlsxSummarySheetName   = "Summary"
+
+	xlsxXMLHeader             = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	xlsxSpreadsheetNamespace  = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelationshipNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageRelNamespace   = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// xlsxSheetNameReplacer replaces the characters that are not allowed in sheet names
+var xlsxSheetNameReplacer = strings.NewReplacer(":", " ", `\`, " ", "/", " ", "?", " ", "*", " ", "[", "(", "]", ")")
+
+// xlsxSheet is a group's sheet in the groups report workbook
+type xlsxSheet struct {
+	name    string
+	group   string
+	groupID string
+	members int
+}
+
+// xlsxCell is a cell of a worksheet row, cellType is the SpreadsheetML cell type
+type xlsxCell struct {
+	value    string
+	cellType string
+}
+
+// xlsxReportWriter writes the groups report as an Excel workbook with a sheet for each group with members and a
+// summary sheet listing the groups. Each group's sheet is written as it is fetched, and the summary and the parts of
+// the workbook that list the sheets are written at the end.
+type xlsxReportWriter struct {
+	zw      *zip.Writer
+	columns []string
+	sheets  []xlsxSheet
+	// names are the sheet names in use, in lower case as sheet names are compared ignoring case
+	names map[string]bool
+}
+
+func newXLSXReportWriter(columns map[string]bool) func(w io.Writer) groupsReportWriter {
+	orderedColumns := orderReportColumns(columns)
+	return func(w io.Writer) groupsReportWriter {
+		return &xlsxReportWriter{
+			zw:      zip.NewWriter(w),
+			columns: orderedColumns,
+			// History is reserved by Excel
+			names: map[string]bool{strings.ToLower(xlsxSummarySheetName): true, "history": true},
+		}
+	}
+}
+
+func (xw *xlsxReportWriter) Begin() error {
+	return xw.writePart("_rels/.rels", xlsxXMLHeader+
+		`<Relationships xmlns="`+xlsxPackageRelNamespace+`">`+
+		`<Relationship Id="rId1" Type="`+xlsxRelationshipNamespace+`/officeDocument" Target="xl/workbook.xml"/>`+
+		`</Relationships>`)
+}
+
+func (xw *xlsxReportWriter) WriteGroup(group groupsReportGroup) error {
+	if len(group.Lines) == 0 {
+		return nil
+	}
+
+	rows := [][]xlsxCell{xw.headerRow()}
+	for i := range group.Lines {
+		rows = append(rows, xw.row(group.Lines[i]))
+	}
+	// the summary is the first sheet
+	if err := xw.writeSheet(len(xw.sheets)+2, rows); err != nil {
+		return err
+	}
+	xw.sheets = append(xw.sheets, xlsxSheet{name: xw.sheetName(group), group: group.Name, groupID: group.ID, members: len(group.Lines)})
+
+	return xw.zw.Flush()
+}
+
+func (xw *xlsxReportWriter) End() error {
+	summary := [][]xlsxCell{{stringCell("Group"), stringCell("Group ID"), stringCell("Members"), stringCell("Sheet")}}
+	for _, sheet := range xw.sheets {
+		summary = append(summary, []xlsxCell{
+			stringCell(sheet.group),
+			stringCell(sheet.groupID),
+			{value: strconv.Itoa(sheet.members), cellType: "n"},
+			stringCell(sheet.name),
+		})
+	}
+	if err := xw.writeSheet(1, summary); err != nil {
+		return err
+	}
+
+	workbook := `<workbook xmlns="` + xlsxSpreadsheetNamespace + `" xmlns:r="` + xlsxRelationshipNamespace + `"><sheets>`
+	relationships := `<Relationships xmlns="` + xlsxPackageRelNamespace + `">`
+	contentTypes := `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`
+	names := append([]string{xlsxSummarySheetName}, make([]string, len(xw.sheets))...)
+	for i, sheet := range xw.sheets {
+		names[i+1] = sheet.name
+	}
+	for i, name := range names {
+		n := i + 1
+		workbook += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(name), n, n)
+		relationships += fmt.Sprintf(`<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, n, xlsxRelationshipNamespace, n)
+		contentTypes += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+
+	if err := xw.writePart("xl/workbook.xml", xlsxXMLHeader+workbook+`</sheets></workbook>`); err != nil {
+		return err
+	}
+	if err := xw.writePart("xl/_rels/workbook.xml.rels", xlsxXMLHeader+relationships+`</Relationships>`); err != nil {
+		return err
+	}
+	if err := xw.writePart("[Content_Types].xml", xlsxXMLHeader+contentTypes+`</Types>`); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
+
+// headerRow returns the header row of a group's sheet, which has no group column as the sheet is the group's
+func (xw *xlsxReportWriter) headerRow() []xlsxCell {
+	header := []xlsxCell{stringCell("User")}
+	for _, column := range xw.columns {
+		header = append(header, stringCell(groupsReportCSVHeaders[column]))
+	}
+	return header
+}
+
+// row returns the cells of a report line, with numbers and booleans typed so that they can be sorted and filtered
+func (xw *xlsxReportWriter) row(line models.ListGroupUsersType) []xlsxCell {
+	row := []xlsxCell{stringCell(line.UserEmail)}
+	for _, column := range xw.columns {
+		value := reportColumnValue(line, column)
+		switch {
+		case value == "":
+			row = append(row, xlsxCell{})
+		case column == models.ReportColumnPrecedence:
+			row = append(row, xlsxCell{value: value, cellType: "n"})
+		case column == models.ReportColumnActive:
+			active := "0"
+			if *line.Active {
+				active = "1"
+			}
+			row = append(row, xlsxCell{value: active, cellType: "b"})
+		default:
+			row = append(row, stringCell(value))
+		}
+	}
+	return row
+}
+
+// sheetName returns a sheet name for a group that is allowed and not already in use. The group's name is used where
+// possible, shortened and numbered as needed.
+func (xw *xlsxReportWriter) sheetName(group groupsReportGroup) string {
+	name := strings.Trim(xlsxSheetNameReplacer.Replace(group.Name), "' ")
+	if name == "" {
+		name = strings.Trim(xlsxSheetNameReplacer.Replace(group.ID), "' ")
+	}
+	if name == "" {
+		name = "Group"
+	}
+
+	candidate := truncateRunes(name, xlsxMaxSheetNameLength)
+	for n := 2; xw.names[strings.ToLower(candidate)]; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncateRunes(name, xlsxMaxSheetNameLength-len(suffix)) + suffix
+	}
+	xw.names[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// writeSheet writes the worksheet with the number, one row to each slice of cells
+func (xw *xlsxReportWriter) writeSheet(number int, rows [][]xlsxCell) error {
+	var sheet strings.Builder
+	sheet.WriteString(xlsxXMLHeader + `<worksheet xmlns="` + xlsxSpreadsheetNamespace + `"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumnName(c) + strconv.Itoa(r+1)
+			switch cell.cellType {
+			case "":
+			case "inlineStr":
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xlsxEscape(cell.value))
+			default:
+				fmt.Fprintf(&sheet, `<c r="%s" t="%s"><v>%s</v></c>`, ref, cell.cellType, cell.value)
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	return xw.writePart(fmt.Sprintf("xl/worksheets/sheet%d.xml", number), sheet.String())
+}
+
+// writePart writes a part of the workbook package
+func (xw *xlsxReportWriter) writePart(name, content string) error {
+	part, err := xw.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+func stringCell(value string) xlsxCell {
+	return xlsxCell{value: value, cellType: "inlineStr"}
+}
+
+// xlsxColumnName returns the letters of the zero based column, A to Z then AA onwards
+func xlsxColumnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxEscape escapes text for use in the workbook's XML, replacing characters that XML does not allow
+func xlsxEscape(s string) string {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimRight(string(runes[:n]), " ")
+}
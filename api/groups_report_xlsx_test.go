@@ -0,0 +1,147 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// xlsxTestWorkbook is the part of a workbook's XML that lists its sheets
+type xlsxTestWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxTestWorksheet is the part of a worksheet's XML that holds its cells
+type xlsxTestWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// cells returns the worksheet's cell values by reference
+func (ws xlsxTestWorksheet) cells() map[string]string {
+	cells := map[string]string{}
+	for _, row := range ws.Rows {
+		for _, cell := range row.Cells {
+			if cell.Type == "inlineStr" {
+				cells[cell.Ref] = cell.Inline
+			} else {
+				cells[cell.Ref] = cell.Value
+			}
+		}
+	}
+	return cells
+}
+
+func TestXLSXReportWriter(t *testing.T) {
+	Convey("Given a workbook written for groups with long, clashing and empty names", t, func() {
+		longName := "A group with a name that is longer than a sheet name can be"
+		groups := []groupsReportGroup{
+			{ID: "group-1", Name: "Editors: <UK>", Lines: []models.ListGroupUsersType{
+				{GroupName: "Editors: <UK>", UserEmail: "a@domain.test", UserID: aws.String("a"), Active: aws.Bool(true), Precedence: aws.Int32(12)},
+				{GroupName: "Editors: <UK>", UserEmail: "b@domain.test", UserID: aws.String("b"), Active: aws.Bool(false), Precedence: aws.Int32(12)},
+			}},
+			{ID: "group-2", Name: "Empty", Lines: []models.ListGroupUsersType{}},
+			{ID: "group-3", Name: longName, Lines: []models.ListGroupUsersType{{GroupName: longName, UserEmail: "c@domain.test"}}},
+			{ID: "group-4", Name: longName, Lines: []models.ListGroupUsersType{{GroupName: longName, UserEmail: "d@domain.test"}}},
+			{ID: "group-5", Name: "summary", Lines: []models.ListGroupUsersType{{GroupName: "summary", UserEmail: "e@domain.test"}}},
+			{ID: "group-6", Lines: []models.ListGroupUsersType{{UserEmail: "f@domain.test"}}},
+		}
+
+		buf := new(bytes.Buffer)
+		writer := newXLSXReportWriter(map[string]bool{models.ReportColumnPrecedence: true, models.ReportColumnUserID: true, models.ReportColumnActive: true})(buf)
+		So(writer.Begin(), ShouldBeNil)
+		for _, group := range groups {
+			So(writer.WriteGroup(group), ShouldBeNil)
+		}
+		So(writer.End(), ShouldBeNil)
+
+		workbook, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		So(err, ShouldBeNil)
+
+		readPart := func(name string, v interface{}) {
+			part, err := workbook.Open(name)
+			So(err, ShouldBeNil)
+			defer part.Close()
+			content, err := io.ReadAll(part)
+			So(err, ShouldBeNil)
+			So(xml.Unmarshal(content, v), ShouldBeNil)
+		}
+
+		Convey("Then it has the parts of a workbook", func() {
+			for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+				_, err := workbook.Open(name)
+				So(err, ShouldBeNil)
+			}
+		})
+
+		Convey("Then there is a summary sheet and then a sheet for each group with members, with allowed unique names", func() {
+			var wb xlsxTestWorkbook
+			readPart("xl/workbook.xml", &wb)
+			var names []string
+			for _, sheet := range wb.Sheets {
+				names = append(names, sheet.Name)
+			}
+			So(names, ShouldResemble, []string{
+				"Summary",
+				"Editors  <UK>",
+				"A group with a name that is lon",
+				"A group with a name that is (2)",
+				"summary (2)",
+				"group-6",
+			})
+			So(wb.Sheets[1].ID, ShouldEqual, "rId2")
+		})
+
+		Convey("Then the summary lists the groups with their member counts and sheets", func() {
+			var ws xlsxTestWorksheet
+			readPart("xl/worksheets/sheet1.xml", &ws)
+			cells := ws.cells()
+			So(ws.Rows, ShouldHaveLength, 6)
+			So(cells["A1"], ShouldEqual, "Group")
+			So(cells["A2"], ShouldEqual, "Editors: <UK>")
+			So(cells["B2"], ShouldEqual, "group-1")
+			So(cells["C2"], ShouldEqual, "2")
+			So(cells["D2"], ShouldEqual, "Editors  <UK>")
+			So(cells["B3"], ShouldEqual, "group-3")
+		})
+
+		Convey("Then a group's sheet has a line for each member with the requested columns", func() {
+			var ws xlsxTestWorksheet
+			readPart("xl/worksheets/sheet2.xml", &ws)
+			cells := ws.cells()
+			So(ws.Rows, ShouldHaveLength, 3)
+			So([]string{cells["A1"], cells["B1"], cells["C1"], cells["D1"]}, ShouldResemble, []string{"User", "User ID", "Active", "Precedence"})
+			So([]string{cells["A3"], cells["B3"], cells["C3"], cells["D3"]}, ShouldResemble, []string{"b@domain.test", "b", "0", "12"})
+			So(ws.Rows[2].Cells[2].Type, ShouldEqual, "b")
+			So(ws.Rows[2].Cells[3].Type, ShouldEqual, "n")
+		})
+	})
+
+	Convey("Columns are named with letters", t, func() {
+		So(xlsxColumnName(0), ShouldEqual, "A")
+		So(xlsxColumnName(25), ShouldEqual, "Z")
+		So(xlsxColumnName(26), ShouldEqual, "AA")
+		So(xlsxColumnName(701), ShouldEqual, "ZZ")
+		So(xlsxColumnName(702), ShouldEqual, "AAA")
+	})
+
+	Convey("Sheet names are shortened without splitting characters", t, func() {
+		So(truncateRunes(strings.Repeat("é", 40), xlsxMaxSheetNameLength), ShouldEqual, strings.Repeat("é", 31))
+	})
+}
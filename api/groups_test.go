@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
@@ -1057,6 +1058,9 @@ func TestUpdateGroup(t *testing.T) {
 	)
 
 	api, w, m := apiMockSetup()
+	m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+		return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName, Description: aws.String("This is a test name")}}, nil
+	}
 
 	Convey("Update a group - check responses", t, func() {
 		createGroupTests := []struct {
@@ -1567,6 +1571,9 @@ func TestDeleteGroupHandler(t *testing.T) {
 		for _, tt := range DeleteGroupTest {
 			Convey(tt.description, func() {
 				m.DeleteGroupFunc = tt.DeleteGroupFunction
+				m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+					return &cognitoidentityprovider.ListUsersInGroupOutput{Users: []types.UserType{}}, nil
+				}
 
 				postBody := map[string]interface{}{"GroupName": "group_name_test"}
 				body, err := json.Marshal(postBody)
@@ -1587,6 +1594,155 @@ func TestDeleteGroupHandler(t *testing.T) {
 	})
 }
 
+func TestDeleteGroupHandler_Protection(t *testing.T) {
+	Convey("Given a group with members", t, func() {
+		api, w, m := apiMockSetup()
+
+		groupMembers := map[string][]string{
+			"test-group":     {"user-1", "user-2"},
+			"transfer-group": {"user-2"},
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			members, ok := groupMembers[*input.GroupName]
+			if !ok {
+				return nil, &types.ResourceNotFoundException{Message: &groupNotFoundDescription}
+			}
+			users := []types.UserType{}
+			for _, member := range members {
+				users = append(users, types.UserType{Username: aws.String(member)})
+			}
+			return &cognitoidentityprovider.ListUsersInGroupOutput{Users: users}, nil
+		}
+		var transferred []string
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			transferred = append(transferred, *input.GroupName+":"+*input.Username)
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		deleted := false
+		m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+			deleted = true
+			return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+		}
+
+		deleteGroup := func(groupID, query string) (*models.SuccessResponse, *models.ErrorResponse) {
+			r := httptest.NewRequest(http.MethodDelete, "http://localhost:25600/v1/groups/"+groupID+query, http.NoBody)
+			return api.DeleteGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": groupID}))
+		}
+
+		Convey("When it is deleted without force it is refused with a 409", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "")
+			So(successResponse, ShouldBeNil)
+			So(deleted, ShouldBeFalse)
+			So(errorResponse.Status, ShouldEqual, http.StatusConflict)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.GroupNotEmptyError)
+		})
+
+		Convey("When it is deleted with force it is deleted", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "?force=true")
+			So(errorResponse, ShouldBeNil)
+			So(successResponse.Status, ShouldEqual, http.StatusNoContent)
+			So(deleted, ShouldBeTrue)
+		})
+
+		Convey("When it is deleted with its members transferred to another group", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "?transfer_to=transfer-group")
+
+			Convey("Then the members not already in the other group are added to it before the group is deleted", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusNoContent)
+				So(transferred, ShouldResemble, []string{"transfer-group:user-1"})
+				So(deleted, ShouldBeTrue)
+			})
+		})
+
+		Convey("When a temporary member is transferred to another group", func() {
+			expiresAt := time.Now().Add(time.Hour).UTC()
+			So(api.MembershipExpiry.SetMembershipExpiry(ctx, "test-group", "user-1", expiresAt), ShouldBeNil)
+
+			successResponse, errorResponse := deleteGroup("test-group", "?transfer_to=transfer-group")
+
+			Convey("Then their membership of the other group expires at the same time", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusNoContent)
+				expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "transfer-group")
+				So(err, ShouldBeNil)
+				So(expiries, ShouldResemble, map[string]time.Time{"user-1": expiresAt})
+			})
+		})
+
+		Convey("When its members are transferred to a role group the request is refused with a 403", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "?transfer_to="+models.AdminRoleGroup)
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusForbidden)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.ProtectedGroupError)
+			So(castErr.Description, ShouldEqual, models.TransferToRoleGroupDescription)
+			So(transferred, ShouldBeEmpty)
+			So(deleted, ShouldBeFalse)
+		})
+
+		Convey("When Cognito fails to delete it its owners and nesting are kept", func() {
+			So(api.GroupOwners.AddGroupOwner(ctx, "test-group", "user-1"), ShouldBeNil)
+			So(api.GroupHierarchy.AddChildGroup(ctx, "test-group", "child-group"), ShouldBeNil)
+			m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+				return nil, errors.New("delete failed")
+			}
+
+			successResponse, errorResponse := deleteGroup("test-group", "?force=true")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			owners, err := api.GroupOwners.ListGroupOwners(ctx, "test-group")
+			So(err, ShouldBeNil)
+			So(owners, ShouldResemble, []string{"user-1"})
+			children, err := api.GroupHierarchy.ListChildGroups(ctx, "test-group")
+			So(err, ShouldBeNil)
+			So(children, ShouldResemble, []string{"child-group"})
+		})
+
+		Convey("When its members are transferred to a group that does not exist it is not deleted", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "?transfer_to=unknown-group")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(deleted, ShouldBeFalse)
+		})
+
+		Convey("When its members are transferred to itself the request is rejected", func() {
+			successResponse, errorResponse := deleteGroup("test-group", "?transfer_to=test-group")
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Description, ShouldEqual, models.TransferToSameGroupDescription)
+			So(deleted, ShouldBeFalse)
+		})
+
+		Convey("When force is not a boolean the request is rejected", func() {
+			_, errorResponse := deleteGroup("test-group", "?force=yes-please")
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(deleted, ShouldBeFalse)
+		})
+	})
+
+	Convey("The role groups cannot be deleted, even with force", t, func() {
+		api, w, m := apiMockSetup()
+		deleted := false
+		m.DeleteGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.DeleteGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+			deleted = true
+			return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+		}
+
+		for _, groupID := range []string{models.AdminRoleGroup, models.PublisherRoleGroup} {
+			r := httptest.NewRequest(http.MethodDelete, "http://localhost:25600/v1/groups/"+groupID+"?force=true", http.NoBody)
+			successResponse, errorResponse := api.DeleteGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": groupID}))
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusForbidden)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.ProtectedGroupError)
+		}
+		So(deleted, ShouldBeFalse)
+	})
+}
+
 func TestSetGroupUsersHandler(t *testing.T) {
 	var (
 		name1     = "user-1"
@@ -1904,7 +2060,7 @@ func TestSetGroupUsers(t *testing.T) {
 			mockListUsersInGroupfunc      func(ctx context.Context, input *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error)
 			group                         models.Group
 			users                         models.UsersList
-			assertions                    func(successResponse *models.UsersList, errorResponse *models.ErrorResponse)
+			assertions                    func(successResponse *models.GroupMembershipDiff, errorResponse *models.ErrorResponse)
 		}{
 			{
 				"200 response from Cognito  with input and output",
@@ -1947,7 +2103,7 @@ func TestSetGroupUsers(t *testing.T) {
 					Users: []models.UserParams{{ID: "user_1"}},
 					Count: 1,
 				},
-				func(successResponse *models.UsersList, _ *models.ErrorResponse) {
+				func(successResponse *models.GroupMembershipDiff, _ *models.ErrorResponse) {
 					So(successResponse, ShouldNotBeNil)
 				},
 			},
@@ -1981,7 +2137,7 @@ func TestSetGroupUsers(t *testing.T) {
 					Users: []models.UserParams{{ID: "user_1"}},
 					Count: 1,
 				},
-				func(successResponse *models.UsersList, errorResponse *models.ErrorResponse) {
+				func(successResponse *models.GroupMembershipDiff, errorResponse *models.ErrorResponse) {
 					So(errorResponse.Status, ShouldNotBeNil)
 					So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
 					castErr := errorResponse.Errors[0].(*models.Error)
@@ -2025,7 +2181,7 @@ func TestSetGroupUsers(t *testing.T) {
 					Users: []models.UserParams{{ID: "user_1"}},
 					Count: 1,
 				},
-				func(successResponse *models.UsersList, errorResponse *models.ErrorResponse) {
+				func(successResponse *models.GroupMembershipDiff, errorResponse *models.ErrorResponse) {
 					So(errorResponse.Status, ShouldNotBeNil)
 					So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
 					castErr := errorResponse.Errors[0].(*models.Error)
@@ -2041,13 +2197,108 @@ func TestSetGroupUsers(t *testing.T) {
 				m.AdminAddUserToGroupFunc = tt.mockAddUserToGroupfunc
 				m.AdminRemoveUserFromGroupFunc = tt.mockRemoveUserToGroupFunc
 				m.ListUsersInGroupFunc = tt.mockListUsersInGroupfunc
-				successResponse, errorResponse := api.SetGroupUsers(ctx, tt.group, tt.users)
+				successResponse, errorResponse := api.SetGroupUsers(ctx, tt.group, tt.users, false)
 				tt.assertions(successResponse, errorResponse)
 			})
 		}
 	})
 }
 
+func TestSetGroupUsersDiff(t *testing.T) {
+	Convey("Given a group with members user-1 and user-2", t, func() {
+		api, w, m := apiMockSetup()
+
+		members := []string{"user-1", "user-2"}
+		m.GetGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: aws.String("test-group")}}, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			users := []types.UserType{}
+			for _, member := range members {
+				users = append(users, types.UserType{Username: aws.String(member), Enabled: true, UserStatus: types.UserStatusTypeConfirmed})
+			}
+			return &cognitoidentityprovider.ListUsersInGroupOutput{Users: users}, nil
+		}
+		var added, removed []string
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			if *input.Username == "user-4" {
+				return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+			}
+			added = append(added, *input.Username)
+			members = append(members, *input.Username)
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		m.AdminRemoveUserFromGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminRemoveUserFromGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+			removed = append(removed, *input.Username)
+			members = []string{"user-2"}
+			return &cognitoidentityprovider.AdminRemoveUserFromGroupOutput{}, nil
+		}
+
+		setGroupUsers := func(query string) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := json.Marshal([]map[string]string{{"user_id": "user-2"}, {"user_id": "user-3"}, {"user_id": "user-4"}})
+			r := httptest.NewRequest(http.MethodPut, addUserToGroupEndPoint+query, bytes.NewReader(body))
+			r = mux.SetURLVars(r, map[string]string{"id": "test-group"})
+			return api.SetGroupUsersHandler(ctx, w, r)
+		}
+
+		Convey("When the membership is set as a dry run", func() {
+			successResponse, errorResponse := setGroupUsers("?dry_run=true")
+
+			Convey("Then the diff is returned without changing the group", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusOK)
+				So(added, ShouldBeEmpty)
+				So(removed, ShouldBeEmpty)
+
+				var diff models.GroupMembershipDiff
+				So(json.Unmarshal(successResponse.Body, &diff), ShouldBeNil)
+				So(diff.DryRun, ShouldBeTrue)
+				So(diff.UsersList, ShouldBeNil)
+				So(diff.ToAdd, ShouldResemble, []models.GroupMembershipChange{
+					{UserID: "user-3", Status: models.MembershipChangePending},
+					{UserID: "user-4", Status: models.MembershipChangePending},
+				})
+				So(diff.ToRemove, ShouldResemble, []models.GroupMembershipChange{{UserID: "user-1", Status: models.MembershipChangePending}})
+				So(diff.Unchanged, ShouldResemble, []string{"user-2"})
+			})
+		})
+
+		Convey("When the membership is set", func() {
+			successResponse, errorResponse := setGroupUsers("")
+
+			Convey("Then each user's outcome and the resulting members are returned", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusOK)
+				So(removed, ShouldResemble, []string{"user-1"})
+				So(added, ShouldResemble, []string{"user-3"})
+
+				var diff models.GroupMembershipDiff
+				So(json.Unmarshal(successResponse.Body, &diff), ShouldBeNil)
+				So(diff.DryRun, ShouldBeFalse)
+				So(diff.ToRemove, ShouldResemble, []models.GroupMembershipChange{{UserID: "user-1", Status: models.MembershipChangeSucceeded}})
+				So(diff.ToAdd[0], ShouldResemble, models.GroupMembershipChange{UserID: "user-3", Status: models.MembershipChangeSucceeded})
+				So(diff.ToAdd[1].UserID, ShouldEqual, "user-4")
+				So(diff.ToAdd[1].Status, ShouldEqual, models.MembershipChangeFailed)
+				So(diff.ToAdd[1].Error.Code, ShouldEqual, models.UserNotFoundError)
+				So(diff.Count, ShouldEqual, 2)
+				So(diff.Users[0].ID, ShouldEqual, "user-2")
+				So(diff.Users[1].ID, ShouldEqual, "user-3")
+			})
+		})
+
+		Convey("When dry_run is not a boolean", func() {
+			successResponse, errorResponse := setGroupUsers("?dry_run=maybe")
+
+			Convey("Then the request is rejected", func() {
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.InvalidFilterQuery)
+			})
+		})
+	})
+}
+
 func TestRemoveUserFromGroup(t *testing.T) {
 	var (
 		userID = "abcd1234"
@@ -2849,9 +3100,20 @@ func TestSortUsers(t *testing.T) {
 	})
 }
 
+// responseBody returns the body of a response, writing it out in place of the stream if it is streamed, as a stream
+// can only be written once
+func responseBody(successResponse *models.SuccessResponse) []byte {
+	if successResponse.Stream != nil {
+		buf := new(bytes.Buffer)
+		So(successResponse.Stream(buf), ShouldBeNil)
+		successResponse.Body, successResponse.Stream = buf.Bytes(), nil
+	}
+	return successResponse.Body
+}
+
 // isCSV will test that there is more than one slice and a header row
 func isCSV(successResponse *models.SuccessResponse, expectedLength int) bool {
-	testOutCSV := string(successResponse.Body)
+	testOutCSV := string(responseBody(successResponse))
 	stringSlice := strings.Split(testOutCSV, "\n")
 	if len(stringSlice) > 1 && stringSlice[0] == "Group,User" && len(stringSlice) == expectedLength {
 		return true
@@ -2862,7 +3124,7 @@ func isCSV(successResponse *models.SuccessResponse, expectedLength int) bool {
 // isJSON test that can be unmarshal into the given structure
 func isJSON(successResponse *models.SuccessResponse, expectedLength int) bool {
 	var testOutJSON []models.ListGroupUsersType
-	jsonErr := json.Unmarshal(successResponse.Body, &testOutJSON)
+	jsonErr := json.Unmarshal(responseBody(successResponse), &testOutJSON)
 	if expectedLength > 0 {
 		if jsonErr == nil && len(testOutJSON) == expectedLength {
 			return true
@@ -2936,7 +3198,7 @@ func TestGetTeamsReportLines(t *testing.T) {
 		for _, tt := range listGroupsUsers {
 			Convey(tt.description, func() {
 				m.ListUsersInGroupFunc = tt.listUsersForGroupFunc
-				groupMembershipList, errorResponse := api.GetTeamsReportLines(ctx, &tt.groupsList)
+				groupMembershipList, errorResponse := api.GetTeamsReportLines(ctx, &tt.groupsList, false)
 				tt.assertions(*groupMembershipList, errorResponse)
 			})
 		}
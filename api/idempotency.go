@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+var (
+	IdempotencyKeyHeaderName     = "Idempotency-Key"
+	IdempotentReplayedHeaderName = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength      = 255
+	DefaultIdempotencyKeyWindow  = 24 * time.Hour
+	// DefaultIdempotencyInProgressTTL is longer than a create request should take, so that a key is only used again
+	// while its request is in progress if that request was lost, such as when an instance of the API stopped
+	DefaultIdempotencyInProgressTTL = 2 * time.Minute
+	idempotencyKeyInUseRetryAfter   = "1"
+)
+
+// idempotent lets a create request be safely repeated by sending the same Idempotency-Key header. The response to the
+// first request with a key is saved for the idempotency window and returned again for repeats of the request, rather
+// than creating another user or group. Reusing a key for a different request is refused with a 422. Requests that fail
+// before the handler calls markChangesMade are not saved, so that they can be tried again with the same key, but a
+// failure after it is saved and returned like a response, so that the changes are not made twice. Keys are scoped to
+// the caller, so that one caller cannot replay or block another's requests by using the same key.
+func (api *API) idempotent(auth authorisation.Middleware, h baseHandler) baseHandler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+		idempotencyKey := req.Header.Get(IdempotencyKeyHeaderName)
+		if api.IdempotencyStore == nil || idempotencyKey == "" {
+			return h(ctx, w, req)
+		}
+		if len(idempotencyKey) > MaxIdempotencyKeyLength {
+			responseErr := models.NewValidationError(ctx, models.InvalidIdempotencyKeyError, models.IdempotencyKeyTooLongDescription)
+			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+		}
+
+		requestHash, err := hashRequest(req)
+		if err != nil {
+			return nil, handleBodyReadError(ctx, err)
+		}
+
+		key := req.URL.Path + ":" + idempotencyCaller(req, auth) + ":" + idempotencyKey
+		window := api.IdempotencyWindow
+		if window <= 0 {
+			window = DefaultIdempotencyKeyWindow
+		}
+		inProgressTTL := api.IdempotencyInProgressTTL
+		if inProgressTTL <= 0 {
+			inProgressTTL = DefaultIdempotencyInProgressTTL
+		}
+
+		record, started, err := api.IdempotencyStore.Start(ctx, key, requestHash, inProgressTTL)
+		if err != nil {
+			// an unavailable store should not stop users and groups being created, the request is just not protected
+			log.Error(ctx, "idempotency store request failed, handling request without it", err, log.Data{"path": req.URL.Path})
+			return h(ctx, w, req)
+		}
+		if !started {
+			switch {
+			case record.RequestHash != requestHash:
+				responseErr := models.NewValidationError(ctx, models.IdempotencyKeyMismatchError, models.IdempotencyKeyMismatchDescription)
+				return nil, models.NewErrorResponse(http.StatusUnprocessableEntity, nil, responseErr)
+			case record.Response == nil:
+				headers := map[string]string{RetryAfterHeaderName: idempotencyKeyInUseRetryAfter}
+				responseErr := models.NewValidationError(ctx, models.IdempotencyKeyInUseError, models.IdempotencyKeyInUseDescription)
+				return nil, models.NewErrorResponse(http.StatusConflict, headers, responseErr)
+			}
+			headers := maps.Clone(record.Response.Headers)
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			headers[IdempotentReplayedHeaderName] = "true"
+			return models.NewSuccessResponse(record.Response.Body, record.Response.Status, headers), nil
+		}
+
+		changesMade := false
+		response, errResponse := h(context.WithValue(ctx, idempotentChangesKey{}, &changesMade), w, req)
+		if errResponse != nil {
+			if changesMade {
+				api.completeFailedRequest(ctx, req, key, errResponse, window)
+				return nil, errResponse
+			}
+			if err := api.IdempotencyStore.Release(ctx, key); err != nil {
+				log.Error(ctx, "failed to release idempotency key", err, log.Data{"path": req.URL.Path})
+			}
+			return nil, errResponse
+		}
+		if err := api.IdempotencyStore.Complete(ctx, key, *response, window); err != nil {
+			log.Error(ctx, "failed to save response for idempotency key", err, log.Data{"path": req.URL.Path})
+		}
+		return response, nil
+	}
+}
+
+type idempotentChangesKey struct{}
+
+// markChangesMade records that a handler wrapped by idempotent has made a change, such as creating a user or group in
+// Cognito, that repeating the request would make again
+func markChangesMade(ctx context.Context) {
+	if changesMade, ok := ctx.Value(idempotentChangesKey{}).(*bool); ok {
+		*changesMade = true
+	}
+}
+
+// completeFailedRequest saves the error response to a request that failed after making changes, as its response
+func (api *API) completeFailedRequest(ctx context.Context, req *http.Request, key string, errResponse *models.ErrorResponse, window time.Duration) {
+	body, err := errorResponseBody(errResponse)
+	if err == nil {
+		err = api.IdempotencyStore.Complete(ctx, key, models.SuccessResponse{Body: body, Status: errResponse.Status, Headers: errResponse.Headers}, window)
+	}
+	if err != nil {
+		log.Error(ctx, "failed to save failed response for idempotency key", err, log.Data{"path": req.URL.Path})
+	}
+}
+
+// idempotencyCaller identifies the caller that an idempotency key belongs to: a user by the ID in their JWT, and a
+// service by a hash of its token, so that the token itself is not kept in the idempotency store
+func idempotencyCaller(req *http.Request, auth authorisation.Middleware) string {
+	if userID := parseCallerID(req, auth); userID != "" {
+		return "user:" + userID
+	}
+	authToken := strings.TrimPrefix(req.Header.Get(AccessTokenHeaderName), "Bearer ")
+	tokenHash := sha256.Sum256([]byte(authToken))
+	return "service:" + hex.EncodeToString(tokenHash[:])
+}
+
+// hashRequest returns a hash of the request's method, URI and body, leaving the body to be read again by the handler
+func hashRequest(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + "\n" + req.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
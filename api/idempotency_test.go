@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorisation "github.com/ONSdigital/dp-authorisation/v2/authorisation/mock"
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	permsdk "github.com/ONSdigital/dp-permissions-api/sdk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdempotent(t *testing.T) {
+	Convey("Given a create endpoint that accepts idempotency keys", t, func() {
+		api, _, _ := apiMockSetup()
+		api.IdempotencyStore = store.NewInMemoryIdempotencyStore()
+		auth := &authorisation.MiddlewareMock{
+			ParseFunc: func(token string) (*permsdk.EntityData, error) {
+				return &permsdk.EntityData{UserID: strings.TrimSuffix(token, ".jwt")}, nil
+			},
+		}
+
+		created := 0
+		var handledBody string
+		var handlerErr *models.ErrorResponse
+		failAfterCreating := false
+		handler := contextAndErrors(api.idempotent(auth, func(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := io.ReadAll(req.Body)
+			handledBody = string(body)
+			if handlerErr != nil && !failAfterCreating {
+				return nil, handlerErr
+			}
+			created++
+			markChangesMade(ctx)
+			if handlerErr != nil {
+				return nil, handlerErr
+			}
+			responseBody, _ := json.Marshal(map[string]int{"id": created})
+			return models.NewSuccessResponse(responseBody, http.StatusCreated, nil), nil
+		}))
+
+		createGroupAs := func(authToken, key, body string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:25600/v1/groups", strings.NewReader(body))
+			r.Header.Set(AccessTokenHeaderName, authToken)
+			if key != "" {
+				r.Header.Set(IdempotencyKeyHeaderName, key)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			return w
+		}
+
+		createGroup := func(key, body string) *httptest.ResponseRecorder {
+			return createGroupAs("Bearer user-1.jwt", key, body)
+		}
+
+		errorCode := func(w *httptest.ResponseRecorder) string {
+			var errorResponse struct {
+				Errors []models.Error `json:"errors"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &errorResponse), ShouldBeNil)
+			return errorResponse.Errors[0].Code
+		}
+
+		Convey("The first request with a key reaches the handler with the body intact", func() {
+			w := createGroup("key-1", `{"name":"Group A"}`)
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(handledBody, ShouldEqual, `{"name":"Group A"}`)
+			So(w.Header().Get(IdempotentReplayedHeaderName), ShouldBeEmpty)
+
+			Convey("And a repeat of the request gets the original response without creating another group", func() {
+				w := createGroup("key-1", `{"name":"Group A"}`)
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(w.Body.String(), ShouldEqual, `{"id":1}`)
+				So(w.Header().Get(IdempotentReplayedHeaderName), ShouldEqual, "true")
+				So(created, ShouldEqual, 1)
+			})
+
+			Convey("And a different request with the same key is refused", func() {
+				w := createGroup("key-1", `{"name":"Group B"}`)
+				So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+				So(errorCode(w), ShouldEqual, models.IdempotencyKeyMismatchError)
+				So(created, ShouldEqual, 1)
+			})
+
+			Convey("And a request with another key creates another group", func() {
+				w := createGroup("key-2", `{"name":"Group A"}`)
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(w.Body.String(), ShouldEqual, `{"id":2}`)
+			})
+
+			Convey("And another user's request with the same key creates another group", func() {
+				w := createGroupAs("Bearer user-2.jwt", "key-1", `{"name":"Group A"}`)
+				So(w.Code, ShouldEqual, http.StatusCreated)
+				So(w.Body.String(), ShouldEqual, `{"id":2}`)
+				So(w.Header().Get(IdempotentReplayedHeaderName), ShouldBeEmpty)
+			})
+		})
+
+		Convey("Services are told apart by their tokens", func() {
+			So(createGroupAs("Bearer service-token-1", "key-1", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusCreated)
+
+			w := createGroupAs("Bearer service-token-1", "key-1", `{"name":"Group A"}`)
+			So(w.Header().Get(IdempotentReplayedHeaderName), ShouldEqual, "true")
+			So(created, ShouldEqual, 1)
+
+			w = createGroupAs("Bearer service-token-2", "key-1", `{"name":"Group A"}`)
+			So(w.Header().Get(IdempotentReplayedHeaderName), ShouldBeEmpty)
+			So(created, ShouldEqual, 2)
+		})
+
+		Convey("A repeat of a request still in progress is refused", func() {
+			inProgress := httptest.NewRequest(http.MethodPost, "http://localhost:25600/v1/groups", strings.NewReader(`{"name":"Group A"}`))
+			requestHash, err := hashRequest(inProgress)
+			So(err, ShouldBeNil)
+			_, started, err := api.IdempotencyStore.Start(context.Background(), "/v1/groups:user:user-1:key-1", requestHash, DefaultIdempotencyInProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+
+			w := createGroup("key-1", `{"name":"Group A"}`)
+			So(w.Code, ShouldEqual, http.StatusConflict)
+			So(errorCode(w), ShouldEqual, models.IdempotencyKeyInUseError)
+			So(w.Header().Get(RetryAfterHeaderName), ShouldEqual, "1")
+			So(created, ShouldEqual, 0)
+		})
+
+		Convey("A failed request can be tried again with the same key", func() {
+			handlerErr = models.NewErrorResponse(http.StatusInternalServerError, nil, models.NewError(context.Background(), nil, models.InternalError, models.InternalErrorDescription))
+			So(createGroup("key-1", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusInternalServerError)
+
+			handlerErr = nil
+			w := createGroup("key-1", `{"name":"Group A"}`)
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(created, ShouldEqual, 1)
+		})
+
+		Convey("A request that fails after creating the group gets the same failure when repeated", func() {
+			handlerErr = models.NewErrorResponse(http.StatusInternalServerError, nil, models.NewError(context.Background(), nil, models.InternalError, models.InternalErrorDescription))
+			failAfterCreating = true
+			first := createGroup("key-1", `{"name":"Group A"}`)
+			So(first.Code, ShouldEqual, http.StatusInternalServerError)
+
+			handlerErr = nil
+			w := createGroup("key-1", `{"name":"Group A"}`)
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldEqual, first.Body.String())
+			So(w.Header().Get(IdempotentReplayedHeaderName), ShouldEqual, "true")
+			So(created, ShouldEqual, 1)
+		})
+
+		Convey("Requests without a key are not deduplicated", func() {
+			So(createGroup("", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusCreated)
+			So(createGroup("", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusCreated)
+			So(created, ShouldEqual, 2)
+		})
+
+		Convey("A key that is too long is refused", func() {
+			w := createGroup(strings.Repeat("k", MaxIdempotencyKeyLength+1), `{"name":"Group A"}`)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(errorCode(w), ShouldEqual, models.InvalidIdempotencyKeyError)
+			So(created, ShouldEqual, 0)
+		})
+
+		Convey("Requests are not deduplicated when there is no idempotency store", func() {
+			api.IdempotencyStore = nil
+			So(createGroup("key-1", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusCreated)
+			So(createGroup("key-1", `{"name":"Group A"}`).Code, ShouldEqual, http.StatusCreated)
+			So(created, ShouldEqual, 2)
+		})
+	})
+}
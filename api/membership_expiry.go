@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	dplogs "github.com/ONSdigital/log.go/v2/log"
+)
+
+// ReconcileMembershipExpiry removes users from groups once their temporary memberships expire, checking every interval
+// until the context is cancelled
+func (api *API) ReconcileMembershipExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			api.RemoveExpiredMemberships(ctx, now)
+		}
+	}
+}
+
+// RemoveExpiredMemberships removes users from groups whose memberships expired before now. Memberships that cannot be
+// removed are left to be retried by the next call. Every instance of the API runs this, and a membership may be renewed
+// while it runs, so a membership is only removed while it still has the expiry it was listed with.
+func (api *API) RemoveExpiredMemberships(ctx context.Context, now time.Time) {
+	expired, err := api.MembershipExpiry.ListExpiredMemberships(ctx, now)
+	if err != nil {
+		dplogs.Error(ctx, "failed to list expired group memberships", err)
+		return
+	}
+
+	for _, membership := range expired {
+		logData := dplogs.Data{"group_id": membership.GroupID, "user_id": membership.UserID, "expires_at": membership.ExpiresAt}
+		group := models.Group{ID: membership.GroupID}
+
+		// the expiry is read again as the memberships listed before it may have taken a while to remove
+		expiries, err := api.MembershipExpiry.ListUserMembershipExpiries(ctx, membership.UserID)
+		if err != nil {
+			dplogs.Error(ctx, "failed to check expiry of group membership", err, logData)
+			continue
+		}
+		if expiresAt, ok := expiries[membership.GroupID]; !ok || !expiresAt.Equal(membership.ExpiresAt) {
+			continue
+		}
+
+		userRemoveFromGroupInput := group.BuildRemoveUserFromGroupRequest(api.UserPoolID, membership.UserID)
+		_, err = api.CognitoClient.AdminRemoveUserFromGroup(ctx, userRemoveFromGroupInput)
+		if err != nil {
+			cognitoErr := models.NewCognitoError(ctx, err, "Cognito AdminRemoveUserFromGroup request from membership expiry")
+			// a user or group that no longer exists has no membership left to remove
+			if cognitoErr.Code != models.UserNotFoundError && cognitoErr.Code != models.NotFoundError {
+				dplogs.Error(ctx, "failed to remove expired group membership", cognitoErr, logData)
+				continue
+			}
+		}
+
+		renewed, err := api.MembershipExpiry.ClearExpiredMembership(ctx, membership)
+		if err != nil {
+			dplogs.Error(ctx, "failed to clear expiry of removed group membership", err, logData)
+			continue
+		}
+		if renewed {
+			// the membership was renewed after it was checked, so the user is put back in the group
+			api.restoreRenewedMembership(ctx, group, membership.UserID, logData)
+			continue
+		}
+		dplogs.Info(ctx, "expired group membership removed", logData)
+	}
+}
+
+// restoreRenewedMembership adds back a user removed from a group while their membership was being renewed. The removal
+// has already been made, so a failure is only logged.
+func (api *API) restoreRenewedMembership(ctx context.Context, group models.Group, userID string, logData dplogs.Data) {
+	userAddToGroupInput := group.BuildAddUserToGroupRequest(api.UserPoolID, userID)
+	if _, err := api.CognitoClient.AdminAddUserToGroup(ctx, userAddToGroupInput); err != nil {
+		cognitoErr := models.NewCognitoError(ctx, err, "Cognito AdminAddUserToGroup request from membership expiry")
+		dplogs.Error(ctx, "failed to restore renewed group membership", cognitoErr, logData)
+		return
+	}
+	dplogs.Info(ctx, "renewed group membership restored", logData)
+}
+
+// addMembershipExpiries sets the expiry time of the group's temporary members
+func (api *API) addMembershipExpiries(ctx context.Context, group models.Group, users []models.UserParams) *models.ErrorResponse {
+	expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, group.ID)
+	if err != nil {
+		return handleMembershipExpiryError(ctx, err)
+	}
+
+	for i := range users {
+		if expiresAt, ok := expiries[users[i].ID]; ok {
+			users[i].ExpiresAt = &expiresAt
+		}
+	}
+	return nil
+}
+
+// replaceMembershipExpiry sets the expiry time of the user's membership of the group, or clears it when expiresAt is
+// nil, returning the expiry time it replaced
+func (api *API) replaceMembershipExpiry(ctx context.Context, groupID, userID string, expiresAt *time.Time) (*time.Time, error) {
+	expiries, err := api.MembershipExpiry.ListUserMembershipExpiries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *time.Time
+	if previousExpiresAt, ok := expiries[groupID]; ok {
+		previous = &previousExpiresAt
+	}
+
+	if expiresAt != nil {
+		err = api.MembershipExpiry.SetMembershipExpiry(ctx, groupID, userID, *expiresAt)
+	} else {
+		err = api.MembershipExpiry.ClearMembershipExpiry(ctx, groupID, userID)
+	}
+	return previous, err
+}
+
+// restoreMembershipExpiry puts back the expiry time a membership had before a change to it failed. The request has
+// already failed, so a failure is only logged.
+func (api *API) restoreMembershipExpiry(ctx context.Context, group models.Group, userID string, expiresAt *time.Time) {
+	if _, err := api.replaceMembershipExpiry(ctx, group.ID, userID, expiresAt); err != nil {
+		dplogs.Error(ctx, "failed to restore group membership expiry", err, dplogs.Data{"group_id": group.ID, "user_id": userID})
+	}
+}
+
+// clearMembershipExpiry forgets the expiry time of a membership that has been removed or made permanent. The
+// membership change has already been made, so a failure is only logged.
+func (api *API) clearMembershipExpiry(ctx context.Context, group models.Group, userID string) {
+	if err := api.MembershipExpiry.ClearMembershipExpiry(ctx, group.ID, userID); err != nil {
+		dplogs.Error(ctx, "failed to clear group membership expiry", err, dplogs.Data{"group_id": group.ID, "user_id": userID})
+	}
+}
+
+func handleMembershipExpiryError(ctx context.Context, err error) *models.ErrorResponse {
+	return models.NewErrorResponse(http.StatusInternalServerError,
+		nil,
+		models.NewError(ctx, err, models.InternalError, models.MembershipExpiryFailedDescription),
+	)
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingMembershipExpiryStore is a MembershipExpiryStore that cannot save expiry times
+type failingMembershipExpiryStore struct {
+	store.MembershipExpiryStore
+}
+
+func (failingMembershipExpiryStore) SetMembershipExpiry(_ context.Context, _, _ string, _ time.Time) error {
+	return errors.New("store unavailable")
+}
+
+// renewingMembershipExpiryStore is a MembershipExpiryStore whose memberships are all renewed once they are listed as
+// expired
+type renewingMembershipExpiryStore struct {
+	store.MembershipExpiryStore
+	renewedExpiresAt time.Time
+}
+
+func (s renewingMembershipExpiryStore) ListExpiredMemberships(ctx context.Context, now time.Time) ([]store.MembershipExpiry, error) {
+	expired, err := s.MembershipExpiryStore.ListExpiredMemberships(ctx, now)
+	for _, membership := range expired {
+		if err := s.SetMembershipExpiry(ctx, membership.GroupID, membership.UserID, s.renewedExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	return expired, err
+}
+
+func TestTemporaryGroupMembership(t *testing.T) {
+	Convey("Given a group and its members", t, func() {
+		api, w, m := apiMockSetup()
+		members := map[string]bool{"user-1": true}
+		m.GetGroupFunc = func(_ context.Context, input *cognitoidentityprovider.GetGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetGroupOutput, error) {
+			return &cognitoidentityprovider.GetGroupOutput{Group: &types.GroupType{GroupName: input.GroupName}}, nil
+		}
+		m.ListUsersInGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+			users := []types.UserType{}
+			for member := range members {
+				users = append(users, types.UserType{Username: aws.String(member), Enabled: true, UserStatus: types.UserStatusTypeConfirmed})
+			}
+			return &cognitoidentityprovider.ListUsersInGroupOutput{Users: users}, nil
+		}
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			members[*input.Username] = true
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		m.AdminRemoveUserFromGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminRemoveUserFromGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+			delete(members, *input.Username)
+			return &cognitoidentityprovider.AdminRemoveUserFromGroupOutput{}, nil
+		}
+
+		addUser := func(body map[string]string) (*models.SuccessResponse, *models.ErrorResponse) {
+			jsonBody, _ := json.Marshal(body)
+			r := httptest.NewRequest(http.MethodPost, addUserToGroupEndPoint, bytes.NewReader(jsonBody))
+			return api.AddUserToGroupHandler(ctx, w, mux.SetURLVars(r, map[string]string{"id": "efgh5678"}))
+		}
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		Convey("When a user is added until a time in the future", func() {
+			successResponse, errorResponse := addUser(map[string]string{"user_id": "user-2", "expires_at": expiresAt.Format(time.RFC3339)})
+			So(errorResponse, ShouldBeNil)
+
+			Convey("Then the response shows when their membership expires", func() {
+				var usersList models.UsersList
+				So(json.Unmarshal(successResponse.Body, &usersList), ShouldBeNil)
+				for _, user := range usersList.Users {
+					if user.ID == "user-2" {
+						So(user.ExpiresAt.Equal(expiresAt), ShouldBeTrue)
+					} else {
+						So(user.ExpiresAt, ShouldBeNil)
+					}
+				}
+			})
+
+			Convey("Then the group's members show when their membership expires", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, getUsersInGroupEndPoint, http.NoBody), map[string]string{"id": "efgh5678"})
+				successResponse, errorResponse := api.ListUsersInGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+				So(string(successResponse.Body), ShouldContainSubstring, `"expires_at":"`+expiresAt.Format(time.RFC3339)+`"`)
+			})
+
+			Convey("Then their groups mark the membership as temporary", func() {
+				m.ListGroupsForUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminListGroupsForUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminListGroupsForUserOutput, error) {
+					return &cognitoidentityprovider.AdminListGroupsForUserOutput{Groups: []types.GroupType{{GroupName: aws.String("efgh5678")}}}, nil
+				}
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, userListGroupsEndPoint, http.NoBody), map[string]string{"id": "user-2"})
+				successResponse, errorResponse := api.ListUserGroupsHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				var userGroups models.ListUserGroups
+				So(json.Unmarshal(successResponse.Body, &userGroups), ShouldBeNil)
+				So(userGroups.Groups[0].Temporary, ShouldBeTrue)
+				So(userGroups.Groups[0].ExpiresAt.Equal(expiresAt), ShouldBeTrue)
+			})
+
+			Convey("Then adding them again without an expiry makes the membership permanent", func() {
+				_, errorResponse := addUser(map[string]string{"user_id": "user-2"})
+				So(errorResponse, ShouldBeNil)
+
+				expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+				So(err, ShouldBeNil)
+				So(expiries, ShouldBeEmpty)
+			})
+
+			Convey("Then a failed attempt to add them again keeps their expiry", func() {
+				m.AdminAddUserToGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+					return nil, errors.New("cognito unavailable")
+				}
+				_, errorResponse := addUser(map[string]string{"user_id": "user-2"})
+				So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+
+				expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+				So(err, ShouldBeNil)
+				So(expiries, ShouldResemble, map[string]time.Time{"user-2": expiresAt})
+			})
+
+			Convey("Then removing them forgets the expiry", func() {
+				r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, removeUserFromGroupEndPoint, http.NoBody),
+					map[string]string{"id": "efgh5678", "user_id": "user-2"})
+				_, errorResponse := api.RemoveUserFromGroupHandler(ctx, w, r)
+				So(errorResponse, ShouldBeNil)
+
+				expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+				So(err, ShouldBeNil)
+				So(expiries, ShouldBeEmpty)
+			})
+
+			Convey("Then they are removed from the group once it expires", func() {
+				api.RemoveExpiredMemberships(ctx, expiresAt.Add(-time.Second))
+				So(members["user-2"], ShouldBeTrue)
+
+				api.RemoveExpiredMemberships(ctx, expiresAt.Add(time.Second))
+				So(members["user-2"], ShouldBeFalse)
+				So(members["user-1"], ShouldBeTrue)
+
+				expired, err := api.MembershipExpiry.ListExpiredMemberships(ctx, expiresAt.Add(time.Second))
+				So(err, ShouldBeNil)
+				So(expired, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the expiry of a new membership cannot be saved the user is not added", func() {
+			api.MembershipExpiry = failingMembershipExpiryStore{api.MembershipExpiry}
+
+			successResponse, errorResponse := addUser(map[string]string{"user_id": "user-2", "expires_at": expiresAt.Format(time.RFC3339)})
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.MembershipExpiryFailedDescription)
+			So(members["user-2"], ShouldBeFalse)
+		})
+
+		Convey("When adding a new temporary member fails their expiry is not kept", func() {
+			m.AdminAddUserToGroupFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+				return nil, errors.New("cognito unavailable")
+			}
+
+			_, errorResponse := addUser(map[string]string{"user_id": "user-2", "expires_at": expiresAt.Format(time.RFC3339)})
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+
+			expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+			So(err, ShouldBeNil)
+			So(expiries, ShouldBeEmpty)
+		})
+
+		Convey("When a user is added until a time in the past the request is refused", func() {
+			successResponse, errorResponse := addUser(map[string]string{"user_id": "user-2", "expires_at": "2020-01-01T00:00:00Z"})
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.InvalidMembershipExpiryDescription)
+			So(members["user-2"], ShouldBeFalse)
+		})
+	})
+}
+
+func TestRemoveExpiredMemberships(t *testing.T) {
+	Convey("Given expired memberships that Cognito fails to remove", t, func() {
+		api, _, m := apiMockSetup()
+		now := time.Now()
+		So(api.MembershipExpiry.SetMembershipExpiry(ctx, "efgh5678", "deleted-user", now.Add(-time.Hour)), ShouldBeNil)
+		So(api.MembershipExpiry.SetMembershipExpiry(ctx, "efgh5678", "user-1", now.Add(-time.Minute)), ShouldBeNil)
+
+		m.AdminRemoveUserFromGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminRemoveUserFromGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+			if *input.Username == "deleted-user" {
+				return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+			}
+			return nil, errors.New("cognito unavailable")
+		}
+
+		Convey("Memberships of users that no longer exist are forgotten and the rest retried later", func() {
+			api.RemoveExpiredMemberships(ctx, now)
+
+			expired, err := api.MembershipExpiry.ListExpiredMemberships(ctx, now)
+			So(err, ShouldBeNil)
+			So(len(expired), ShouldEqual, 1)
+			So(expired[0].UserID, ShouldEqual, "user-1")
+		})
+	})
+
+	Convey("Given an expired membership that is renewed", t, func() {
+		api, _, m := apiMockSetup()
+		now := time.Now()
+		renewedExpiresAt := now.Add(time.Hour)
+		So(api.MembershipExpiry.SetMembershipExpiry(ctx, "efgh5678", "user-1", now.Add(-time.Minute)), ShouldBeNil)
+
+		members := map[string]bool{"user-1": true}
+		removals := 0
+		m.AdminAddUserToGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminAddUserToGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+			members[*input.Username] = true
+			return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+		}
+		m.AdminRemoveUserFromGroupFunc = func(_ context.Context, input *cognitoidentityprovider.AdminRemoveUserFromGroupInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+			removals++
+			delete(members, *input.Username)
+			return &cognitoidentityprovider.AdminRemoveUserFromGroupOutput{}, nil
+		}
+
+		Convey("When it is renewed before it is removed the user is left in the group", func() {
+			api.MembershipExpiry = renewingMembershipExpiryStore{api.MembershipExpiry, renewedExpiresAt}
+			api.RemoveExpiredMemberships(ctx, now)
+
+			So(removals, ShouldEqual, 0)
+			So(members["user-1"], ShouldBeTrue)
+			expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+			So(err, ShouldBeNil)
+			So(expiries["user-1"], ShouldEqual, renewedExpiresAt)
+		})
+
+		Convey("When it is renewed while it is removed the user is added back to the group", func() {
+			removeUser := m.AdminRemoveUserFromGroupFunc
+			m.AdminRemoveUserFromGroupFunc = func(ctx context.Context, input *cognitoidentityprovider.AdminRemoveUserFromGroupInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+				So(api.MembershipExpiry.SetMembershipExpiry(ctx, "efgh5678", "user-1", renewedExpiresAt), ShouldBeNil)
+				return removeUser(ctx, input, optFns...)
+			}
+			api.RemoveExpiredMemberships(ctx, now)
+
+			So(removals, ShouldEqual, 1)
+			So(members["user-1"], ShouldBeTrue)
+			expiries, err := api.MembershipExpiry.ListGroupMembershipExpiries(ctx, "efgh5678")
+			So(err, ShouldBeNil)
+			So(expiries["user-1"], ShouldEqual, renewedExpiresAt)
+		})
+	})
+
+	Convey("The reconciler stops when its context is cancelled", t, func() {
+		api, _, _ := apiMockSetup()
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			api.ReconcileMembershipExpiry(cancelledCtx, time.Hour)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("reconciler did not stop")
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+var (
+	RetryAfterHeaderName   = "Retry-After"
+	ForwardedForHeaderName = "X-Forwarded-For"
+	// MaxRateLimitedBodySize is the largest request body read to find the email address of a rate limited request
+	MaxRateLimitedBodySize int64 = 64 * 1024
+)
+
+// RateLimits configures the rate limiting of the unauthenticated sign in and password reset endpoints
+type RateLimits struct {
+	IP    store.RateLimit
+	Email store.RateLimit
+	// TrustedProxies is the number of proxies in front of the API that append to X-Forwarded-For
+	TrustedProxies int
+}
+
+type rateLimitKey struct {
+	key   string
+	limit store.RateLimit
+}
+
+// rateLimited limits how often the handler may be called from a client IP address and, separately, for the email
+// address in the request body. Requests over either limit get a 429 with a Retry-After header and never reach Cognito.
+func (api *API) rateLimited(h baseHandler) baseHandler {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+		if api.RateLimitStore == nil {
+			return h(ctx, w, req)
+		}
+
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(w, req.Body, MaxRateLimitedBodySize)
+		}
+		email, err := requestEmail(req)
+		if err != nil {
+			return nil, handleBodyReadError(ctx, err)
+		}
+
+		keys := []rateLimitKey{{req.URL.Path + ":ip:" + clientIP(req, api.RateLimits.TrustedProxies), api.RateLimits.IP}}
+		if email != "" {
+			keys = append(keys, rateLimitKey{req.URL.Path + ":email:" + email, api.RateLimits.Email})
+		}
+
+		for _, k := range keys {
+			if k.limit.Burst <= 0 {
+				continue
+			}
+			allowed, retryAfter, err := api.RateLimitStore.Take(ctx, k.key, k.limit)
+			if err != nil {
+				// an unavailable store should not stop everyone signing in, Cognito's own limits still apply
+				log.Error(ctx, "rate limit store request failed, allowing request", err, log.Data{"path": req.URL.Path})
+				continue
+			}
+			if !allowed {
+				headers := map[string]string{RetryAfterHeaderName: strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}
+				responseErr := models.NewValidationError(ctx, models.TooManyFailedAttemptsError, models.RateLimitExceededDescription)
+				return nil, models.NewErrorResponse(http.StatusTooManyRequests, headers, responseErr)
+			}
+		}
+
+		return h(ctx, w, req)
+	}
+}
+
+// requestEmail returns the lower cased email address from a JSON request body, leaving the body to be read again
+// by the handler
+func requestEmail(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var params struct {
+		Email string `json:"email"`
+	}
+	// an unparsable body is left for the handler to reject
+	_ = json.Unmarshal(body, &params)
+	return strings.ToLower(strings.TrimSpace(params.Email)), nil
+}
+
+// clientIP returns the address of the client, taken from X-Forwarded-For when the API is behind trusted proxies.
+// Entries further left than the trusted proxies could have been set by the client, so they are ignored.
+func clientIP(req *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		if forwarded := strings.Join(req.Header.Values(ForwardedForHeaderName), ","); forwarded != "" {
+			addrs := strings.Split(forwarded, ",")
+			i := max(len(addrs)-trustedProxies, 0)
+			return strings.TrimSpace(addrs[i])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimited(t *testing.T) {
+	Convey("Given the sign in endpoint is rate limited by IP address and email", t, func() {
+		api, _, _ := apiMockSetup()
+		api.RateLimitStore = store.NewInMemoryRateLimitStore()
+		api.RateLimits = RateLimits{
+			IP:    store.RateLimit{Burst: 3, Interval: time.Minute},
+			Email: store.RateLimit{Burst: 2, Interval: time.Minute},
+		}
+
+		var handledBody string
+		handler := contextAndErrors(api.rateLimited(func(_ context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+			body, _ := io.ReadAll(req.Body)
+			handledBody = string(body)
+			return models.NewSuccessResponse(nil, http.StatusCreated, nil), nil
+		}))
+
+		signIn := func(remoteAddr, email string) *httptest.ResponseRecorder {
+			body := `{"email": "` + email + `", "password": "Passw0rd!"}`
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:25600/v1/tokens", strings.NewReader(body))
+			r.RemoteAddr = remoteAddr
+			w := httptest.NewRecorder()
+			handler(w, r)
+			return w
+		}
+
+		Convey("Requests within the limits reach the handler with the body intact", func() {
+			w := signIn("10.0.0.1:1234", "email@ons.gov.uk")
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(handledBody, ShouldContainSubstring, "email@ons.gov.uk")
+		})
+
+		Convey("Requests for one email over its limit are refused from any IP address", func() {
+			So(signIn("10.0.0.1:1234", "email@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+			So(signIn("10.0.0.2:1234", "EMAIL@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+
+			w := signIn("10.0.0.3:1234", "email@ons.gov.uk")
+			So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+			So(w.Header().Get(RetryAfterHeaderName), ShouldEqual, "60")
+
+			var errorResponse struct {
+				Errors []models.Error `json:"errors"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &errorResponse), ShouldBeNil)
+			So(errorResponse.Errors[0].Code, ShouldEqual, models.TooManyFailedAttemptsError)
+			So(errorResponse.Errors[0].Description, ShouldEqual, models.RateLimitExceededDescription)
+		})
+
+		Convey("Requests from one IP address over its limit are refused for any email", func() {
+			So(signIn("10.0.0.1:1234", "one@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+			So(signIn("10.0.0.1:1234", "two@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+			So(signIn("10.0.0.1:1234", "three@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+			So(signIn("10.0.0.1:1234", "four@ons.gov.uk").Code, ShouldEqual, http.StatusTooManyRequests)
+			So(signIn("10.0.0.2:1234", "four@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+		})
+
+		Convey("A body too large to be a sign in request is refused without being read", func() {
+			handledBody = ""
+			body := `{"email": "email@ons.gov.uk", "password": "` + strings.Repeat("a", int(MaxRateLimitedBodySize)) + `"}`
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:25600/v1/tokens", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(handledBody, ShouldBeEmpty)
+		})
+
+		Convey("Requests are not limited when there is no rate limit store", func() {
+			api.RateLimitStore = nil
+			for i := 0; i < 5; i++ {
+				So(signIn("10.0.0.1:1234", "email@ons.gov.uk").Code, ShouldEqual, http.StatusCreated)
+			}
+		})
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	Convey("The client IP address is taken from the connection or trusted X-Forwarded-For entries", t, func() {
+		clientIPTests := []struct {
+			forwardedFor   []string
+			trustedProxies int
+			expected       string
+		}{
+			{nil, 0, "10.0.0.1"},
+			{[]string{"1.1.1.1"}, 0, "10.0.0.1"},
+			{nil, 1, "10.0.0.1"},
+			{[]string{"1.1.1.1"}, 1, "1.1.1.1"},
+			{[]string{"6.6.6.6, 1.1.1.1"}, 1, "1.1.1.1"},
+			{[]string{"6.6.6.6, 1.1.1.1", "2.2.2.2"}, 2, "1.1.1.1"},
+			{[]string{"1.1.1.1"}, 3, "1.1.1.1"},
+		}
+
+		for _, tt := range clientIPTests {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:25600/v1/tokens", http.NoBody)
+			r.RemoteAddr = "10.0.0.1:1234"
+			for _, forwardedFor := range tt.forwardedFor {
+				r.Header.Add(ForwardedForHeaderName, forwardedFor)
+			}
+
+			So(clientIP(r, tt.trustedProxies), ShouldEqual, tt.expected)
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 // TokensHandler uses submitted email address and password to sign a user in against Cognito and returns a http handler interface
 func (api *API) TokensHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	defer api.padResponseTime(ctx, time.Now())
 	defer func() {
 		if err := req.Body.Close(); err != nil {
 			_ = models.NewError(ctx, err, models.BodyCloseError, models.BodyClosedFailedDescription)
@@ -37,16 +38,29 @@ func (api *API) TokensHandler(ctx context.Context, _ http.ResponseWriter, req *h
 	result, authErr := api.CognitoClient.InitiateAuth(ctx, input)
 	if authErr != nil {
 		responseErr := models.NewCognitoError(ctx, authErr, "Cognito InitiateAuth request from sign in handler")
+		api.audit(ctx, AuditEvent{
+			Action:   AuditActionSignIn,
+			Email:    userSignIn.Email,
+			ClientIP: clientIP(req, api.RateLimits.TrustedProxies),
+			Outcome:  AuditOutcomeFailure,
+			Cause:    responseErr.Code + ": " + responseErr.Description,
+		})
 		if responseErr.Code == models.InternalError {
 			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 		}
+		// Returning `WWW-Authenticate` in header as part of http.StatusUnauthorized response
+		// See here: https://datatracker.ietf.org/doc/html/rfc7235#section-4.1
+		headers := map[string]string{
+			WWWAuthenticateName: "Bearer realm=\"" + ONSRealm + "\", charset=\"" + Charset + "\"",
+		}
+		if api.AntiEnumeration.Enabled {
+			// every failure looks like an incorrect password, so the response does not reveal whether the account
+			// exists, is locked out or is unconfirmed
+			signInErr := models.NewError(ctx, responseErr, models.NotAuthorisedError, models.SignInFailedDescription)
+			return nil, models.NewErrorResponse(http.StatusUnauthorized, headers, signInErr)
+		}
 		switch responseErr.Description {
 		case models.SignInFailedDescription:
-			// Returning `WWW-Authenticate` in header as part of http.StatusUnauthorized response
-			// See here: https://datatracker.ietf.org/doc/html/rfc7235#section-4.1
-			headers := map[string]string{
-				WWWAuthenticateName: "Bearer realm=\"" + ONSRealm + "\", charset=\"" + Charset + "\"",
-			}
 			return nil, models.NewErrorResponse(http.StatusUnauthorized, headers, responseErr)
 		case models.SignInAttemptsExceededDescription:
 			// Cognito returns the same Code for invalid credentials and too many attempts errors, changing our Error.Code to enable differentiation in the client
@@ -88,6 +102,13 @@ func (api *API) TokensHandler(ctx context.Context, _ http.ResponseWriter, req *h
 		headers = nil
 	}
 
+	api.audit(ctx, AuditEvent{
+		Action:   AuditActionSignIn,
+		Email:    userSignIn.Email,
+		ClientIP: clientIP(req, api.RateLimits.TrustedProxies),
+		Outcome:  AuditOutcomeSuccess,
+	})
+
 	// response - http.StatusCreated by default
 	httpStatus := http.StatusCreated
 	if result.ChallengeName == NewPasswordChallenge {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
 
@@ -22,6 +23,8 @@ const (
 	UsersCreatePermission = "users:create"
 	UsersReadPermission   = "users:read"
 	UsersUpdatePermission = "users:update"
+
+	maxPasswordGenerationAttempts = 5
 )
 
 // CreateUserHandler creates a new user and returns a http handler interface
@@ -50,8 +53,7 @@ func (api *API) CreateUserHandler(ctx context.Context, _ http.ResponseWriter, re
 
 	validationErrs := user.ValidateRegistration(ctx, api.AllowedDomains, api.BlockPlusAddressing)
 
-	listUserInput := models.UsersList{}.BuildListUserRequest("email = \""+user.Email+"\"", "email", int32(1), nil, &api.UserPoolID)
-	listUserResp, err := api.CognitoClient.ListUsers(ctx, listUserInput)
+	listUserResp, err := api.listUsersWithEmail(ctx, user.Email)
 	if err != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, models.NewCognitoError(ctx, err, "ListUsers request from create users endpoint"))
 	}
@@ -74,6 +76,7 @@ func (api *API) CreateUserHandler(ctx context.Context, _ http.ResponseWriter, re
 		}
 		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
 	}
+	markChangesMade(ctx)
 	resultUser.User.Enabled = true
 	createdUser := models.UserParams{}.MapCognitoDetails(*resultUser.User)
 	jsonResponse, responseErr := createdUser.BuildSuccessfulJSONResponse(ctx)
@@ -126,25 +129,12 @@ func (api *API) ListUsersHandler(ctx context.Context, _ http.ResponseWriter, req
 // GetUserHandler lists the users in the user pool
 func (api *API) GetUserHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
 	vars := mux.Vars(req)
-	user := models.UserParams{ID: vars["id"]}
-	userInput := user.BuildAdminGetUserRequest(api.UserPoolID)
-	userResp, err := api.CognitoClient.AdminGetUser(ctx, userInput)
-	if err != nil {
-		responseErr := models.NewCognitoError(ctx, err, "AdminGetUser request from get user endpoint")
-		if responseErr.Code == models.UserNotFoundError {
-			return nil, models.NewErrorResponse(http.StatusNotFound, nil, responseErr)
-		}
-		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
-	}
-
-	user.MapCognitoGetResponse(userResp)
-
-	jsonResponse, responseErr := user.BuildSuccessfulJSONResponse(ctx)
-	if responseErr != nil {
-		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	jsonResponse, errResponse := api.getUserJSON(ctx, vars["id"], "AdminGetUser request from get user endpoint")
+	if errResponse != nil {
+		return nil, errResponse
 	}
 
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, etagHeaders(newETag(jsonResponse))), nil
 }
 
 // UpdateUserHandler updates a users details in Cognito and returns a http handler interface
@@ -174,6 +164,16 @@ func (api *API) UpdateUserHandler(ctx context.Context, _ http.ResponseWriter, re
 		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
 	}
 
+	if hasIfMatch(req) {
+		currentJSON, errResponse := api.getUserJSON(ctx, user.ID, "AdminGetUser request from update user endpoint")
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		if !ifMatch(req, newETag(currentJSON)) {
+			return nil, preconditionFailed(ctx)
+		}
+	}
+
 	if user.Active {
 		userEnableRequest := user.BuildEnableUserRequest(api.UserPoolID)
 		if _, err = api.CognitoClient.AdminEnableUser(ctx, userEnableRequest); err != nil {
@@ -193,21 +193,35 @@ func (api *API) UpdateUserHandler(ctx context.Context, _ http.ResponseWriter, re
 		return nil, processUpdateCognitoError(ctx, err, "AdminUpdateUserAttributes request from update user endpoint")
 	}
 
-	userDetailsRequest := user.BuildAdminGetUserRequest(api.UserPoolID)
-	userDetailsResponse, err := api.CognitoClient.AdminGetUser(ctx, userDetailsRequest)
+	// the user is read back so the response, and its entity tag, are the same as the get user endpoint would return.
+	// The user has already been updated, so failing to read them is a server error whatever Cognito reports.
+	jsonResponse, errResponse := api.getUserJSON(ctx, user.ID, "AdminGetUser request from update user endpoint")
+	if errResponse != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, errResponse.Errors...)
+	}
+
+	return models.NewSuccessResponse(jsonResponse, http.StatusOK, etagHeaders(newETag(jsonResponse))), nil
+}
+
+// getUserJSON reads the user from Cognito and returns them as the get user endpoint does
+func (api *API) getUserJSON(ctx context.Context, userID, errContext string) ([]byte, *models.ErrorResponse) {
+	user := models.UserParams{ID: userID}
+	userResp, err := api.CognitoClient.AdminGetUser(ctx, user.BuildAdminGetUserRequest(api.UserPoolID))
 	if err != nil {
-		responseErr := models.NewCognitoError(ctx, err, "AdminGetUser request from update user endpoint")
+		responseErr := models.NewCognitoError(ctx, err, errContext)
+		if responseErr.Code == models.UserNotFoundError {
+			return nil, models.NewErrorResponse(http.StatusNotFound, nil, responseErr)
+		}
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
 
-	user.MapCognitoGetResponse(userDetailsResponse)
+	user.MapCognitoGetResponse(userResp)
 
 	jsonResponse, responseErr := user.BuildSuccessfulJSONResponse(ctx)
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
-
-	return models.NewSuccessResponse(jsonResponse, http.StatusOK, nil), nil
+	return jsonResponse, nil
 }
 
 // UserSetPasswordHandler sets a users password to a generated password in Cognito and returns a http handler interface
@@ -234,9 +248,24 @@ func (api *API) UserSetPasswordHandler(ctx context.Context, _ http.ResponseWrite
 		return nil, models.NewErrorResponse(http.StatusForbidden, nil, validationErrs...)
 	}
 
-	err = user.GeneratePassword(ctx)
-	if err != nil {
-		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, err)
+	// the generated password is random, so try again if it happens to break the password policy. It is a temporary
+	// password the user does not choose, so it is not checked against or added to their password history.
+	for attempt := 1; ; attempt++ {
+		err = user.GeneratePassword(ctx)
+		if err != nil {
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, err)
+		}
+
+		policyErrs, policyErr := user.ValidatePasswordPolicy(ctx, api.PasswordPolicy)
+		if policyErr != nil {
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, policyErr)
+		}
+		if len(policyErrs) == 0 {
+			break
+		}
+		if attempt == maxPasswordGenerationAttempts {
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, policyErrs...)
+		}
 	}
 
 	userSetPasswordInput := user.BuildSetPasswordRequest(api.UserPoolID)
@@ -260,6 +289,129 @@ func (api *API) UserSetPasswordHandler(ctx context.Context, _ http.ResponseWrite
 	return models.NewSuccessResponse(nil, http.StatusAccepted, nil), nil
 }
 
+// UpdateUserEmailHandler starts a change of a users email address in Cognito and returns a http handler interface
+// The new address is not used until the user confirms it with the verification code Cognito sends to it
+func (api *API) UpdateUserEmailHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	defer func() {
+		if err := req.Body.Close(); err != nil {
+			_ = models.NewError(ctx, err, models.BodyCloseError, models.BodyClosedFailedDescription)
+		}
+	}()
+	vars := mux.Vars(req)
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	user := models.UserParams{}
+	err = json.Unmarshal(body, &user)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+	user.ID = vars["id"]
+
+	validationErrs := user.ValidateEmailChange(ctx, api.AllowedDomains, api.BlockPlusAddressing)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+
+	listUserResp, err := api.listUsersWithEmail(ctx, user.Email)
+	if err != nil {
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, models.NewCognitoError(ctx, err, "ListUsers request from update user email endpoint"))
+	}
+	duplicateEmailErr := user.CheckForDuplicateEmail(ctx, listUserResp)
+	if duplicateEmailErr != nil {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, duplicateEmailErr)
+	}
+
+	userPool, err := api.CognitoClient.DescribeUserPool(ctx, &cognitoidentityprovider.DescribeUserPoolInput{UserPoolId: &api.UserPoolID})
+	if err != nil {
+		responseErr := models.NewCognitoError(ctx, err, "DescribeUserPool request from update user email endpoint")
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	// without this user pool setting Cognito would switch the user to the unverified address straight away
+	if !emailRequiresVerificationBeforeUpdate(userPool) {
+		responseErr := models.NewError(ctx, nil, models.InvalidUserPoolError, models.EmailChangeUnverifiedDescription)
+		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+
+	userUpdateRequest := user.BuildUpdateEmailRequest(api.UserPoolID)
+	_, err = api.CognitoClient.AdminUpdateUserAttributes(ctx, userUpdateRequest)
+	if err != nil {
+		return nil, processUpdateCognitoError(ctx, err, "AdminUpdateUserAttributes request from update user email endpoint")
+	}
+
+	log.Info(ctx, "user email change requested, awaiting verification", log.Data{"userID": user.ID})
+
+	return models.NewSuccessResponse(nil, http.StatusAccepted, nil), nil
+}
+
+// VerifyUserEmailHandler confirms a pending change to the signed in users email address using the code sent to the new address
+func (api *API) VerifyUserEmailHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	defer func() {
+		if err := req.Body.Close(); err != nil {
+			_ = models.NewError(ctx, err, models.BodyCloseError, models.BodyClosedFailedDescription)
+		}
+	}()
+
+	accessToken := models.AccessToken{
+		AuthHeader: req.Header.Get(AccessTokenHeaderName),
+	}
+	validationErr := accessToken.Validate(ctx)
+	if validationErr != nil {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErr)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, handleBodyReadError(ctx, err)
+	}
+
+	emailVerification := models.EmailVerification{}
+	err = json.Unmarshal(body, &emailVerification)
+	if err != nil {
+		return nil, handleBodyUnmarshalError(ctx, err)
+	}
+
+	validationErrs := emailVerification.Validate(ctx)
+	if len(validationErrs) != 0 {
+		return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+
+	_, err = api.CognitoClient.VerifyUserAttribute(ctx, emailVerification.BuildVerifyEmailRequest(accessToken.TokenString))
+	if err != nil {
+		responseErr := models.NewCognitoError(ctx, err, "VerifyUserAttribute request from verify user email endpoint")
+		switch responseErr.Code {
+		case models.InvalidCodeError, models.ExpiredCodeError, models.NotAuthorisedError, models.UsernameExistsError:
+			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, responseErr)
+		default:
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+		}
+	}
+
+	return models.NewSuccessResponse(nil, http.StatusNoContent, nil), nil
+}
+
+// listUsersWithEmail looks up any users already registered with the given email address
+func (api *API) listUsersWithEmail(ctx context.Context, email string) (*cognitoidentityprovider.ListUsersOutput, error) {
+	listUserInput := models.UsersList{}.BuildListUserRequest("email = \""+email+"\"", "email", int32(1), nil, &api.UserPoolID)
+	return api.CognitoClient.ListUsers(ctx, listUserInput)
+}
+
+// emailRequiresVerificationBeforeUpdate checks the user pool keeps the original email address active until a new one is verified
+func emailRequiresVerificationBeforeUpdate(userPool *cognitoidentityprovider.DescribeUserPoolOutput) bool {
+	if userPool == nil || userPool.UserPool == nil || userPool.UserPool.UserAttributeUpdateSettings == nil {
+		return false
+	}
+	for _, attr := range userPool.UserPool.UserAttributeUpdateSettings.AttributesRequireVerificationBeforeUpdate {
+		if attr == types.VerifiedAttributeTypeEmail {
+			return true
+		}
+	}
+	return false
+}
+
 func processUpdateCognitoError(ctx context.Context, err error, errContext string) *models.ErrorResponse {
 	responseErr := models.NewCognitoError(ctx, err, errContext)
 
@@ -299,10 +451,20 @@ func (api *API) ChangePasswordHandler(ctx context.Context, _ http.ResponseWriter
 	// that much. TODO: It needs a greater level of refactoring
 	if changePasswordParams.ChangeType == models.NewPasswordRequiredType {
 		validationErrs := changePasswordParams.ValidateNewPasswordRequiredRequest(ctx)
+		policyErrs, policyErr := changePasswordParams.ValidatePasswordPolicy(ctx, api.PasswordPolicy)
+		if policyErr != nil {
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, policyErr)
+		}
+		validationErrs = append(validationErrs, policyErrs...)
 		if len(validationErrs) != 0 {
 			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
 		}
 
+		historyUserID, errResponse := api.checkNewPassword(ctx, changePasswordParams)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+
 		changePasswordRequest := changePasswordParams.BuildAuthChallengeResponseRequest(api.ClientSecret, api.ClientID, NewPasswordChallenge)
 
 		result, cognitoErr := api.CognitoClient.RespondToAuthChallenge(ctx, changePasswordRequest)
@@ -315,6 +477,8 @@ func (api *API) ChangePasswordHandler(ctx context.Context, _ http.ResponseWriter
 				return nil, models.NewErrorResponse(http.StatusBadRequest, nil, parsedErr)
 			}
 		} else {
+			api.recordPasswordHistory(ctx, historyUserID, changePasswordParams.NewPassword)
+
 			// Determine the refresh token TTL (DescribeUserPoolClient)
 			userPoolClient, err := api.CognitoClient.DescribeUserPoolClient(ctx,
 				&cognitoidentityprovider.DescribeUserPoolClientInput{
@@ -341,9 +505,19 @@ func (api *API) ChangePasswordHandler(ctx context.Context, _ http.ResponseWriter
 		}
 	} else if changePasswordParams.ChangeType == models.ForgottenPasswordType {
 		validationErrs := changePasswordParams.ValidateForgottenPasswordRequest(ctx)
+		policyErrs, policyErr := changePasswordParams.ValidatePasswordPolicy(ctx, api.PasswordPolicy)
+		if policyErr != nil {
+			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, policyErr)
+		}
+		validationErrs = append(validationErrs, policyErrs...)
 		if len(validationErrs) != 0 {
 			return nil, models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
 		}
+
+		historyUserID, errResponse := api.checkNewPassword(ctx, changePasswordParams)
+		if errResponse != nil {
+			return nil, errResponse
+		}
 		changeForgottenPasswordRequest := changePasswordParams.BuildConfirmForgotPasswordRequest(api.ClientSecret, api.ClientID)
 
 		_, cognitoErr := api.CognitoClient.ConfirmForgotPassword(ctx, changeForgottenPasswordRequest)
@@ -355,6 +529,8 @@ func (api *API) ChangePasswordHandler(ctx context.Context, _ http.ResponseWriter
 			} else if parsedErr.Code == models.InvalidPasswordError || parsedErr.Code == models.InvalidCodeError || parsedErr.Code == models.ExpiredCodeError {
 				return nil, models.NewErrorResponse(http.StatusBadRequest, nil, parsedErr)
 			}
+		} else {
+			api.recordPasswordHistory(ctx, historyUserID, changePasswordParams.NewPassword)
 		}
 	} else {
 		err = models.NewValidationError(ctx, models.UnknownRequestTypeError, models.UnknownPasswordChangeTypeDescription)
@@ -368,8 +544,69 @@ func (api *API) ChangePasswordHandler(ctx context.Context, _ http.ResponseWriter
 	return models.NewSuccessResponse(jsonResponse, http.StatusAccepted, headers), nil
 }
 
+// checkNewPassword looks up the user changing their password and checks the new password against their name, email
+// address and password history. This is done before the change is sent to Cognito, so that a rejected password leaves
+// the session or verification code to be used again. The user's ID is returned for adding the password to their
+// history once it has been changed. A user that cannot be found is left for Cognito to reject.
+func (api *API) checkNewPassword(ctx context.Context, changePasswordParams models.ChangePassword) (string, *models.ErrorResponse) {
+	// Cognito accepts the email address, as an alias, or the username for the user
+	user := models.UserParams{ID: changePasswordParams.Email}
+	userResp, err := api.CognitoClient.AdminGetUser(ctx, user.BuildAdminGetUserRequest(api.UserPoolID))
+	if err != nil {
+		responseErr := models.NewCognitoError(ctx, err, "AdminGetUser request from change password endpoint")
+		if responseErr.Code == models.UserNotFoundError {
+			return "", nil
+		}
+		return "", models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
+	}
+	user.MapCognitoGetResponse(userResp)
+	if userResp.Username != nil {
+		user.ID = *userResp.Username
+	}
+
+	validationErrs := changePasswordParams.ValidatePersonalInfo(ctx, api.PasswordPolicy, user)
+	historyErrs, historyErr := api.checkPasswordHistory(ctx, user.ID, changePasswordParams.NewPassword)
+	if historyErr != nil {
+		return "", models.NewErrorResponse(http.StatusInternalServerError, nil, historyErr)
+	}
+	validationErrs = append(validationErrs, historyErrs...)
+	if len(validationErrs) != 0 {
+		return "", models.NewErrorResponse(http.StatusBadRequest, nil, validationErrs...)
+	}
+	return user.ID, nil
+}
+
+// checkPasswordHistory returns a validation error if the password is one of the user's recent passwords
+func (api *API) checkPasswordHistory(ctx context.Context, userID, password string) ([]error, error) {
+	if api.PasswordHistory == nil || userID == "" || password == "" {
+		return nil, nil
+	}
+
+	reused, err := api.PasswordHistory.Contains(ctx, userID, password)
+	if err != nil {
+		return nil, models.NewError(ctx, err, models.InternalError, models.PasswordHistoryFailedDescription)
+	}
+	if reused {
+		return []error{models.NewValidationError(ctx, models.InvalidPasswordError, models.PasswordReusedDescription)}, nil
+	}
+	return nil, nil
+}
+
+// recordPasswordHistory adds a password that has been set in Cognito to the user's password history. The password
+// has already changed by this point, so a failure is logged rather than returned.
+func (api *API) recordPasswordHistory(ctx context.Context, userID, password string) {
+	if api.PasswordHistory == nil || userID == "" {
+		return
+	}
+
+	if err := api.PasswordHistory.Add(ctx, userID, password); err != nil {
+		log.Error(ctx, "failed to record password history", err, log.Data{"userID": userID})
+	}
+}
+
 // PasswordResetHandler requests a password reset email be sent to the user and returns a http handler interface
 func (api *API) PasswordResetHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
+	defer api.padResponseTime(ctx, time.Now())
 	defer func() {
 		if err := req.Body.Close(); err != nil {
 			_ = models.NewError(ctx, err, models.BodyCloseError, models.BodyClosedFailedDescription)
@@ -403,6 +640,19 @@ func (api *API) PasswordResetHandler(ctx context.Context, _ http.ResponseWriter,
 	_, err = api.CognitoClient.ForgotPassword(ctx, forgotPasswordRequest)
 	if err != nil {
 		responseErr := models.NewCognitoError(ctx, err, "ForgotPassword request from password reset endpoint")
+		api.audit(ctx, AuditEvent{
+			Action:   AuditActionPasswordReset,
+			Email:    passwordResetParams.Email,
+			ClientIP: clientIP(req, api.RateLimits.TrustedProxies),
+			Outcome:  AuditOutcomeFailure,
+			Cause:    responseErr.Code + ": " + responseErr.Description,
+		})
+
+		if api.AntiEnumeration.Enabled {
+			// the response must not reveal whether the account exists, so every failure is accepted like a success
+			log.Error(ctx, "password reset failed, hidden from client by anti-enumeration mode", responseErr, log.Data{"user_email": passwordResetParams.Email})
+			return models.NewSuccessResponse(nil, http.StatusAccepted, nil), nil
+		}
 
 		if responseErr.Code == models.LimitExceededError || responseErr.Code == models.TooManyRequestsError {
 			log.Error(ctx, "cognito request limit exceeded", responseErr, log.Data{"user_email": passwordResetParams.Email})
@@ -411,6 +661,13 @@ func (api *API) PasswordResetHandler(ctx context.Context, _ http.ResponseWriter,
 			log.Error(ctx, "user not found or user not confirmed", responseErr, log.Data{"user_email": passwordResetParams.Email})
 			return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 		}
+	} else {
+		api.audit(ctx, AuditEvent{
+			Action:   AuditActionPasswordReset,
+			Email:    passwordResetParams.Email,
+			ClientIP: clientIP(req, api.RateLimits.TrustedProxies),
+			Outcome:  AuditOutcomeSuccess,
+		})
 	}
 
 	log.Info(ctx, "password reset completed", log.Data{"user_email": passwordResetParams.Email})
@@ -419,7 +676,8 @@ func (api *API) PasswordResetHandler(ctx context.Context, _ http.ResponseWriter,
 }
 
 // List Groups for user pagination allows first call and then any other call if nextToken is not ""
-func (api *API) getGroupsForUser(ctx context.Context, listOfGroups []types.GroupType, userID models.UserParams) ([]types.GroupType, error) {
+// When effective is true the groups that the user's groups are nested in are added, and returned as inherited
+func (api *API) getGroupsForUser(ctx context.Context, listOfGroups []types.GroupType, userID models.UserParams, effective bool) ([]types.GroupType, map[string]bool, error) {
 	firstTimeCheck := false
 	var nextToken string
 	for !firstTimeCheck || nextToken != "" {
@@ -428,7 +686,7 @@ func (api *API) getGroupsForUser(ctx context.Context, listOfGroups []types.Group
 		userGroupsRequest := userID.BuildListUserGroupsRequest(api.UserPoolID, nextToken)
 		userGroupsResponse, err := api.CognitoClient.AdminListGroupsForUser(ctx, userGroupsRequest)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		listOfGroups = append(listOfGroups, userGroupsResponse.Groups...)
@@ -437,10 +695,23 @@ func (api *API) getGroupsForUser(ctx context.Context, listOfGroups []types.Group
 			nextToken = *userGroupsResponse.NextToken
 		}
 	}
-	return listOfGroups, nil
+
+	if !effective {
+		return listOfGroups, nil, nil
+	}
+	inheritedGroups, err := api.getInheritedGroups(ctx, listOfGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	inherited := make(map[string]bool, len(inheritedGroups))
+	for i := range inheritedGroups {
+		inherited[aws.ToString(inheritedGroups[i].GroupName)] = true
+	}
+	return append(listOfGroups, inheritedGroups...), inherited, nil
 }
 
-// ListUserGroupsHandler lists the users in the user pool
+// ListUserGroupsHandler lists the groups a user is a member of, including those inherited through nested groups when
+// ?effective=true is given
 func (api *API) ListUserGroupsHandler(ctx context.Context, _ http.ResponseWriter, req *http.Request) (*models.SuccessResponse, *models.ErrorResponse) {
 	vars := mux.Vars(req)
 	userID := models.UserParams{ID: vars["id"]}
@@ -448,7 +719,12 @@ func (api *API) ListUserGroupsHandler(ctx context.Context, _ http.ResponseWriter
 	finalUserResponse := cognitoidentityprovider.AdminListGroupsForUserOutput{}
 	listusergroups := models.ListUserGroups{}
 
-	listofGroupsOutput, err := api.getGroupsForUser(ctx, listofgroupsInput, userID)
+	effective, errResponse := parseBoolQuery(ctx, req, "effective")
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
+	listofGroupsOutput, inherited, err := api.getGroupsForUser(ctx, listofgroupsInput, userID, effective)
 	if err != nil {
 		cognitoErr := models.NewCognitoError(ctx, err, "Cognito ListofUserGroups request from list user groups endpoint")
 		if cognitoErr.Code == models.NotFoundError {
@@ -457,7 +733,13 @@ func (api *API) ListUserGroupsHandler(ctx context.Context, _ http.ResponseWriter
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, cognitoErr)
 	}
 	finalUserResponse.Groups = append(finalUserResponse.Groups, listofGroupsOutput...)
-	jsonResponse, responseErr := listusergroups.BuildListUserGroupsSuccessfulJSONResponse(ctx, &finalUserResponse)
+
+	expiries, err := api.MembershipExpiry.ListUserMembershipExpiries(ctx, userID.ID)
+	if err != nil {
+		return nil, handleMembershipExpiryError(ctx, err)
+	}
+
+	jsonResponse, responseErr := listusergroups.BuildListUserGroupsSuccessfulJSONResponse(ctx, &finalUserResponse, expiries, inherited)
 	if responseErr != nil {
 		return nil, models.NewErrorResponse(http.StatusInternalServerError, nil, responseErr)
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"testing"
 
 	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/ONSdigital/dp-identity-api/v2/validation"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	"github.com/gorilla/mux"
@@ -36,6 +39,8 @@ const (
 	changePasswordEndPoint                        = "http://localhost:25600/v1/users/self/password"    // #nosec
 	requestResetEndPoint                          = "http://localhost:25600/v1/password-reset"
 	userListGroupsEndPoint                        = "http://localhost:25600/v1/users/abcd1234/groups"
+	userEmailEndPoint                             = "http://localhost:25600/v1/users/abcd1234/email"
+	verifyEmailEndPoint                           = "http://localhost:25600/v1/users/self/email"
 )
 
 func TestCreateUserHandler(t *testing.T) {
@@ -1065,6 +1070,294 @@ func TestSetUserPasswordHandler(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given password history is enabled", t, func() {
+		var setPassword string
+		mockAPI.PasswordHistory = store.NewPasswordHistory(store.NewInMemoryPasswordHistoryStore(), 3)
+		defer func() { mockAPI.PasswordHistory = nil }()
+		mockCognito.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			user := &cognitoidentityprovider.AdminGetUserOutput{
+				UserStatus: types.UserStatusTypeForceChangePassword,
+				Username:   &userID,
+				Enabled:    true,
+			}
+			return user, nil
+		}
+		mockCognito.AdminSetUserPasswordFunc = func(_ context.Context, input *cognitoidentityprovider.AdminSetUserPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+			setPassword = *input.Password
+			return &cognitoidentityprovider.AdminSetUserPasswordOutput{}, nil
+		}
+
+		Convey("When the SetUserPasswordHandler is called", func() {
+			r := httptest.NewRequest(http.MethodGet, userSetPasswordEndPoint, http.NoBody)
+			r = mux.SetURLVars(r, map[string]string{"id": userID})
+			successResponse, errorResponse := mockAPI.UserSetPasswordHandler(ctx, w, r)
+
+			Convey("Then the generated password is not added to the user's password history", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusAccepted)
+				So(setPassword, ShouldNotBeEmpty)
+
+				reused, err := mockAPI.PasswordHistory.Contains(ctx, userID, setPassword)
+				So(err, ShouldBeNil)
+				So(reused, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a password policy that generated passwords cannot meet", t, func() {
+		setPasswordCalled := false
+		mockAPI.PasswordPolicy = &validation.PasswordPolicy{MinLength: 20}
+		mockCognito.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			user := &cognitoidentityprovider.AdminGetUserOutput{
+				UserStatus: types.UserStatusTypeForceChangePassword,
+				Username:   &userID,
+				Enabled:    true,
+			}
+			return user, nil
+		}
+		mockCognito.AdminSetUserPasswordFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminSetUserPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+			setPasswordCalled = true
+			return &cognitoidentityprovider.AdminSetUserPasswordOutput{}, nil
+		}
+
+		Convey("When the SetUserPasswordHandler is called", func() {
+			r := httptest.NewRequest(http.MethodGet, userSetPasswordEndPoint, http.NoBody)
+			successResponse, errorResponse := mockAPI.UserSetPasswordHandler(ctx, w, r)
+
+			Convey("Then the password policy errors are returned without calling Cognito", func() {
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.InvalidPasswordError)
+				So(castErr.Description, ShouldEqual, models.PasswordTooShortDescription)
+				So(setPasswordCalled, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestUpdateUserEmailHandler(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		userID   = "abcd1234"
+		newEmail = "new.email@ons.gov.uk"
+		urlVars  = map[string]string{"id": userID}
+	)
+
+	mockAPI, w, mockCognito := apiMockSetup()
+
+	noUsersFound := func(_ context.Context, _ *cognitoidentityprovider.ListUsersInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersOutput, error) {
+		return &cognitoidentityprovider.ListUsersOutput{Users: []types.UserType{}}, nil
+	}
+	verificationRequired := func(_ context.Context, _ *cognitoidentityprovider.DescribeUserPoolInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
+		return &cognitoidentityprovider.DescribeUserPoolOutput{
+			UserPool: &types.UserPoolType{
+				UserAttributeUpdateSettings: &types.UserAttributeUpdateSettingsType{
+					AttributesRequireVerificationBeforeUpdate: []types.VerifiedAttributeType{types.VerifiedAttributeTypeEmail},
+				},
+			},
+		}, nil
+	}
+
+	Convey("Given a new email address that is valid and not in use", t, func() {
+		var updateInput *cognitoidentityprovider.AdminUpdateUserAttributesInput
+		mockCognito.ListUsersFunc = noUsersFound
+		mockCognito.DescribeUserPoolFunc = verificationRequired
+		mockCognito.AdminUpdateUserAttributesFunc = func(_ context.Context, input *cognitoidentityprovider.AdminUpdateUserAttributesInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminUpdateUserAttributesOutput, error) {
+			updateInput = input
+			return &cognitoidentityprovider.AdminUpdateUserAttributesOutput{}, nil
+		}
+
+		Convey("When the UpdateUserEmailHandler is called", func() {
+			body, _ := json.Marshal(map[string]interface{}{"email": newEmail})
+			r := httptest.NewRequest(http.MethodPut, userEmailEndPoint, bytes.NewReader(body))
+			r = mux.SetURLVars(r, urlVars)
+			successResponse, errorResponse := mockAPI.UpdateUserEmailHandler(ctx, w, r)
+
+			Convey("Then the change is accepted pending verification of the new address", func() {
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusAccepted)
+				So(*updateInput.Username, ShouldEqual, userID)
+				So(len(updateInput.UserAttributes), ShouldEqual, 1)
+				So(*updateInput.UserAttributes[0].Value, ShouldEqual, newEmail)
+			})
+		})
+	})
+
+	Convey("Given a new email address that fails the registration rules", t, func() {
+		invalidEmailTests := []string{"new.email@gmail.com", "new.email+01@ons.gov.uk", ""}
+
+		for _, email := range invalidEmailTests {
+			body, _ := json.Marshal(map[string]interface{}{"email": email})
+			r := httptest.NewRequest(http.MethodPut, userEmailEndPoint, bytes.NewReader(body))
+			r = mux.SetURLVars(r, urlVars)
+			successResponse, errorResponse := mockAPI.UpdateUserEmailHandler(ctx, w, r)
+
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.InvalidEmailError)
+		}
+	})
+
+	Convey("Given a new email address already used by another account", t, func() {
+		mockCognito.ListUsersFunc = func(_ context.Context, _ *cognitoidentityprovider.ListUsersInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ListUsersOutput, error) {
+			return &cognitoidentityprovider.ListUsersOutput{Users: []types.UserType{{Username: aws.String("efgh5678")}}}, nil
+		}
+
+		Convey("When the UpdateUserEmailHandler is called", func() {
+			body, _ := json.Marshal(map[string]interface{}{"email": newEmail})
+			r := httptest.NewRequest(http.MethodPut, userEmailEndPoint, bytes.NewReader(body))
+			r = mux.SetURLVars(r, urlVars)
+			successResponse, errorResponse := mockAPI.UpdateUserEmailHandler(ctx, w, r)
+
+			Convey("Then a duplicate email error is returned", func() {
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.InvalidEmailError)
+				So(castErr.Description, ShouldEqual, models.DuplicateEmailDescription)
+			})
+		})
+	})
+
+	Convey("Given a user pool that does not keep the original email active until verification", t, func() {
+		mockCognito.ListUsersFunc = noUsersFound
+		mockCognito.DescribeUserPoolFunc = func(_ context.Context, _ *cognitoidentityprovider.DescribeUserPoolInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
+			return &cognitoidentityprovider.DescribeUserPoolOutput{UserPool: &types.UserPoolType{}}, nil
+		}
+
+		Convey("When the UpdateUserEmailHandler is called", func() {
+			body, _ := json.Marshal(map[string]interface{}{"email": newEmail})
+			r := httptest.NewRequest(http.MethodPut, userEmailEndPoint, bytes.NewReader(body))
+			r = mux.SetURLVars(r, urlVars)
+			successResponse, errorResponse := mockAPI.UpdateUserEmailHandler(ctx, w, r)
+
+			Convey("Then the email is not changed and an internal error is returned", func() {
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.InvalidUserPoolError)
+			})
+		})
+	})
+
+	Convey("Given a user that does not exist", t, func() {
+		mockCognito.ListUsersFunc = noUsersFound
+		mockCognito.DescribeUserPoolFunc = verificationRequired
+		mockCognito.AdminUpdateUserAttributesFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminUpdateUserAttributesInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminUpdateUserAttributesOutput, error) {
+			return nil, &smithy.GenericAPIError{
+				Code:    awsUNFErrCode,
+				Message: awsUNFErrMessage,
+				Fault:   clientError,
+			}
+		}
+
+		Convey("When the UpdateUserEmailHandler is called", func() {
+			body, _ := json.Marshal(map[string]interface{}{"email": newEmail})
+			r := httptest.NewRequest(http.MethodPut, userEmailEndPoint, bytes.NewReader(body))
+			r = mux.SetURLVars(r, urlVars)
+			successResponse, errorResponse := mockAPI.UpdateUserEmailHandler(ctx, w, r)
+
+			Convey("Then a not found error is returned", func() {
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func TestVerifyUserEmailHandler(t *testing.T) {
+	ctx := context.Background()
+
+	mockAPI, w, mockCognito := apiMockSetup()
+
+	Convey("Verify user email - check expected responses", t, func() {
+		verifyEmailTests := []struct {
+			description                 string
+			verifyUserAttributeFunction func(_ context.Context, input *cognitoidentityprovider.VerifyUserAttributeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifyUserAttributeOutput, error)
+			httpResponse                int
+		}{
+			{
+				"Cognito successfully verifies the new email",
+				func(_ context.Context, input *cognitoidentityprovider.VerifyUserAttributeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifyUserAttributeOutput, error) {
+					So(*input.AccessToken, ShouldEqual, "aaaa.bbbb.cccc")
+					So(*input.AttributeName, ShouldEqual, "email")
+					return &cognitoidentityprovider.VerifyUserAttributeOutput{}, nil
+				},
+				http.StatusNoContent,
+			},
+			{
+				"Cognito rejects the verification code",
+				func(_ context.Context, _ *cognitoidentityprovider.VerifyUserAttributeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifyUserAttributeOutput, error) {
+					return nil, &smithy.GenericAPIError{
+						Code:    "CodeMismatchException",
+						Message: "invalid code",
+						Fault:   clientError,
+					}
+				},
+				http.StatusBadRequest,
+			},
+			{
+				"Cognito internal error",
+				func(_ context.Context, _ *cognitoidentityprovider.VerifyUserAttributeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifyUserAttributeOutput, error) {
+					return nil, &smithy.GenericAPIError{
+						Code:    awsErrCode,
+						Message: awsErrMessage,
+						Fault:   serverError,
+					}
+				},
+				http.StatusInternalServerError,
+			},
+		}
+
+		for _, tt := range verifyEmailTests {
+			Convey(tt.description, func() {
+				mockCognito.VerifyUserAttributeFunc = tt.verifyUserAttributeFunction
+
+				body, _ := json.Marshal(map[string]interface{}{"verification_token": "123456"})
+				r := httptest.NewRequest(http.MethodPut, verifyEmailEndPoint, bytes.NewReader(body))
+				r.Header.Set(AccessTokenHeaderName, "Bearer aaaa.bbbb.cccc")
+
+				successResponse, errorResponse := mockAPI.VerifyUserEmailHandler(ctx, w, r)
+
+				if tt.httpResponse > 399 {
+					So(successResponse, ShouldBeNil)
+					So(errorResponse.Status, ShouldEqual, tt.httpResponse)
+				} else {
+					So(errorResponse, ShouldBeNil)
+					So(successResponse.Status, ShouldEqual, tt.httpResponse)
+				}
+			})
+		}
+	})
+
+	Convey("Validation fails 400: no access token or verification token provided", t, func() {
+		validationTests := []struct {
+			authHeader string
+			body       map[string]interface{}
+			errorCode  string
+		}{
+			{"", map[string]interface{}{"verification_token": "123456"}, models.InvalidTokenError},
+			{"Bearer aaaa.bbbb.cccc", map[string]interface{}{"verification_token": ""}, models.InvalidTokenError},
+		}
+
+		for _, tt := range validationTests {
+			body, _ := json.Marshal(tt.body)
+			r := httptest.NewRequest(http.MethodPut, verifyEmailEndPoint, bytes.NewReader(body))
+			if tt.authHeader != "" {
+				r.Header.Set(AccessTokenHeaderName, tt.authHeader)
+			}
+
+			successResponse, errorResponse := mockAPI.VerifyUserEmailHandler(ctx, w, r)
+
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			castErr := errorResponse.Errors[0].(*models.Error)
+			So(castErr.Code, ShouldEqual, tt.errorCode)
+		}
+	})
 }
 
 func TestProcessUpdateCognitoError(t *testing.T) {
@@ -1120,10 +1413,14 @@ func TestChangePasswordHandler(t *testing.T) {
 		email, password, session                 = "foo_bar123@ext.ons.gov.uk", "Password2", "auth-challenge-session"
 		accessToken, idToken, refreshToken       = "aaaa.bbbb.cccc", "llll.mmmm.nnnn", "zzzz.yyyy.xxxx.wwww.vvvv"
 		expireLength                       int32 = 500
+		challengePassword                  string
 	)
 
 	api, w, m := apiMockSetup()
 
+	m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+		return &cognitoidentityprovider.AdminGetUserOutput{Username: aws.String("abcd1234"), Enabled: true}, nil
+	}
 	m.DescribeUserPoolClientFunc = func(_ context.Context, _ *cognitoidentityprovider.DescribeUserPoolClientInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DescribeUserPoolClientOutput, error) {
 		tokenValidDays := int32(1)
 		refreshTokenUnits := types.TimeUnitsTypeDays
@@ -1146,7 +1443,8 @@ func TestChangePasswordHandler(t *testing.T) {
 		}{
 			{
 				// Cognito successful password change
-				func(_ context.Context, _ *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+				func(_ context.Context, input *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+					challengePassword = input.ChallengeResponses["NEW_PASSWORD"]
 					return &cognitoidentityprovider.RespondToAuthChallengeOutput{
 						AuthenticationResult: &types.AuthenticationResultType{
 							AccessToken:  &accessToken,
@@ -1164,6 +1462,7 @@ func TestChangePasswordHandler(t *testing.T) {
 					So(errorResponse, ShouldBeNil)
 					So(responseBody["expirationTime"], ShouldNotBeNil)
 					So(responseBody["refreshTokenExpirationTime"], ShouldNotBeNil)
+					So(challengePassword, ShouldEqual, password)
 				},
 			},
 			{
@@ -1243,6 +1542,250 @@ func TestChangePasswordHandler(t *testing.T) {
 			tt.assertions(successResponse, errorResponse)
 		}
 	})
+
+	Convey("AdminGetUser - check expected responses before the session is used", t, func() {
+		cognitoCalled := false
+		m.RespondToAuthChallengeFunc = func(_ context.Context, _ *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+			cognitoCalled = true
+			return nil, &smithy.GenericAPIError{Code: "CodeMismatchException", Message: "invalid session"}
+		}
+		changePassword := func() (*models.SuccessResponse, *models.ErrorResponse) {
+			cognitoCalled = false
+			postBody := map[string]interface{}{"type": models.NewPasswordRequiredType, "email": email, "password": password, "session": session}
+			body, _ := json.Marshal(postBody)
+			r := httptest.NewRequest(http.MethodPut, changePasswordEndPoint, bytes.NewReader(body))
+			return api.ChangePasswordHandler(ctx, w, r)
+		}
+
+		Convey("Cognito internal error leaves the session unused", func() {
+			m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+				return nil, &smithy.GenericAPIError{Code: awsErrCode, Message: awsErrMessage}
+			}
+
+			successResponse, errorResponse := changePassword()
+
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusInternalServerError)
+			So(cognitoCalled, ShouldBeFalse)
+		})
+
+		Convey("A user that cannot be found is left for Cognito to reject", func() {
+			m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+				return nil, &smithy.GenericAPIError{Code: "UserNotFoundException", Message: "user not found"}
+			}
+
+			successResponse, errorResponse := changePassword()
+
+			So(successResponse, ShouldBeNil)
+			So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+			So(errorResponse.Errors[0].(*models.Error).Code, ShouldEqual, models.InvalidCodeError)
+			So(cognitoCalled, ShouldBeTrue)
+		})
+	})
+}
+
+func TestChangePasswordHandlerPasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	api, w, m := apiMockSetup()
+	api.PasswordPolicy = &validation.PasswordPolicy{
+		MinLength:     10,
+		RequireSymbol: true,
+		BannedWords:   []string{"password"},
+	}
+
+	cognitoCalled := false
+	m.RespondToAuthChallengeFunc = func(_ context.Context, _ *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+		cognitoCalled = true
+		return nil, errors.New("unexpected call to RespondToAuthChallenge")
+	}
+	m.ConfirmForgotPasswordFunc = func(_ context.Context, _ *cognitoidentityprovider.ConfirmForgotPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ConfirmForgotPasswordOutput, error) {
+		cognitoCalled = true
+		return nil, errors.New("unexpected call to ConfirmForgotPassword")
+	}
+
+	Convey("Change password - a new password breaking the password policy is rejected before Cognito is called", t, func() {
+		policyTests := []struct {
+			description          string
+			postBody             map[string]interface{}
+			expectedDescriptions []string
+		}{
+			{
+				"NewPasswordRequired with a short password containing a banned word",
+				map[string]interface{}{"type": models.NewPasswordRequiredType, "email": "jane.smith@ons.gov.uk", "password": "Password1", "session": "auth-challenge-session"},
+				[]string{models.PasswordTooShortDescription, models.PasswordMissingSymbolDescription, models.PasswordBannedWordDescription},
+			},
+			{
+				"ForgottenPassword with a password missing a symbol",
+				map[string]interface{}{"type": models.ForgottenPasswordType, "email": "abcd1234", "password": "Correct2024Horse", "verification_token": "verification-token"},
+				[]string{models.PasswordMissingSymbolDescription},
+			},
+		}
+
+		for _, tt := range policyTests {
+			Convey(tt.description, func() {
+				cognitoCalled = false
+				body, _ := json.Marshal(tt.postBody)
+				r := httptest.NewRequest(http.MethodPut, changePasswordEndPoint, bytes.NewReader(body))
+
+				successResponse, errorResponse := api.ChangePasswordHandler(ctx, w, r)
+
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				So(len(errorResponse.Errors), ShouldEqual, len(tt.expectedDescriptions))
+				for i, expectedDescription := range tt.expectedDescriptions {
+					castErr := errorResponse.Errors[i].(*models.Error)
+					So(castErr.Code, ShouldEqual, models.InvalidPasswordError)
+					So(castErr.Description, ShouldEqual, expectedDescription)
+				}
+				So(cognitoCalled, ShouldBeFalse)
+			})
+		}
+	})
+
+	Convey("Change password - a new password containing the user's name is rejected before Cognito is called", t, func() {
+		var getUserInput, challengePassword string
+		m.RespondToAuthChallengeFunc = func(_ context.Context, input *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+			challengePassword = input.ChallengeResponses["NEW_PASSWORD"]
+			return &cognitoidentityprovider.RespondToAuthChallengeOutput{AuthenticationResult: &types.AuthenticationResultType{}}, nil
+		}
+		m.ConfirmForgotPasswordFunc = func(_ context.Context, input *cognitoidentityprovider.ConfirmForgotPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ConfirmForgotPasswordOutput, error) {
+			challengePassword = *input.Password
+			return &cognitoidentityprovider.ConfirmForgotPasswordOutput{}, nil
+		}
+		m.AdminGetUserFunc = func(_ context.Context, input *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			getUserInput = *input.Username
+			return &cognitoidentityprovider.AdminGetUserOutput{
+				Username: aws.String("abcd1234"),
+				UserAttributes: []types.AttributeType{
+					{Name: aws.String("given_name"), Value: aws.String("Jane")},
+					{Name: aws.String("family_name"), Value: aws.String("Smith")},
+					{Name: aws.String("email"), Value: aws.String("js@ons.gov.uk")},
+				},
+			}, nil
+		}
+
+		for _, postBody := range []map[string]interface{}{
+			{"type": models.NewPasswordRequiredType, "email": "js@ons.gov.uk", "password": "Smith-2024-abc", "session": "auth-challenge-session"},
+			{"type": models.ForgottenPasswordType, "email": "abcd1234", "password": "Smith-2024-abc", "verification_token": "verification-token"},
+		} {
+			Convey(postBody["type"].(string), func() {
+				body, _ := json.Marshal(postBody)
+				r := httptest.NewRequest(http.MethodPut, changePasswordEndPoint, bytes.NewReader(body))
+
+				successResponse, errorResponse := api.ChangePasswordHandler(ctx, w, r)
+
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				So(len(errorResponse.Errors), ShouldEqual, 1)
+				So(errorResponse.Errors[0].(*models.Error).Description, ShouldEqual, models.PasswordPersonalInfoDescription)
+				So(getUserInput, ShouldEqual, postBody["email"])
+				So(challengePassword, ShouldBeEmpty)
+			})
+		}
+	})
+}
+
+func TestChangePasswordHandlerPasswordHistory(t *testing.T) {
+	var (
+		ctx                  = context.Background()
+		userID, email        = "abcd1234", "jane.smith@ons.gov.uk"
+		oldPassword          = "Correct-Horse-1"
+		accessToken, idToken = "aaaa.bbbb.cccc", "llll.mmmm.nnnn"
+		refreshToken         = "zzzz.yyyy.xxxx.wwww.vvvv"
+		cognitoCalled        bool
+		challengePassword    string
+		expireLength         int32 = 500
+	)
+
+	api, w, m := apiMockSetup()
+	api.PasswordHistory = store.NewPasswordHistory(store.NewInMemoryPasswordHistoryStore(), 3)
+
+	m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+		return &cognitoidentityprovider.AdminGetUserOutput{Username: aws.String(userID)}, nil
+	}
+	m.RespondToAuthChallengeFunc = func(_ context.Context, input *cognitoidentityprovider.RespondToAuthChallengeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+		cognitoCalled, challengePassword = true, input.ChallengeResponses["NEW_PASSWORD"]
+		return &cognitoidentityprovider.RespondToAuthChallengeOutput{
+			AuthenticationResult: &types.AuthenticationResultType{
+				AccessToken:  &accessToken,
+				ExpiresIn:    expireLength,
+				IdToken:      &idToken,
+				RefreshToken: &refreshToken,
+			},
+		}, nil
+	}
+	m.DescribeUserPoolClientFunc = func(_ context.Context, _ *cognitoidentityprovider.DescribeUserPoolClientInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.DescribeUserPoolClientOutput, error) {
+		return &cognitoidentityprovider.DescribeUserPoolClientOutput{
+			UserPoolClient: &types.UserPoolClientType{
+				RefreshTokenValidity: 1,
+				TokenValidityUnits:   &types.TokenValidityUnitsType{RefreshToken: types.TimeUnitsTypeDays},
+			},
+		}, nil
+	}
+	m.ConfirmForgotPasswordFunc = func(_ context.Context, input *cognitoidentityprovider.ConfirmForgotPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ConfirmForgotPasswordOutput, error) {
+		cognitoCalled, challengePassword = true, *input.Password
+		return &cognitoidentityprovider.ConfirmForgotPasswordOutput{}, nil
+	}
+
+	changePassword := func(postBody map[string]interface{}) (*models.SuccessResponse, *models.ErrorResponse) {
+		cognitoCalled, challengePassword = false, ""
+		body, _ := json.Marshal(postBody)
+		r := httptest.NewRequest(http.MethodPut, changePasswordEndPoint, bytes.NewReader(body))
+		return api.ChangePasswordHandler(ctx, w, r)
+	}
+
+	Convey("Given a user whose password history holds their previous password", t, func() {
+		So(api.PasswordHistory.Add(ctx, userID, oldPassword), ShouldBeNil)
+
+		changeTypeTests := []struct {
+			description string
+			newPassword string
+			postBody    func(password string) map[string]interface{}
+		}{
+			{
+				"NewPasswordRequired",
+				"Battery-Staple-2",
+				func(password string) map[string]interface{} {
+					return map[string]interface{}{"type": models.NewPasswordRequiredType, "email": email, "password": password, "session": "auth-challenge-session"}
+				},
+			},
+			{
+				"ForgottenPassword",
+				"Battery-Staple-3",
+				func(password string) map[string]interface{} {
+					return map[string]interface{}{"type": models.ForgottenPasswordType, "email": userID, "password": password, "verification_token": "verification-token"}
+				},
+			},
+		}
+
+		for _, tt := range changeTypeTests {
+			Convey(tt.description+": reusing the previous password is rejected before Cognito is called", func() {
+				successResponse, errorResponse := changePassword(tt.postBody(oldPassword))
+
+				So(successResponse, ShouldBeNil)
+				So(errorResponse.Status, ShouldEqual, http.StatusBadRequest)
+				So(len(errorResponse.Errors), ShouldEqual, 1)
+				castErr := errorResponse.Errors[0].(*models.Error)
+				So(castErr.Code, ShouldEqual, models.InvalidPasswordError)
+				So(castErr.Description, ShouldEqual, models.PasswordReusedDescription)
+				So(cognitoCalled, ShouldBeFalse)
+			})
+
+			Convey(tt.description+": a new password is accepted and added to the history", func() {
+				successResponse, errorResponse := changePassword(tt.postBody(tt.newPassword))
+
+				So(errorResponse, ShouldBeNil)
+				So(successResponse.Status, ShouldEqual, http.StatusAccepted)
+				So(cognitoCalled, ShouldBeTrue)
+				So(challengePassword, ShouldEqual, tt.newPassword)
+
+				reused, err := api.PasswordHistory.Contains(ctx, userID, tt.newPassword)
+				So(err, ShouldBeNil)
+				So(reused, ShouldBeTrue)
+			})
+		}
+	})
 }
 
 func TestConfirmForgotPasswordChangePasswordHandler(t *testing.T) {
@@ -1254,6 +1797,12 @@ func TestConfirmForgotPasswordChangePasswordHandler(t *testing.T) {
 	)
 
 	api, w, m := apiMockSetup()
+	m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+		return &cognitoidentityprovider.AdminGetUserOutput{Username: aws.String("abcd1234"), Enabled: true}, nil
+	}
+	m.AdminSetUserPasswordFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminSetUserPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+		return &cognitoidentityprovider.AdminSetUserPasswordOutput{}, nil
+	}
 	Convey("ConfirmForgotPassword - check expected responses", t, func() {
 		confirmForgotPasswordTests := []struct {
 			confirmForgotPasswordFunction func(_ context.Context, _ *cognitoidentityprovider.ConfirmForgotPasswordInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ConfirmForgotPasswordOutput, error)
@@ -1654,7 +2203,7 @@ func TestGetGroupsforUser(t *testing.T) {
 			return nil, &userNotFoundException
 		}
 
-		listGroupsforUserResponse, errorResponse := api.getGroupsForUser(ctx, nil, userID)
+		listGroupsforUserResponse, _, errorResponse := api.getGroupsForUser(ctx, nil, userID, false)
 
 		So(listGroupsforUserResponse, ShouldBeNil)
 		So(errorResponse.Error(), ShouldResemble, "ResourceNotFoundException: User not found")
@@ -1678,7 +2227,7 @@ func TestGetGroupsforUser(t *testing.T) {
 			return listGroupsForUser, nil
 		}
 
-		listOfUsersResponse, errorResponse := api.getGroupsForUser(ctx, nil, userID)
+		listOfUsersResponse, _, errorResponse := api.getGroupsForUser(ctx, nil, userID, false)
 
 		So(listOfUsersResponse, ShouldResemble, listOfGroupsForUser)
 
@@ -1723,7 +2272,7 @@ func TestGetGroupsforUser(t *testing.T) {
 			return listGroupsForUser, nil
 		}
 
-		listGroupsForUserResponse, errorResponse := api.getGroupsForUser(ctx, listOfGroups, userID)
+		listGroupsForUserResponse, _, errorResponse := api.getGroupsForUser(ctx, listOfGroups, userID, false)
 
 		So(listGroupsForUserResponse, ShouldResemble, listOfGroupsForUser)
 		So(errorResponse, ShouldBeNil)
@@ -1756,7 +2305,7 @@ func TestGetGroupsforUser(t *testing.T) {
 			return listGroupsForUser, nil
 		}
 
-		listGroupsForUseResponse, errorResponse := api.getGroupsForUser(ctx, listOfGroups, userID)
+		listGroupsForUseResponse, _, errorResponse := api.getGroupsForUser(ctx, listOfGroups, userID, false)
 
 		So(listGroupsForUseResponse, ShouldResemble, returnedlistOfGroups)
 		So(errorResponse, ShouldBeNil)
@@ -34,4 +34,5 @@ type Client interface {
 	AdminListGroupsForUser(ctx context.Context, params *cognito.AdminListGroupsForUserInput, optFns ...func(*cognito.Options)) (*cognito.AdminListGroupsForUserOutput, error)
 	AdminDeleteUser(ctx context.Context, params *cognito.AdminDeleteUserInput, optFns ...func(*cognito.Options)) (*cognito.AdminDeleteUserOutput, error)
 	AdminSetUserPassword(ctx context.Context, params *cognito.AdminSetUserPasswordInput, optFns ...func(*cognito.Options)) (*cognito.AdminSetUserPasswordOutput, error)
+	VerifyUserAttribute(ctx context.Context, params *cognito.VerifyUserAttributeInput, optFns ...func(*cognito.Options)) (*cognito.VerifyUserAttributeOutput, error)
 }
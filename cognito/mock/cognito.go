@@ -34,6 +34,7 @@ type MockCognitoIdentityProviderClient struct { //nolint:revive // Mock here is
 	ListUsersInGroupFunc          func(ctx context.Context, input *cognito.ListUsersInGroupInput, optFns ...func(*cognito.Options)) (*cognito.ListUsersInGroupOutput, error)
 	RespondToAuthChallengeFunc    func(ctx context.Context, params *cognito.RespondToAuthChallengeInput, optFns ...func(*cognito.Options)) (*cognito.RespondToAuthChallengeOutput, error)
 	UpdateGroupFunc               func(ctx context.Context, input *cognito.UpdateGroupInput, optFns ...func(*cognito.Options)) (*cognito.UpdateGroupOutput, error)
+	VerifyUserAttributeFunc       func(ctx context.Context, input *cognito.VerifyUserAttributeInput, optFns ...func(*cognito.Options)) (*cognito.VerifyUserAttributeOutput, error)
 }
 
 func (m *MockCognitoIdentityProviderClient) DescribeUserPool(ctx context.Context, poolInputData *cognito.DescribeUserPoolInput, _ ...func(*cognito.Options)) (*cognito.DescribeUserPoolOutput, error) {
@@ -140,3 +141,7 @@ func (m *MockCognitoIdentityProviderClient) ListGroups(ctx context.Context, inpu
 func (m *MockCognitoIdentityProviderClient) UpdateGroup(ctx context.Context, input *cognito.UpdateGroupInput, _ ...func(*cognito.Options)) (*cognito.UpdateGroupOutput, error) {
 	return m.UpdateGroupFunc(ctx, input, nil)
 }
+
+func (m *MockCognitoIdentityProviderClient) VerifyUserAttribute(ctx context.Context, input *cognito.VerifyUserAttributeInput, _ ...func(*cognito.Options)) (*cognito.VerifyUserAttributeOutput, error) {
+	return m.VerifyUserAttributeFunc(ctx, input, nil)
+}
@@ -391,7 +391,8 @@ func (m *CognitoIdentityProviderClientStub) AdminGetUser(_ context.Context, inpu
 		givenNameAttr, familyNameAttr, emailAttr = "given_name", "family_name", "email"
 	)
 	for _, user := range m.Users {
-		if user.ID == *input.Username {
+		// the email address is an alias for the username
+		if user.ID == *input.Username || user.Email == *input.Username {
 			if user.Email == "internal.error@ons.gov.uk" {
 				return nil, &smithy.GenericAPIError{
 					Code:    errCodeInternalError,
@@ -416,6 +417,10 @@ func (m *CognitoIdentityProviderClientStub) AdminGetUser(_ context.Context, inpu
 						Name:  &emailAttr,
 						Value: aws.String(user.Email),
 					},
+					{
+						Name:  aws.String("custom:status_notes"),
+						Value: aws.String(user.StatusNotes),
+					},
 				},
 				Enabled:    user.Active,
 				UserStatus: user.Status,
@@ -761,6 +766,15 @@ func (m *CognitoIdentityProviderClientStub) DeleteGroup(_ context.Context, input
 			Message: "get group - group not found",
 		}
 	}
+
+	var remainingGroups []*Group
+	for _, group := range m.Groups {
+		if group.Name != *input.GroupName {
+			remainingGroups = append(remainingGroups, group)
+		}
+	}
+	m.Groups = remainingGroups
+
 	return nil, nil
 }
 
@@ -921,3 +935,8 @@ func (m *CognitoIdentityProviderClientStub) UpdateGroup(_ context.Context, input
 	}
 	return updateGroupOutput, nil
 }
+
+// VerifyUserAttribute - Added to fully implement interface, email verification is not covered by the component tests
+func (m *CognitoIdentityProviderClientStub) VerifyUserAttribute(_ context.Context, _ *cognitoidentityprovider.VerifyUserAttributeInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifyUserAttributeOutput, error) {
+	return &cognitoidentityprovider.VerifyUserAttributeOutput{}, nil
+}
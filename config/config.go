@@ -26,6 +26,30 @@ type Config struct {
 	MessageAction              types.MessageActionType `envconfig:"MESSAGE_ACTION"`
 	HTTPWriteTimeout           *time.Duration          `envconfig:"HTTP_WRITE_TIMEOUT"`
 	BlockPlusAddressing        bool                    `envconfig:"ENABLE_PLUS_EMAIL_BLOCKING"`
+	PasswordMinLength          int                     `envconfig:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUppercase   bool                    `envconfig:"PASSWORD_REQUIRE_UPPERCASE"`
+	PasswordRequireLowercase   bool                    `envconfig:"PASSWORD_REQUIRE_LOWERCASE"`
+	PasswordRequireNumber      bool                    `envconfig:"PASSWORD_REQUIRE_NUMBER"`
+	PasswordRequireSymbol      bool                    `envconfig:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordBannedWords        []string                `envconfig:"PASSWORD_BANNED_WORDS"`
+	PasswordBreachedListPath   string                  `envconfig:"PASSWORD_BREACHED_LIST_PATH"`
+	PasswordHistorySize        int                     `envconfig:"PASSWORD_HISTORY_SIZE"`
+	PasswordHistoryStore       string                  `envconfig:"PASSWORD_HISTORY_STORE"`
+	RateLimitEnabled           bool                    `envconfig:"RATE_LIMIT_ENABLED"`
+	RateLimitIPBurst           int                     `envconfig:"RATE_LIMIT_IP_BURST"`
+	RateLimitIPInterval        time.Duration           `envconfig:"RATE_LIMIT_IP_INTERVAL"`
+	RateLimitEmailBurst        int                     `envconfig:"RATE_LIMIT_EMAIL_BURST"`
+	RateLimitEmailInterval     time.Duration           `envconfig:"RATE_LIMIT_EMAIL_INTERVAL"`
+	RateLimitTrustedProxies    int                     `envconfig:"RATE_LIMIT_TRUSTED_PROXIES"`
+	AntiEnumerationEnabled     bool                    `envconfig:"ANTI_ENUMERATION_ENABLED"`
+	AntiEnumerationMinTime     time.Duration           `envconfig:"ANTI_ENUMERATION_MIN_RESPONSE_TIME"`
+	MembershipExpiryInterval   time.Duration           `envconfig:"MEMBERSHIP_EXPIRY_INTERVAL"`
+	IdempotencyKeyWindow       time.Duration           `envconfig:"IDEMPOTENCY_KEY_WINDOW"`
+	GroupsReportConcurrency    int                     `envconfig:"GROUPS_REPORT_CONCURRENCY"`
+	DataStore                  string                  `envconfig:"DATA_STORE"`
+	RedisAddress               string                  `envconfig:"REDIS_ADDRESS"`
+	RedisPassword              string                  `envconfig:"REDIS_PASSWORD" json:"-"`
+	RedisDatabase              int                     `envconfig:"REDIS_DATABASE"`
 
 	AuthorisationConfig *authorisation.Config
 }
@@ -50,6 +74,19 @@ func Get() (*Config, error) {
 		AuthorisationConfig:        authorisation.NewDefaultConfig(),
 		BlockPlusAddressing:        true,
 		HTTPWriteTimeout:           nil,
+		PasswordMinLength:          8,
+		PasswordHistoryStore:       "cognito",
+		RateLimitEnabled:           true,
+		RateLimitIPBurst:           20,
+		RateLimitIPInterval:        3 * time.Second,
+		RateLimitEmailBurst:        5,
+		RateLimitEmailInterval:     time.Minute,
+		RateLimitTrustedProxies:    1,
+		AntiEnumerationMinTime:     time.Second,
+		MembershipExpiryInterval:   time.Minute,
+		IdempotencyKeyWindow:       24 * time.Hour,
+		GroupsReportConcurrency:    5,
+		DataStore:                  "memory",
 	}
 
 	return cfg, envconfig.Process("", cfg)
@@ -36,6 +36,19 @@ func TestConfig(t *testing.T) {
 					BlockPlusAddressing:        true,
 					MessageAction:              "",
 					HTTPWriteTimeout:           nil,
+					PasswordMinLength:          8,
+					PasswordHistoryStore:       "cognito",
+					RateLimitEnabled:           true,
+					RateLimitIPBurst:           20,
+					RateLimitIPInterval:        3 * time.Second,
+					RateLimitEmailBurst:        5,
+					RateLimitEmailInterval:     time.Minute,
+					RateLimitTrustedProxies:    1,
+					AntiEnumerationMinTime:     time.Second,
+					MembershipExpiryInterval:   time.Minute,
+					IdempotencyKeyWindow:       24 * time.Hour,
+					GroupsReportConcurrency:    5,
+					DataStore:                  "memory",
 				})
 			})
 
@@ -143,7 +143,25 @@ Feature: Groups - Add User
           }
         ],
         "count": 1,
-        "PaginationToken": ""
+        "PaginationToken": "",
+        "dry_run": false,
+        "to_add": [
+          {
+            "user_id": "abcd1234",
+            "status": "succeeded"
+          }
+        ],
+        "to_remove": [
+          {
+            "user_id": "user_1",
+            "status": "succeeded"
+          },
+          {
+            "user_id": "user_2",
+            "status": "succeeded"
+          }
+        ],
+        "unchanged": []
       }
       """
 
@@ -193,7 +211,21 @@ Feature: Groups - Add User
           }
         ],
         "count": 2,
-        "PaginationToken": ""
+        "PaginationToken": "",
+        "dry_run": false,
+        "to_add": [
+          {
+            "user_id": "abcd1234",
+            "status": "succeeded"
+          }
+        ],
+        "to_remove": [
+          {
+            "user_id": "user_2",
+            "status": "succeeded"
+          }
+        ],
+        "unchanged": ["user_1"]
       }
       """
 
@@ -215,10 +247,64 @@ Feature: Groups - Add User
       {
         "users": [],
         "count": 0,
-        "PaginationToken": ""
+        "PaginationToken": "",
+        "dry_run": false,
+        "to_add": [],
+        "to_remove": [
+          {
+            "user_id": "user_1",
+            "status": "succeeded"
+          },
+          {
+            "user_id": "user_2",
+            "status": "succeeded"
+          }
+        ],
+        "unchanged": []
       }
       """
 
+  Scenario: PUT /v1/groups/{id}/members as a dry run and checking the group is unchanged
+    Given group "test-group" exists in the database
+    And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
+    And user "user_1" is a member of group "test-group"
+    And a user with username "user_2" and email "email@ons.gov.uk" exists in the database
+    And user "user_2" is a member of group "test-group"
+    And there are 2 users in group "test-group"
+    And a user with username "abcd1234" and email "email@ons.gov.uk" exists in the database
+    And I am an admin user
+    When I PUT "/v1/groups/test-group/members?dry_run=true"
+      """
+      [
+        {
+          "user_id": "abcd1234"
+        },
+        {
+          "user_id": "user_1"
+        }
+      ]
+      """
+    Then I should receive the following JSON response with status "200":
+      """
+      {
+        "dry_run": true,
+        "to_add": [
+          {
+            "user_id": "abcd1234",
+            "status": "pending"
+          }
+        ],
+        "to_remove": [
+          {
+            "user_id": "user_2",
+            "status": "pending"
+          }
+        ],
+        "unchanged": ["user_1"]
+      }
+      """
+    And there are 2 users in group "test-group"
+
   Scenario: PUT /v1/groups/{id}/members and non-admin user
     Given group "test-group" exists in the database
     And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
@@ -28,3 +28,72 @@ Feature: Groups - Delete
     Given I am an admin user
     When I DELETE "/v1/groups/internal-error"
     Then the HTTP status code should be "500"
+
+  Scenario: DELETE /v1/groups/{id} for a group with members and checking the response status 409
+    Given group "test-group" exists in the database
+    And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
+    And user "user_1" is a member of group "test-group"
+    And I am an admin user
+    When I DELETE "/v1/groups/test-group"
+    Then I should receive the following JSON response with status "409":
+      """
+      {
+        "errors": [
+          {
+            "code": "GroupNotEmpty",
+            "description": "the group has members, set force=true or transfer_to to delete it"
+          }
+        ]
+      }
+      """
+
+  Scenario: DELETE /v1/groups/{id} with force for a group with members and checking the response status 204
+    Given group "test-group" exists in the database
+    And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
+    And user "user_1" is a member of group "test-group"
+    And I am an admin user
+    When I DELETE "/v1/groups/test-group?force=true"
+    Then the HTTP status code should be "204"
+
+  Scenario: DELETE /v1/groups/{id} transferring the members to another group and checking the response status 204
+    Given group "test-group" exists in the database
+    And group "other-group" exists in the database
+    And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
+    And user "user_1" is a member of group "test-group"
+    And I am an admin user
+    When I DELETE "/v1/groups/test-group?transfer_to=other-group"
+    Then the HTTP status code should be "204"
+    And there are 1 users in group "other-group"
+
+  Scenario: DELETE /v1/groups/{id} for a role group and checking the response status 403
+    Given I am an admin user
+    When I DELETE "/v1/groups/role-admin?force=true"
+    Then I should receive the following JSON response with status "403":
+      """
+      {
+        "errors": [
+          {
+            "code": "ProtectedGroup",
+            "description": "role groups cannot be deleted"
+          }
+        ]
+      }
+      """
+
+  Scenario: DELETE /v1/groups/{id} transferring the members to a role group and checking the response status 403
+    Given group "test-group" exists in the database
+    And a user with username "user_1" and email "email@ons.gov.uk" exists in the database
+    And user "user_1" is a member of group "test-group"
+    And I am an admin user
+    When I DELETE "/v1/groups/test-group?transfer_to=role-admin"
+    Then I should receive the following JSON response with status "403":
+      """
+      {
+        "errors": [
+          {
+            "code": "ProtectedGroup",
+            "description": "members cannot be transferred to role groups"
+          }
+        ]
+      }
+      """
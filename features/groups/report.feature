@@ -144,3 +144,54 @@ Feature: Groups - Report
       test group_5 description,email3@ons.gov.uk
       """
     And the response header "Content-Type" should contain "text/csv"
+
+  Scenario: GET /v1/groups-report limited to a group with extra columns and checking the response status 200
+    Given I am an admin user
+    And group "test-group_1" and description "test group_1 description" exists in the database
+    And group "test-group_2" and description "test group_2 description" exists in the database
+    And a user with username "abcd1234" and email "email1@ons.gov.uk" exists in the database
+    And user "abcd1234" is a member of group "test-group_1"
+    And user "abcd1234" is a member of group "test-group_2"
+    When I GET "/v1/groups-report?group_id=test-group_2&columns=user_id,group_id"
+    Then I should receive the following JSON response with status "200":
+      """
+      [
+        {
+          "group": "test group_2 description",
+          "user": "email1@ons.gov.uk",
+          "user_id": "abcd1234",
+          "group_id": "test-group_2"
+        }
+      ]
+      """
+
+  Scenario: GET /v1/groups-report as newline delimited JSON and checking the response status 200
+    Given I am an admin user
+    And group "test-group_1" and description "test group_1 description" exists in the database
+    And group "test-group_2" and description "test group_2 description" exists in the database
+    And a user with username "abcd1234" and email "email1@ons.gov.uk" exists in the database
+    And user "abcd1234" is a member of group "test-group_1"
+    And user "abcd1234" is a member of group "test-group_2"
+    And request header Accept is "application/x-ndjson"
+
+    When I GET "/v1/groups-report"
+    Then the HTTP status code should be "200"
+    And the response header "Content-Type" should contain "application/x-ndjson"
+    And the response header "Content-Disposition" should contain "attachment; filename=groups-report.ndjson"
+    And the response should match the following csv:
+      """
+      {"group":"test group_1 description","user":"email1@ons.gov.uk"}
+      {"group":"test group_2 description","user":"email1@ons.gov.uk"}
+      """
+
+  Scenario: GET /v1/groups-report as an Excel workbook and checking the response status 200
+    Given I am an admin user
+    And group "test-group_1" and description "test group_1 description" exists in the database
+    And a user with username "abcd1234" and email "email1@ons.gov.uk" exists in the database
+    And user "abcd1234" is a member of group "test-group_1"
+    And request header Accept is "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+    When I GET "/v1/groups-report"
+    Then the HTTP status code should be "200"
+    And the response header "Content-Type" should contain "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+    And the response header "Content-Disposition" should contain "attachment; filename=groups-report.xlsx"
@@ -268,8 +268,8 @@ func (c *IdentityComponent) theResponseShouldMatchTheFollowingJSONForListGroups(
 	return nil
 }
 
-func (c *IdentityComponent) requestHeaderAcceptIs() error {
-	err := c.apiFeature.ISetTheHeaderTo("Accept", "text/csv")
+func (c *IdentityComponent) requestHeaderAcceptIs(accept string) error {
+	err := c.apiFeature.ISetTheHeaderTo("Accept", accept)
 	return err
 }
 
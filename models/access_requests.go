@@ -0,0 +1,124 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestRejected = "rejected"
+
+	MaxAccessRequestJustificationLength = 1024
+)
+
+// AccessRequest is a user's request to be added to a group, and the decision on it once made
+type AccessRequest struct {
+	ID            string     `json:"id"`
+	GroupID       string     `json:"group_id"`
+	UserID        string     `json:"user_id"`
+	Justification string     `json:"justification"`
+	Status        string     `json:"status"`
+	Created       time.Time  `json:"created"`
+	DecidedBy     string     `json:"decided_by,omitempty"`
+	Decided       *time.Time `json:"decided,omitempty"`
+	DecisionNote  string     `json:"decision_note,omitempty"`
+}
+
+// AccessRequestsList is a list of a group's access requests
+type AccessRequestsList struct {
+	AccessRequests []AccessRequest `json:"access_requests"`
+	Count          int             `json:"count"`
+}
+
+// CreateAccessRequest is the body of a request for access to a group
+type CreateAccessRequest struct {
+	Justification string `json:"justification"`
+}
+
+// AccessRequestDecision is an owner's or admin's decision on an access request
+type AccessRequestDecision struct {
+	Status    string    `json:"status"`
+	Note      string    `json:"note"`
+	DecidedBy string    `json:"-"`
+	Decided   time.Time `json:"-"`
+}
+
+// Validate validates the justification for an access request, returns validation errors for anything that fails
+func (c *CreateAccessRequest) Validate(ctx context.Context) []error {
+	var validationErrs []error
+	c.Justification = strings.TrimSpace(c.Justification)
+	if c.Justification == "" {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidFieldError, MissingJustificationDescription))
+	} else if len(c.Justification) > MaxAccessRequestJustificationLength {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidFieldError, JustificationTooLongDescription))
+	}
+	return validationErrs
+}
+
+// NewAccessRequest returns a pending access request to the group for the user
+func (c *CreateAccessRequest) NewAccessRequest(id, groupID, userID string, created time.Time) AccessRequest {
+	return AccessRequest{
+		ID:            id,
+		GroupID:       groupID,
+		UserID:        userID,
+		Justification: c.Justification,
+		Status:        AccessRequestPending,
+		Created:       created,
+	}
+}
+
+// Validate validates the decision on an access request, returns validation errors for anything that fails
+func (d *AccessRequestDecision) Validate(ctx context.Context) []error {
+	var validationErrs []error
+	if d.Status != AccessRequestApproved && d.Status != AccessRequestRejected {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidFieldError, InvalidAccessDecisionDescription))
+	}
+	d.Note = strings.TrimSpace(d.Note)
+	if len(d.Note) > MaxAccessRequestJustificationLength {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidFieldError, DecisionNoteTooLongDescription))
+	}
+	return validationErrs
+}
+
+// Decide records the decision on the access request
+func (r *AccessRequest) Decide(decision AccessRequestDecision) {
+	decided := decision.Decided
+	r.Status = decision.Status
+	r.DecidedBy = decision.DecidedBy
+	r.Decided = &decided
+	r.DecisionNote = decision.Note
+}
+
+// Reopen removes the decision on the access request, returning it to pending
+func (r *AccessRequest) Reopen() {
+	r.Status = AccessRequestPending
+	r.DecidedBy = ""
+	r.Decided = nil
+	r.DecisionNote = ""
+}
+
+// BuildSuccessfulJSONResponse builds the AccessRequest response json for client responses
+func (r *AccessRequest) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(r)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// BuildSuccessfulJSONResponse builds the AccessRequestsList response json for client responses
+func (l *AccessRequestsList) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	if l.AccessRequests == nil {
+		l.AccessRequests = []AccessRequest{}
+	}
+	l.Count = len(l.AccessRequests)
+	jsonResponse, err := json.Marshal(l)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateAccessRequest_Validate(t *testing.T) {
+	var ctx = context.Background()
+
+	Convey("A justification is trimmed and required", t, func() {
+		createRequest := models.CreateAccessRequest{Justification: "  I publish the census datasets  "}
+		So(createRequest.Validate(ctx), ShouldBeEmpty)
+		So(createRequest.Justification, ShouldEqual, "I publish the census datasets")
+
+		createRequest = models.CreateAccessRequest{Justification: "  "}
+		errs := createRequest.Validate(ctx)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].(*models.Error).Description, ShouldEqual, models.MissingJustificationDescription)
+	})
+
+	Convey("A justification that is too long is rejected", t, func() {
+		createRequest := models.CreateAccessRequest{Justification: strings.Repeat("a", models.MaxAccessRequestJustificationLength+1)}
+		errs := createRequest.Validate(ctx)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].(*models.Error).Description, ShouldEqual, models.JustificationTooLongDescription)
+	})
+}
+
+func TestAccessRequestDecision_Validate(t *testing.T) {
+	var ctx = context.Background()
+
+	Convey("A decision must approve or reject the request", t, func() {
+		for _, status := range []string{models.AccessRequestApproved, models.AccessRequestRejected} {
+			decision := models.AccessRequestDecision{Status: status}
+			So(decision.Validate(ctx), ShouldBeEmpty)
+		}
+
+		for _, status := range []string{"", models.AccessRequestPending, "cancelled"} {
+			decision := models.AccessRequestDecision{Status: status}
+			errs := decision.Validate(ctx)
+			So(len(errs), ShouldEqual, 1)
+			So(errs[0].(*models.Error).Description, ShouldEqual, models.InvalidAccessDecisionDescription)
+		}
+	})
+
+	Convey("A decision note that is too long is rejected", t, func() {
+		decision := models.AccessRequestDecision{Status: models.AccessRequestApproved, Note: strings.Repeat("a", models.MaxAccessRequestJustificationLength+1)}
+		errs := decision.Validate(ctx)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].(*models.Error).Description, ShouldEqual, models.DecisionNoteTooLongDescription)
+	})
+}
+
+func TestAccessRequest_Decide(t *testing.T) {
+	Convey("Deciding a request records the decision and who made it", t, func() {
+		createRequest := models.CreateAccessRequest{Justification: "I publish the census datasets"}
+		accessRequest := createRequest.NewAccessRequest("request-1", "efgh5678", "abcd1234", time.Now())
+		So(accessRequest.Status, ShouldEqual, models.AccessRequestPending)
+
+		decided := time.Now()
+		accessRequest.Decide(models.AccessRequestDecision{Status: models.AccessRequestApproved, Note: "ok", DecidedBy: "owner-user", Decided: decided})
+		So(accessRequest.Status, ShouldEqual, models.AccessRequestApproved)
+		So(accessRequest.DecidedBy, ShouldEqual, "owner-user")
+		So(accessRequest.DecisionNote, ShouldEqual, "ok")
+		So(accessRequest.Decided.Equal(decided), ShouldBeTrue)
+
+		Convey("And reopening it removes the decision", func() {
+			accessRequest.Reopen()
+			So(accessRequest, ShouldResemble, createRequest.NewAccessRequest("request-1", "efgh5678", "abcd1234", accessRequest.Created))
+		})
+	})
+}
@@ -41,6 +41,16 @@ const (
 	InvalidGroupPrecedence       = "InvalidGroupPrecedence"
 	InvalidFilterQuery           = "InvalidFilterQuery"
 	JWKSParseError               = "JWKSParseError"
+	PreconditionFailedError      = "PreconditionFailed"
+	GroupNotEmptyError           = "GroupNotEmpty"
+	ProtectedGroupError          = "ProtectedGroup"
+	InvalidGroupDescription      = "InvalidGroupDescription"
+	AccessRequestExistsError     = "AccessRequestExists"
+	AccessRequestDecidedError    = "AccessRequestDecided"
+	GroupCycleError              = "GroupCycle"
+	InvalidIdempotencyKeyError   = "InvalidIdempotencyKey"
+	IdempotencyKeyInUseError     = "IdempotencyKeyInUse"
+	IdempotencyKeyMismatchError  = "IdempotencyKeyMismatch"
 )
 
 // API error descriptions
@@ -87,11 +97,60 @@ const (
 	JWKSExponentErrorDescription           = "unexpected exponent: unable to decode JWK"
 	JWKSEmptyWebKeySetDescription          = "empty json web key set"
 	InvalidStatusDescription               = "user was not in a valid state to perform action"
+	EmailChangeUnverifiedDescription       = "the user pool does not keep the original email address active until a new one is verified"
+	PasswordTooShortDescription            = "the password is shorter than the minimum length"
+	PasswordMissingUppercaseDescription    = "the password must contain an uppercase letter"
+	PasswordMissingLowercaseDescription    = "the password must contain a lowercase letter"
+	PasswordMissingNumberDescription       = "the password must contain a number"
+	PasswordMissingSymbolDescription       = "the password must contain a symbol"
+	PasswordBannedWordDescription          = "the password contains a banned word"
+	PasswordPersonalInfoDescription        = "the password must not contain the user's name or email address"
+	PasswordBreachedDescription            = "the password has appeared in a data breach"
+	PasswordPolicyCheckFailedDescription   = "the password could not be checked against the password policy"
+	PasswordReusedDescription              = "the password has been used recently"
+	PasswordHistoryFailedDescription       = "the password history could not be checked"
+	RateLimitExceededDescription           = "too many requests, try again later"
+	ResourceModifiedDescription            = "the resource has been modified since it was read"
+	GroupNotEmptyDescription               = "the group has members, set force=true or transfer_to to delete it"
+	ProtectedGroupDescription              = "role groups cannot be deleted"
+	TransferToSameGroupDescription         = "members cannot be transferred to the group being deleted"
+	TransferToRoleGroupDescription         = "members cannot be transferred to role groups"
+	GroupDescriptionTooLong                = "the group description is too long"
+	InvalidGroupContactEmail               = "the group contact email could not be validated"
+	GroupMetadataFailedDescription         = "the group metadata could not be read or saved"
+	RoleGroupOwnersDescription             = "role groups cannot have owners"
+	NotGroupOwnerDescription               = "the user is not an owner of the group"
+	GroupOwnersFailedDescription           = "the group owners could not be read or saved"
+	InvalidMembershipExpiryDescription     = "expires_at must be an RFC 3339 time in the future"
+	MembershipExpiryFailedDescription      = "the membership expiry could not be read or saved"
+	MissingJustificationDescription        = "a justification for the access request is required"
+	JustificationTooLongDescription        = "the justification is too long"
+	InvalidAccessDecisionDescription       = "the status must be approved or rejected"
+	DecisionNoteTooLongDescription         = "the decision note is too long"
+	AccessRequestNotFoundDescription       = "the access request was not found"
+	AccessRequestExistsDescription         = "the user already has a pending access request for the group"
+	AccessRequestDecidedDescription        = "the access request has already been decided"
+	AccessRequestsFailedDescription        = "the access requests could not be read or saved"
+	InvalidCallerDescription               = "the caller could not be identified from the Authorization token"
+	RoleGroupNestingDescription            = "role groups cannot be nested"
+	ChildGroupNotFoundDescription          = "the child group was not found"
+	NotChildGroupDescription               = "the group is not nested in the parent group"
+	GroupCycleDescription                  = "the parent group is nested in the child group"
+	GroupHierarchyFailedDescription        = "the group hierarchy could not be read or saved"
+	AutoPrecedenceConflictDescription      = "a precedence cannot be given when auto_precedence is true"
+	NoFreePrecedenceDescription            = "every precedence between 10 and 100 is in use"
+	TooManyGroupsToRenumberDescription     = "there are too many groups to give each a precedence between 10 and 100"
+	GroupNameHistoryFailedDescription      = "the group name history could not be read or saved"
+	IdempotencyKeyTooLongDescription       = "the Idempotency-Key header is too long"
+	IdempotencyKeyInUseDescription         = "a request with the Idempotency-Key is still in progress"
+	IdempotencyKeyMismatchDescription      = "the Idempotency-Key has already been used for a different request"
+	InvalidReportColumnDescription         = "the report columns must be from user_id, forename, lastname, active, group_id, precedence and joined"
 )
 
 // CognitoErrorMapping mapping Cognito error codes to API error codes
 var CognitoErrorMapping = map[string]string{
 	"InternalErrorException":          InternalError,
+	"AliasExistsException":            UsernameExistsError,
 	"CodeDeliveryFailureException":    DeliveryFailureError,
 	"CodeMismatchException":           InvalidCodeError,
 	"ConcurrentModificationException": InternalError,
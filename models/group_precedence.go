@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// GroupPrecedenceReport describes how the precedences between 10 and 100 are used by groups other than role groups
+type GroupPrecedenceReport struct {
+	Duplicates []PrecedenceDuplicate `json:"duplicates"`
+	Unused     []int32               `json:"unused"`
+	// OutOfRange lists the groups without a precedence between 10 and 100
+	OutOfRange []string           `json:"out_of_range"`
+	Changes    []PrecedenceChange `json:"changes,omitempty"`
+}
+
+// PrecedenceDuplicate is a precedence shared by more than one group
+type PrecedenceDuplicate struct {
+	Precedence int32    `json:"precedence"`
+	Groups     []string `json:"groups"`
+}
+
+// PrecedenceChange is the renumbering of a group's precedence
+type PrecedenceChange struct {
+	GroupID string `json:"group_id"`
+	From    *int32 `json:"from"`
+	To      int32  `json:"to"`
+}
+
+// NewGroupPrecedenceReport reports the duplicate and unused precedences of the groups, ignoring role groups
+func NewGroupPrecedenceReport(groups []types.GroupType) GroupPrecedenceReport {
+	report := GroupPrecedenceReport{Duplicates: []PrecedenceDuplicate{}, Unused: []int32{}, OutOfRange: []string{}}
+
+	groupsByPrecedence := map[int32][]string{}
+	for _, group := range precedenceGroups(groups) {
+		groupID := aws.ToString(group.GroupName)
+		if !inPrecedenceRange(group.Precedence) {
+			report.OutOfRange = append(report.OutOfRange, groupID)
+			continue
+		}
+		groupsByPrecedence[*group.Precedence] = append(groupsByPrecedence[*group.Precedence], groupID)
+	}
+
+	for precedence := groupPrecedenceMin; precedence <= groupPrecedenceMax; precedence++ {
+		switch groupIDs := groupsByPrecedence[precedence]; {
+		case len(groupIDs) == 0:
+			report.Unused = append(report.Unused, precedence)
+		case len(groupIDs) > 1:
+			sort.Strings(groupIDs)
+			report.Duplicates = append(report.Duplicates, PrecedenceDuplicate{Precedence: precedence, Groups: groupIDs})
+		}
+	}
+	sort.Strings(report.OutOfRange)
+	return report
+}
+
+// NextFreePrecedence returns the lowest precedence between 10 and 100 that no group uses, reporting false if they are
+// all in use
+func NextFreePrecedence(groups []types.GroupType) (int32, bool) {
+	used := map[int32]bool{}
+	for i := range groups {
+		if groups[i].Precedence != nil {
+			used[*groups[i].Precedence] = true
+		}
+	}
+	for precedence := groupPrecedenceMin; precedence <= groupPrecedenceMax; precedence++ {
+		if !used[precedence] {
+			return precedence, true
+		}
+	}
+	return 0, false
+}
+
+// PlanPrecedenceRenumbering gives the groups, other than role groups, consecutive precedences from 10 in their current
+// order, with ties broken by group ID and groups outside the range last. It returns only the groups whose precedence
+// changes, or false if there are too many groups to fit between 10 and 100.
+func PlanPrecedenceRenumbering(groups []types.GroupType) ([]PrecedenceChange, bool) {
+	ordered := precedenceGroups(groups)
+	if len(ordered) > int(groupPrecedenceMax-groupPrecedenceMin+1) {
+		return nil, false
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		iInRange, jInRange := inPrecedenceRange(ordered[i].Precedence), inPrecedenceRange(ordered[j].Precedence)
+		if iInRange != jInRange {
+			return iInRange
+		}
+		if iInRange && *ordered[i].Precedence != *ordered[j].Precedence {
+			return *ordered[i].Precedence < *ordered[j].Precedence
+		}
+		return aws.ToString(ordered[i].GroupName) < aws.ToString(ordered[j].GroupName)
+	})
+
+	changes := []PrecedenceChange{}
+	for i, group := range ordered {
+		precedence := groupPrecedenceMin + int32(i)
+		if group.Precedence == nil || *group.Precedence != precedence {
+			changes = append(changes, PrecedenceChange{GroupID: aws.ToString(group.GroupName), From: group.Precedence, To: precedence})
+		}
+	}
+	return changes, true
+}
+
+// BuildUpdateGroupPrecedenceInput builds the UpdateGroupInput that changes the group's precedence, keeping its
+// description and role
+func BuildUpdateGroupPrecedenceInput(userPoolID string, group types.GroupType, precedence int32) *cognitoidentityprovider.UpdateGroupInput {
+	return &cognitoidentityprovider.UpdateGroupInput{
+		GroupName:   group.GroupName,
+		Description: group.Description,
+		Precedence:  &precedence,
+		RoleArn:     group.RoleArn,
+		UserPoolId:  &userPoolID,
+	}
+}
+
+// BuildSuccessfulJSONResponse builds the GroupPrecedenceReport response json for client responses
+func (r *GroupPrecedenceReport) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(r)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// precedenceGroups returns the groups whose precedence is chosen by users, which excludes the role groups
+func precedenceGroups(groups []types.GroupType) []types.GroupType {
+	var filtered []types.GroupType
+	for i := range groups {
+		group := Group{ID: aws.ToString(groups[i].GroupName)}
+		if !group.IsRoleGroup() {
+			filtered = append(filtered, groups[i])
+		}
+	}
+	return filtered
+}
+
+func inPrecedenceRange(precedence *int32) bool {
+	return precedence != nil && *precedence >= groupPrecedenceMin && *precedence <= groupPrecedenceMax
+}
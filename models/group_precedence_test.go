@@ -0,0 +1,69 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func precedenceTestGroups() []types.GroupType {
+	return []types.GroupType{
+		{GroupName: aws.String(models.AdminRoleGroup), Precedence: aws.Int32(models.AdminRoleGroupPrecedence)},
+		{GroupName: aws.String("group-c"), Precedence: aws.Int32(12)},
+		{GroupName: aws.String("group-b"), Precedence: aws.Int32(10)},
+		{GroupName: aws.String("group-a"), Precedence: aws.Int32(12)},
+		{GroupName: aws.String("group-d")},
+	}
+}
+
+func TestNewGroupPrecedenceReport(t *testing.T) {
+	Convey("The report lists duplicate, unused and out of range precedences, ignoring role groups", t, func() {
+		report := models.NewGroupPrecedenceReport(precedenceTestGroups())
+
+		So(report.Duplicates, ShouldResemble, []models.PrecedenceDuplicate{{Precedence: 12, Groups: []string{"group-a", "group-c"}}})
+		So(report.Unused, ShouldHaveLength, 89)
+		So(report.Unused[0], ShouldEqual, 11)
+		So(report.Unused[1], ShouldEqual, 13)
+		So(report.OutOfRange, ShouldResemble, []string{"group-d"})
+	})
+}
+
+func TestNextFreePrecedence(t *testing.T) {
+	Convey("The lowest unused precedence is allocated", t, func() {
+		precedence, ok := models.NextFreePrecedence(precedenceTestGroups())
+		So(ok, ShouldBeTrue)
+		So(precedence, ShouldEqual, 11)
+	})
+
+	Convey("No precedence is allocated once they are all in use", t, func() {
+		var groups []types.GroupType
+		for precedence := int32(10); precedence <= 100; precedence++ {
+			groups = append(groups, types.GroupType{Precedence: aws.Int32(precedence)})
+		}
+		_, ok := models.NextFreePrecedence(groups)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestPlanPrecedenceRenumbering(t *testing.T) {
+	Convey("Groups are renumbered from 10 in their current order, with out of range groups last", t, func() {
+		changes, ok := models.PlanPrecedenceRenumbering(precedenceTestGroups())
+		So(ok, ShouldBeTrue)
+		So(changes, ShouldResemble, []models.PrecedenceChange{
+			{GroupID: "group-a", From: aws.Int32(12), To: 11},
+			{GroupID: "group-d", To: 13},
+		})
+	})
+
+	Convey("Renumbering is refused when the groups cannot each have their own precedence", t, func() {
+		var groups []types.GroupType
+		for i := 0; i < 92; i++ {
+			groups = append(groups, types.GroupType{GroupName: aws.String("group"), Precedence: aws.Int32(50)})
+		}
+		_, ok := models.PlanPrecedenceRenumbering(groups)
+		So(ok, ShouldBeFalse)
+	})
+}
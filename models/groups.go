@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ONSdigital/dp-identity-api/v2/validation"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
 
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
@@ -27,10 +28,42 @@ var (
 	groupPrecedenceMax    = int32(100)
 )
 
+const MaxGroupDescriptionLength = 1024
+
+// Optional columns of the groups report
+const (
+	ReportColumnUserID     = "user_id"
+	ReportColumnForename   = "forename"
+	ReportColumnLastname   = "lastname"
+	ReportColumnActive     = "active"
+	ReportColumnGroupID    = "group_id"
+	ReportColumnPrecedence = "precedence"
+	ReportColumnJoined     = "joined"
+)
+
+// ReportColumns are the optional columns of the groups report in the order they are output
+var ReportColumns = []string{
+	ReportColumnUserID,
+	ReportColumnForename,
+	ReportColumnLastname,
+	ReportColumnActive,
+	ReportColumnGroupID,
+	ReportColumnPrecedence,
+	ReportColumnJoined,
+}
+
 // ListGroupUsersType list of groups and the membership for user report group-report
+// the optional columns are only set when they are requested, and joined only when it is known
 type ListGroupUsersType struct {
-	GroupName string `type:"string" json:"group"`
-	UserEmail string `type:"string" json:"user"`
+	GroupName  string     `type:"string" json:"group"`
+	UserEmail  string     `type:"string" json:"user"`
+	UserID     *string    `json:"user_id,omitempty"`
+	Forename   *string    `json:"forename,omitempty"`
+	Lastname   *string    `json:"lastname,omitempty"`
+	Active     *bool      `json:"active,omitempty"`
+	GroupID    *string    `json:"group_id,omitempty"`
+	Precedence *int32     `json:"precedence,omitempty"`
+	Joined     *time.Time `json:"joined,omitempty"`
 }
 
 // Group is a type for the identity API representation of a group's details
@@ -39,6 +72,53 @@ type Group struct {
 	Name       string    `json:"name"`
 	Precedence int32     `json:"precedence"`
 	Created    time.Time `json:"created"`
+	GroupMetadata
+}
+
+// GroupMetadata holds the details of a group that Cognito has no fields for. Cognito's group description is used for
+// the group's name, so the description here is kept separately.
+type GroupMetadata struct {
+	Description  string `json:"description,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	ContactEmail string `json:"contact_email,omitempty"`
+}
+
+// GroupMetadataUpdate holds the group metadata fields in a request, nil fields are left unchanged
+type GroupMetadataUpdate struct {
+	Description  *string `json:"description,omitempty"`
+	Owner        *string `json:"owner,omitempty"`
+	ContactEmail *string `json:"contact_email,omitempty"`
+}
+
+// IsSet reports whether any metadata fields are being changed
+func (u *GroupMetadataUpdate) IsSet() bool {
+	return u.Description != nil || u.Owner != nil || u.ContactEmail != nil
+}
+
+// Apply returns the metadata with the fields being changed replaced
+func (u *GroupMetadataUpdate) Apply(metadata GroupMetadata) GroupMetadata {
+	if u.Description != nil {
+		metadata.Description = strings.TrimSpace(*u.Description)
+	}
+	if u.Owner != nil {
+		metadata.Owner = strings.TrimSpace(*u.Owner)
+	}
+	if u.ContactEmail != nil {
+		metadata.ContactEmail = strings.TrimSpace(*u.ContactEmail)
+	}
+	return metadata
+}
+
+// Validate validates the metadata fields being changed, returns validation errors for anything that fails
+func (u *GroupMetadataUpdate) Validate(ctx context.Context) []error {
+	var validationErrs []error
+	if u.Description != nil && len(*u.Description) > MaxGroupDescriptionLength {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidGroupDescription, GroupDescriptionTooLong))
+	}
+	if u.ContactEmail != nil && *u.ContactEmail != "" && !validation.IsEmailValid(strings.TrimSpace(*u.ContactEmail)) {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidEmailError, InvalidGroupContactEmail))
+	}
+	return validationErrs
 }
 
 // NewAdminRoleGroup is a constructor for a new instance of the admin role group
@@ -59,6 +139,11 @@ func NewPublisherRoleGroup() Group {
 	}
 }
 
+// IsRoleGroup reports whether the group is one of the role groups the API creates on start up
+func (g *Group) IsRoleGroup() bool {
+	return g.ID == AdminRoleGroup || g.ID == PublisherRoleGroup
+}
+
 // ValidateAddRemoveUser validates the required fields for adding a user to a group, returns validation errors for anything that fails
 func (g *Group) ValidateAddRemoveUser(ctx context.Context, userID string) []error {
 	var validationErrs []error
@@ -161,6 +246,7 @@ type CreateUpdateGroup struct {
 	Precedence *int32  `json:"precedence"`
 	ID         *string `json:"id"`
 	GroupsList *cognitoidentityprovider.ListGroupsOutput
+	GroupMetadataUpdate
 }
 
 // ValidateCreateUpdateGroupRequest validate the create group request
@@ -189,6 +275,8 @@ func (g *CreateUpdateGroup) ValidateCreateUpdateGroupRequest(ctx context.Context
 		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidGroupPrecedence, GroupPrecedenceIncorrect))
 	}
 
+	validationErrs = append(validationErrs, g.GroupMetadataUpdate.Validate(ctx)...)
+
 	return validationErrs
 }
 
@@ -225,13 +313,21 @@ func (g *CreateUpdateGroup) NewSuccessResponse(jsonBody []byte, statusCode int,
 	var cg = CreateUpdateGroup{}
 	_ = json.Unmarshal(jsonBody, &cg)
 
-	jsonResponse, _ := json.Marshal(
-		map[string]interface{}{
-			"name":       cg.Name,
-			"precedence": cg.Precedence,
-			"id":         cg.ID,
-		},
-	)
+	response := map[string]interface{}{
+		"name":       cg.Name,
+		"precedence": cg.Precedence,
+		"id":         cg.ID,
+	}
+	if cg.Description != nil {
+		response["description"] = cg.Description
+	}
+	if cg.Owner != nil {
+		response["owner"] = cg.Owner
+	}
+	if cg.ContactEmail != nil {
+		response["contact_email"] = cg.ContactEmail
+	}
+	jsonResponse, _ := json.Marshal(response)
 
 	return &SuccessResponse{
 		Body:    jsonResponse,
@@ -241,8 +337,9 @@ func (g *CreateUpdateGroup) NewSuccessResponse(jsonBody []byte, statusCode int,
 }
 
 // BuildListGroupsSuccessfulJSONResponse
-// formats the output to comply with current standards and to json , adds the count of groups returned and
-func (p *ListUserGroups) BuildListGroupsSuccessfulJSONResponse(ctx context.Context, result *cognitoidentityprovider.ListGroupsOutput) ([]byte, error) {
+// formats the output to comply with current standards and to json , adds the count of groups returned,
+// the metadata of each group and the former name each group was found by, either of which may be nil
+func (p *ListUserGroups) BuildListGroupsSuccessfulJSONResponse(ctx context.Context, result *cognitoidentityprovider.ListGroupsOutput, metadata map[string]GroupMetadata, renamedFrom map[string]string) ([]byte, error) {
 	if result == nil {
 		return nil, NewValidationError(ctx, InternalError, UnrecognisedCognitoResponseDescription)
 	}
@@ -257,6 +354,10 @@ func (p *ListUserGroups) BuildListGroupsSuccessfulJSONResponse(ctx context.Conte
 			RoleArn:          tmpGroup.RoleArn,
 			UserPoolID:       tmpGroup.UserPoolId,
 		}
+		if tmpGroup.GroupName != nil {
+			newGroup.GroupMetadata = metadata[*tmpGroup.GroupName]
+			newGroup.RenamedFrom = renamedFrom[*tmpGroup.GroupName]
+		}
 
 		p.Groups = append(p.Groups, &newGroup)
 	}
@@ -295,3 +396,161 @@ func CleanString(description string) string {
 		),
 	)
 }
+
+const (
+	MembershipChangePending   = "pending"
+	MembershipChangeSucceeded = "succeeded"
+	MembershipChangeFailed    = "failed"
+)
+
+// GroupMembershipChange is a user being added to or removed from a group when its membership is set, and the outcome
+type GroupMembershipChange struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// GroupMembershipDiff is the difference between a group's current membership and the requested membership. The
+// embedded UsersList holds the resulting members once the changes have been made, and is left out of dry runs.
+type GroupMembershipDiff struct {
+	*UsersList
+	DryRun    bool                    `json:"dry_run"`
+	ToAdd     []GroupMembershipChange `json:"to_add"`
+	ToRemove  []GroupMembershipChange `json:"to_remove"`
+	Unchanged []string                `json:"unchanged"`
+}
+
+// NewGroupMembershipDiff works out which users need adding to and removing from a group to go from the current
+// members to the requested members. Every change starts as pending.
+func NewGroupMembershipDiff(currentUserIDs, requestedUserIDs []string) *GroupMembershipDiff {
+	diff := &GroupMembershipDiff{
+		ToAdd:     []GroupMembershipChange{},
+		ToRemove:  []GroupMembershipChange{},
+		Unchanged: []string{},
+	}
+
+	current := make(map[string]bool, len(currentUserIDs))
+	for _, userID := range currentUserIDs {
+		current[userID] = true
+	}
+
+	requested := make(map[string]bool, len(requestedUserIDs))
+	for _, userID := range requestedUserIDs {
+		if requested[userID] {
+			continue
+		}
+		requested[userID] = true
+		if current[userID] {
+			diff.Unchanged = append(diff.Unchanged, userID)
+		} else {
+			diff.ToAdd = append(diff.ToAdd, GroupMembershipChange{UserID: userID, Status: MembershipChangePending})
+		}
+	}
+
+	for _, userID := range currentUserIDs {
+		if !requested[userID] {
+			diff.ToRemove = append(diff.ToRemove, GroupMembershipChange{UserID: userID, Status: MembershipChangePending})
+		}
+	}
+
+	return diff
+}
+
+// BuildSuccessfulJSONResponse builds the GroupMembershipDiff response json for client responses
+func (d *GroupMembershipDiff) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(d)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// GroupOwners is the list of users who can manage a group's membership without the groups edit permission
+type GroupOwners struct {
+	Owners []string `json:"owners"`
+	Count  int      `json:"count"`
+}
+
+// NewGroupOwners returns the GroupOwners for the owners' user IDs
+func NewGroupOwners(userIDs []string) GroupOwners {
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+	return GroupOwners{Owners: userIDs, Count: len(userIDs)}
+}
+
+// BuildSuccessfulJSONResponse builds the GroupOwners response json for client responses
+func (o *GroupOwners) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(o)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// GroupRename is a former name of a group and when it stopped being used
+type GroupRename struct {
+	GroupID string    `json:"-"`
+	Name    string    `json:"name"`
+	Renamed time.Time `json:"renamed"`
+}
+
+// GroupRenameHistory is the list of a group's former names, oldest first
+type GroupRenameHistory struct {
+	Renames []GroupRename `json:"renames"`
+	Count   int           `json:"count"`
+}
+
+// NewGroupRenameHistory returns the GroupRenameHistory for the group's renames
+func NewGroupRenameHistory(renames []GroupRename) GroupRenameHistory {
+	if renames == nil {
+		renames = []GroupRename{}
+	}
+	return GroupRenameHistory{Renames: renames, Count: len(renames)}
+}
+
+// BuildSuccessfulJSONResponse builds the GroupRenameHistory response json for client responses
+func (h *GroupRenameHistory) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(h)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// ChildGroups is the list of groups nested directly in a group
+type ChildGroups struct {
+	Groups []string `json:"groups"`
+	Count  int      `json:"count"`
+}
+
+// NewChildGroups returns the ChildGroups for the nested groups' IDs
+func NewChildGroups(groupIDs []string) ChildGroups {
+	if groupIDs == nil {
+		groupIDs = []string{}
+	}
+	return ChildGroups{Groups: groupIDs, Count: len(groupIDs)}
+}
+
+// BuildSuccessfulJSONResponse builds the ChildGroups response json for client responses
+func (c *ChildGroups) BuildSuccessfulJSONResponse(ctx context.Context) ([]byte, error) {
+	jsonResponse, err := json.Marshal(c)
+	if err != nil {
+		return nil, NewError(ctx, err, JSONMarshalError, ErrorMarshalFailedDescription)
+	}
+	return jsonResponse, nil
+}
+
+// ParseMembershipExpiry parses the optional expiry time of a group membership, which must be an RFC 3339 time after
+// now. It returns nil for a permanent membership.
+func ParseMembershipExpiry(ctx context.Context, expiresAt string, now time.Time) (*time.Time, error) {
+	if expiresAt == "" {
+		return nil, nil
+	}
+	expiry, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil || !expiry.After(now) {
+		return nil, NewValidationError(ctx, InvalidFieldError, InvalidMembershipExpiryDescription)
+	}
+	expiry = expiry.UTC()
+	return &expiry, nil
+}
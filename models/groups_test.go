@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +38,18 @@ func TestNewPublisherRoleGroup(t *testing.T) {
 	})
 }
 
+func TestGroup_IsRoleGroup(t *testing.T) {
+	Convey("the admin and publisher role groups are role groups", t, func() {
+		adminGroup := models.NewAdminRoleGroup()
+		publisherGroup := models.NewPublisherRoleGroup()
+		teamGroup := models.Group{ID: "123e4567-e89b-12d3-a456-426614174000"}
+
+		So(adminGroup.IsRoleGroup(), ShouldBeTrue)
+		So(publisherGroup.IsRoleGroup(), ShouldBeTrue)
+		So(teamGroup.IsRoleGroup(), ShouldBeFalse)
+	})
+}
+
 func TestGroup_ValidateAddUser(t *testing.T) {
 	var ctx = context.Background()
 
@@ -282,7 +295,7 @@ func TestGroup_BuildListGroupsSuccessfulJsonResponse(t *testing.T) {
 			NextToken: new(string),
 		}
 
-		response, err := group.BuildListGroupsSuccessfulJSONResponse(ctx, results)
+		response, err := group.BuildListGroupsSuccessfulJSONResponse(ctx, results, nil, nil)
 
 		So(err, ShouldBeNil)
 		So(reflect.TypeOf(response), ShouldEqual, reflect.TypeOf([]byte{}))
@@ -306,7 +319,7 @@ func TestGroup_BuildListGroupsSuccessfulJsonResponse(t *testing.T) {
 		ctx := context.Background()
 		group := models.ListUserGroups{}
 		var results *cognitoidentityprovider.ListGroupsOutput
-		response, err := group.BuildListGroupsSuccessfulJSONResponse(ctx, results)
+		response, err := group.BuildListGroupsSuccessfulJSONResponse(ctx, results, nil, nil)
 		So(response, ShouldBeNil)
 		So(err, ShouldNotBeNil)
 	},
@@ -550,3 +563,97 @@ func TestGroup_CreateUpdateGroupNewSuccessResponse(t *testing.T) {
 		So(int64(CreateUpdateGroupResponse["precedence"].(float64)), ShouldEqual, precedence)
 	})
 }
+
+func TestGroupMetadataUpdate(t *testing.T) {
+	ctx := context.Background()
+	current := models.GroupMetadata{Description: "Publishing team", Owner: "Digital Publishing", ContactEmail: "publishing@ons.gov.uk"}
+
+	Convey("Only the metadata fields in the update are changed", t, func() {
+		owner := "  Data Dissemination  "
+		update := models.GroupMetadataUpdate{Owner: &owner}
+
+		So(update.IsSet(), ShouldBeTrue)
+		So(update.Apply(current), ShouldResemble, models.GroupMetadata{
+			Description:  "Publishing team",
+			Owner:        "Data Dissemination",
+			ContactEmail: "publishing@ons.gov.uk",
+		})
+	})
+
+	Convey("An update without metadata fields is not set", t, func() {
+		update := models.GroupMetadataUpdate{}
+		So(update.IsSet(), ShouldBeFalse)
+		So(update.Apply(current), ShouldResemble, current)
+	})
+
+	Convey("Validation", t, func() {
+		Convey("An empty contact email clears the field and is valid", func() {
+			contactEmail := ""
+			update := models.GroupMetadataUpdate{ContactEmail: &contactEmail}
+			So(update.Validate(ctx), ShouldBeEmpty)
+		})
+
+		Convey("A description that is too long or an invalid contact email is rejected", func() {
+			description := strings.Repeat("a", models.MaxGroupDescriptionLength+1)
+			contactEmail := "not-an-email"
+			update := models.GroupMetadataUpdate{Description: &description, ContactEmail: &contactEmail}
+
+			validationErrs := update.Validate(ctx)
+			So(len(validationErrs), ShouldEqual, 2)
+			So(validationErrs[0].(*models.Error).Code, ShouldEqual, models.InvalidGroupDescription)
+			So(validationErrs[0].(*models.Error).Description, ShouldEqual, models.GroupDescriptionTooLong)
+			So(validationErrs[1].(*models.Error).Code, ShouldEqual, models.InvalidEmailError)
+			So(validationErrs[1].(*models.Error).Description, ShouldEqual, models.InvalidGroupContactEmail)
+		})
+	})
+}
+
+func TestNewGroupMembershipDiff(t *testing.T) {
+	Convey("the users to add, remove and leave unchanged are worked out from the current and requested members", t, func() {
+		diff := models.NewGroupMembershipDiff([]string{"user-1", "user-2", "user-3"}, []string{"user-2", "user-4", "user-4"})
+
+		So(diff.ToAdd, ShouldResemble, []models.GroupMembershipChange{{UserID: "user-4", Status: models.MembershipChangePending}})
+		So(diff.ToRemove, ShouldResemble, []models.GroupMembershipChange{
+			{UserID: "user-1", Status: models.MembershipChangePending},
+			{UserID: "user-3", Status: models.MembershipChangePending},
+		})
+		So(diff.Unchanged, ShouldResemble, []string{"user-2"})
+	})
+
+	Convey("an empty diff is returned as empty lists without a users list", t, func() {
+		ctx := context.Background()
+		diff := models.NewGroupMembershipDiff(nil, nil)
+		diff.DryRun = true
+
+		response, err := diff.BuildSuccessfulJSONResponse(ctx)
+		So(err, ShouldBeNil)
+		So(string(response), ShouldEqual, `{"dry_run":true,"to_add":[],"to_remove":[],"unchanged":[]}`)
+	})
+}
+
+func TestParseMembershipExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	Convey("A membership without an expiry time is permanent", t, func() {
+		expiresAt, err := models.ParseMembershipExpiry(ctx, "", now)
+		So(err, ShouldBeNil)
+		So(expiresAt, ShouldBeNil)
+	})
+
+	Convey("An expiry time in the future is returned in UTC", t, func() {
+		expiresAt, err := models.ParseMembershipExpiry(ctx, "2026-10-20T09:00:00+01:00", now)
+		So(err, ShouldBeNil)
+		So(*expiresAt, ShouldEqual, time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC))
+	})
+
+	Convey("An expiry time that is invalid or not in the future is rejected", t, func() {
+		for _, value := range []string{"tomorrow", "2026-10-20", "2026-10-19T12:00:00Z", "2026-10-18T12:00:00Z"} {
+			expiresAt, err := models.ParseMembershipExpiry(ctx, value, now)
+			So(expiresAt, ShouldBeNil)
+			castErr := err.(*models.Error)
+			So(castErr.Code, ShouldEqual, models.InvalidFieldError)
+			So(castErr.Description, ShouldEqual, models.InvalidMembershipExpiryDescription)
+		}
+	})
+}
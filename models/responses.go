@@ -1,5 +1,7 @@
 package models
 
+import "io"
+
 type ErrorResponse struct {
 	Errors  []error           `json:"errors"`
 	Status  int               `json:"-"`
@@ -18,6 +20,8 @@ type SuccessResponse struct {
 	Body    []byte            `json:"-"`
 	Status  int               `json:"-"`
 	Headers map[string]string `json:"-"`
+	// Stream, when set, writes the body instead of Body, so that a large response is not held in memory
+	Stream func(w io.Writer) error `json:"-"`
 }
 
 func NewSuccessResponse(jsonBody []byte, statusCode int, headers map[string]string) *SuccessResponse {
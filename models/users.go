@@ -11,6 +11,7 @@ import (
 	"github.com/ONSdigital/dp-identity-api/v2/config"
 	"github.com/ONSdigital/dp-identity-api/v2/utilities"
 	"github.com/ONSdigital/dp-identity-api/v2/validation"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	"github.com/sethvargo/go-password/password"
 )
@@ -38,6 +39,11 @@ type ListUserGroupType struct {
 	Precedence       *int32     `type:"integer" json:"precedence"`
 	RoleArn          *string    `min:"20" type:"string" json:"role_arn"`
 	UserPoolID       *string    `min:"1" type:"string" json:"user_pool_id"`
+	Temporary        bool       `json:"temporary,omitempty"`
+	ExpiresAt        *time.Time `type:"timestamp" json:"expires_at,omitempty"`
+	Inherited        bool       `json:"inherited,omitempty"`
+	RenamedFrom      string     `json:"renamed_from,omitempty"`
+	GroupMetadata
 }
 
 // ListUserGroups list of groups for user output structure from cognitoidentityprovider.AdminListGroupsForUserOutput
@@ -106,6 +112,7 @@ type UserParams struct {
 	Active      bool                 `json:"active"`
 	ID          string               `json:"id"`
 	StatusNotes string               `json:"status_notes"`
+	ExpiresAt   *time.Time           `json:"expires_at,omitempty"`
 }
 
 // GeneratePassword creates a password for the user and assigns it to the struct
@@ -244,6 +251,41 @@ func (p UserParams) BuildUpdateUserRequest(userPoolID string) *cognitoidentitypr
 	}
 }
 
+// ValidateEmailChange validates a new email address for the user against the same rules applied to registration, returning validation errors for any failures
+func (p UserParams) ValidateEmailChange(ctx context.Context, allowedDomains []string, blockPlusAddressing bool) []error {
+	var validationErrs []error
+	if p.ID == "" {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidUserIDError, MissingUserIDErrorDescription))
+	}
+
+	emailErr := validateEmail(ctx, p.Email, allowedDomains, blockPlusAddressing)
+	if emailErr != nil {
+		validationErrs = append(validationErrs, emailErr)
+	}
+
+	return validationErrs
+}
+
+// BuildUpdateEmailRequest generates a AdminUpdateUserAttributesInput for Cognito changing only the users email address
+// email_verified is deliberately not set so that Cognito sends a verification code to the new address and, where the
+// user pool requires verification before update, keeps the old address active until the code is confirmed
+func (p UserParams) BuildUpdateEmailRequest(userPoolID string) *cognitoidentityprovider.AdminUpdateUserAttributesInput {
+	var (
+		emailAttrName = "email"
+	)
+
+	return &cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserAttributes: []types.AttributeType{
+			{
+				Name:  &emailAttrName,
+				Value: &p.Email,
+			},
+		},
+		UserPoolId: &userPoolID,
+		Username:   &p.ID,
+	}
+}
+
 // BuildEnableUserRequest generates a AdminEnableUserInput for Cognito
 func (p UserParams) BuildEnableUserRequest(userPoolID string) *cognitoidentityprovider.AdminEnableUserInput {
 	return &cognitoidentityprovider.AdminEnableUserInput{
@@ -289,6 +331,12 @@ func (p UserParams) ValidateSetPasswordRequest(ctx context.Context) []error {
 	return validationErrs
 }
 
+// ValidatePasswordPolicy checks the user's password against the password policy, returning a validation error for
+// each rule it breaks
+func (p UserParams) ValidatePasswordPolicy(ctx context.Context, policy *validation.PasswordPolicy) ([]error, error) {
+	return validatePasswordPolicy(ctx, policy, p.Password, p.Forename, p.Lastname, p.Email)
+}
+
 // BuildSetPasswordRequest generates a AdminSetUserPasswordInput for Cognito
 func (p UserParams) BuildSetPasswordRequest(userPoolID string) *cognitoidentityprovider.AdminSetUserPasswordInput {
 	return &cognitoidentityprovider.AdminSetUserPasswordInput{
@@ -518,6 +566,83 @@ func (p ChangePassword) BuildConfirmForgotPasswordRequest(clientSecret, clientID
 	}
 }
 
+// ValidatePasswordPolicy checks the new password against the password policy, returning a validation error for each
+// rule it breaks. An empty password is left to ValidateNewPasswordRequiredRequest and ValidateForgottenPasswordRequest.
+// The request only identifies the user, so their name and email address are checked by ValidatePersonalInfo once the
+// user has been looked up.
+func (p ChangePassword) ValidatePasswordPolicy(ctx context.Context, policy *validation.PasswordPolicy) ([]error, error) {
+	return validatePasswordPolicy(ctx, policy, p.NewPassword)
+}
+
+// ValidatePersonalInfo checks the new password does not contain the user's name or email address
+func (p ChangePassword) ValidatePersonalInfo(ctx context.Context, policy *validation.PasswordPolicy, user UserParams) []error {
+	if policy == nil || p.NewPassword == "" {
+		return nil
+	}
+
+	var validationErrs []error
+	for _, violation := range policy.CheckPersonalInfo(p.NewPassword, user.Forename, user.Lastname, user.Email) {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidPasswordError, passwordViolationDescriptions[violation]))
+	}
+	return validationErrs
+}
+
+// passwordViolationDescriptions maps password policy rules to the descriptions returned to clients
+var passwordViolationDescriptions = map[validation.PasswordViolation]string{
+	validation.PasswordTooShort:         PasswordTooShortDescription,
+	validation.PasswordMissingUppercase: PasswordMissingUppercaseDescription,
+	validation.PasswordMissingLowercase: PasswordMissingLowercaseDescription,
+	validation.PasswordMissingNumber:    PasswordMissingNumberDescription,
+	validation.PasswordMissingSymbol:    PasswordMissingSymbolDescription,
+	validation.PasswordBannedWord:       PasswordBannedWordDescription,
+	validation.PasswordPersonalInfo:     PasswordPersonalInfoDescription,
+	validation.PasswordBreached:         PasswordBreachedDescription,
+}
+
+func validatePasswordPolicy(ctx context.Context, policy *validation.PasswordPolicy, password string, personalInfo ...string) ([]error, error) {
+	if policy == nil || password == "" {
+		return nil, nil
+	}
+
+	violations, err := policy.Check(password, personalInfo...)
+	if err != nil {
+		return nil, NewError(ctx, err, InternalError, PasswordPolicyCheckFailedDescription)
+	}
+
+	var validationErrs []error
+	for _, violation := range violations {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidPasswordError, passwordViolationDescriptions[violation]))
+	}
+	return validationErrs, nil
+}
+
+// EmailVerification is a request from a signed in user to confirm a pending change to their email address
+type EmailVerification struct {
+	VerificationToken string `json:"verification_token"`
+}
+
+// Validate validates the required fields have been submitted
+func (p EmailVerification) Validate(ctx context.Context) []error {
+	var validationErrs []error
+	if p.VerificationToken == "" {
+		validationErrs = append(validationErrs, NewValidationError(ctx, InvalidTokenError, InvalidTokenDescription))
+	}
+	return validationErrs
+}
+
+// BuildVerifyEmailRequest generates a VerifyUserAttributeInput for Cognito
+func (p EmailVerification) BuildVerifyEmailRequest(accessToken string) *cognitoidentityprovider.VerifyUserAttributeInput {
+	var (
+		emailAttrName = "email"
+	)
+
+	return &cognitoidentityprovider.VerifyUserAttributeInput{
+		AccessToken:   &accessToken,
+		AttributeName: &emailAttrName,
+		Code:          &p.VerificationToken,
+	}
+}
+
 type PasswordReset struct {
 	Email string `json:"email"`
 }
@@ -555,7 +680,9 @@ func (p UserParams) BuildListUserGroupsRequest(userPoolID, nextToken string) *co
 
 // BuildListUserGroupsSuccessfulJSONResponse
 // formats the output to comply with current standards and to json , adds the count of groups returned and
-func (p *ListUserGroups) BuildListUserGroupsSuccessfulJSONResponse(ctx context.Context, result *cognitoidentityprovider.AdminListGroupsForUserOutput) ([]byte, error) {
+// marks the memberships with an expiry time, keyed by group ID, as temporary and the groups in inherited, which the
+// user is only a member of through a nested group, as inherited
+func (p *ListUserGroups) BuildListUserGroupsSuccessfulJSONResponse(ctx context.Context, result *cognitoidentityprovider.AdminListGroupsForUserOutput, expiries map[string]time.Time, inherited map[string]bool) ([]byte, error) {
 	if result == nil {
 		return nil, NewValidationError(ctx, InternalError, UnrecognisedCognitoResponseDescription)
 	}
@@ -570,6 +697,11 @@ func (p *ListUserGroups) BuildListUserGroupsSuccessfulJSONResponse(ctx context.C
 			RoleArn:          tmpGroup.RoleArn,
 			UserPoolID:       tmpGroup.UserPoolId,
 		}
+		if expiresAt, ok := expiries[aws.ToString(tmpGroup.GroupName)]; ok {
+			newGroup.Temporary = true
+			newGroup.ExpiresAt = &expiresAt
+		}
+		newGroup.Inherited = inherited[aws.ToString(tmpGroup.GroupName)]
 
 		p.Groups = append(p.Groups, &newGroup)
 	}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ONSdigital/dp-identity-api/v2/api"
 	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/ONSdigital/dp-identity-api/v2/validation"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	. "github.com/smartystreets/goconvey/convey"
@@ -455,6 +456,78 @@ func TestUserParams_BuildUpdateUserRequest(t *testing.T) {
 	})
 }
 
+func TestUserParams_ValidateEmailChange(t *testing.T) {
+	ctx := context.Background()
+	allowedDomains := []string{"@ons.gov.uk", "@ext.ons.gov.uk"}
+
+	Convey("returns an InvalidEmail error if a non ONS email is submitted", t, func() {
+		user := models.UserParams{
+			ID:    userID,
+			Email: "email@gmail.com",
+		}
+
+		errs := user.ValidateEmailChange(ctx, allowedDomains, true)
+
+		So(len(errs), ShouldEqual, 1)
+		castErr := errs[0].(*models.Error)
+		So(castErr.Code, ShouldEqual, models.InvalidEmailError)
+		So(castErr.Description, ShouldEqual, models.InvalidEmailDescription)
+	})
+
+	Convey("returns an InvalidEmail error if the email contains a '+' sign and blockPlusAddressing is true", t, func() {
+		user := models.UserParams{
+			ID:    userID,
+			Email: "email+01@ons.gov.uk",
+		}
+
+		errs := user.ValidateEmailChange(ctx, allowedDomains, true)
+
+		So(len(errs), ShouldEqual, 1)
+		castErr := errs[0].(*models.Error)
+		So(castErr.Code, ShouldEqual, models.InvalidEmailError)
+	})
+
+	Convey("returns an InvalidUserId error if the user id is missing", t, func() {
+		user := models.UserParams{
+			Email: "email@ons.gov.uk",
+		}
+
+		errs := user.ValidateEmailChange(ctx, allowedDomains, true)
+
+		So(len(errs), ShouldEqual, 1)
+		castErr := errs[0].(*models.Error)
+		So(castErr.Code, ShouldEqual, models.InvalidUserIDError)
+	})
+
+	Convey("returns no errors for a valid ONS email", t, func() {
+		user := models.UserParams{
+			ID:    userID,
+			Email: "email+01@ons.gov.uk",
+		}
+
+		errs := user.ValidateEmailChange(ctx, allowedDomains, false)
+
+		So(len(errs), ShouldEqual, 0)
+	})
+}
+
+func TestUserParams_BuildUpdateEmailRequest(t *testing.T) {
+	Convey("builds a correctly populated Cognito AdminUpdateUserAttributeInput request body without verifying the email", t, func() {
+		user := models.UserParams{
+			ID:    "abcd1234",
+			Email: "new.email@ons.gov.uk",
+		}
+
+		response := user.BuildUpdateEmailRequest(userPoolID)
+
+		So(*response.Username, ShouldEqual, user.ID)
+		So(*response.UserPoolId, ShouldEqual, userPoolID)
+		So(len(response.UserAttributes), ShouldEqual, 1)
+		So(*response.UserAttributes[0].Name, ShouldEqual, "email")
+		So(*response.UserAttributes[0].Value, ShouldEqual, user.Email)
+	})
+}
+
 func TestUserParams_BuildSuccessfulJsonResponse(t *testing.T) {
 	Convey("returns a byte array of the response JSON", t, func() {
 		ctx := context.Background()
@@ -824,6 +897,86 @@ func TestChangePassword_ValidateNewPasswordRequiredRequest(t *testing.T) {
 	})
 }
 
+func TestChangePassword_ValidatePasswordPolicy(t *testing.T) {
+	var ctx = context.Background()
+	policy := &validation.PasswordPolicy{MinLength: 10, RequireNumber: true}
+
+	Convey("returns a validation error for each password policy rule broken", t, func() {
+		passwordChangeParams := models.ChangePassword{
+			Email:       "jane.smith@ons.gov.uk",
+			NewPassword: "janeabc",
+		}
+
+		validationErrs, err := passwordChangeParams.ValidatePasswordPolicy(ctx, policy)
+
+		So(err, ShouldBeNil)
+		So(len(validationErrs), ShouldEqual, 2)
+		expectedDescriptions := []string{models.PasswordTooShortDescription, models.PasswordMissingNumberDescription}
+		for i, expectedDescription := range expectedDescriptions {
+			castErr := validationErrs[i].(*models.Error)
+			So(castErr.Code, ShouldEqual, models.InvalidPasswordError)
+			So(castErr.Description, ShouldEqual, expectedDescription)
+		}
+	})
+
+	Convey("returns no errors for an empty password, a compliant password or no policy", t, func() {
+		for _, tt := range []struct {
+			password string
+			policy   *validation.PasswordPolicy
+		}{
+			{"", policy},
+			{"correct-horse-2024", policy},
+			{"a", nil},
+		} {
+			passwordChangeParams := models.ChangePassword{Email: "jane.smith@ons.gov.uk", NewPassword: tt.password}
+
+			validationErrs, err := passwordChangeParams.ValidatePasswordPolicy(ctx, tt.policy)
+
+			So(err, ShouldBeNil)
+			So(validationErrs, ShouldBeEmpty)
+		}
+	})
+}
+
+func TestChangePassword_ValidatePersonalInfo(t *testing.T) {
+	var ctx = context.Background()
+	user := models.UserParams{ID: "abcd1234", Forename: "Jane", Lastname: "Smith", Email: "js@ons.gov.uk"}
+
+	Convey("rejects a password containing the name of the user it is for", t, func() {
+		passwordChangeParams := models.ChangePassword{Email: "abcd1234", NewPassword: "Smith!2024abc"}
+
+		validationErrs := passwordChangeParams.ValidatePersonalInfo(ctx, validation.DefaultPasswordPolicy(), user)
+
+		So(len(validationErrs), ShouldEqual, 1)
+		castErr := validationErrs[0].(*models.Error)
+		So(castErr.Code, ShouldEqual, models.InvalidPasswordError)
+		So(castErr.Description, ShouldEqual, models.PasswordPersonalInfoDescription)
+	})
+
+	Convey("returns no errors for a password without the user's details or no policy", t, func() {
+		passwordChangeParams := models.ChangePassword{Email: "abcd1234", NewPassword: "correct-horse-2024"}
+		So(passwordChangeParams.ValidatePersonalInfo(ctx, validation.DefaultPasswordPolicy(), user), ShouldBeEmpty)
+
+		passwordChangeParams.NewPassword = "Smith!2024abc"
+		So(passwordChangeParams.ValidatePersonalInfo(ctx, nil, user), ShouldBeEmpty)
+	})
+}
+
+func TestUserParams_ValidatePasswordPolicy(t *testing.T) {
+	var ctx = context.Background()
+
+	Convey("rejects a password containing the user's name", t, func() {
+		user := models.UserParams{Forename: "Jane", Lastname: "Smith", Email: "js@ons.gov.uk", Password: "Smith!2024abc"}
+
+		validationErrs, err := user.ValidatePasswordPolicy(ctx, validation.DefaultPasswordPolicy())
+
+		So(err, ShouldBeNil)
+		So(len(validationErrs), ShouldEqual, 1)
+		castErr := validationErrs[0].(*models.Error)
+		So(castErr.Description, ShouldEqual, models.PasswordPersonalInfoDescription)
+	})
+}
+
 func TestChangePassword_BuildAuthChallengeResponseRequest(t *testing.T) {
 	Convey("builds a correctly populated Cognito RespondToAuthChallengeInput request body", t, func() {
 		passwordChangeParams := models.ChangePassword{
@@ -978,6 +1131,40 @@ func TestForgottenPassword_BuildConfirmForgotPasswordRequest(t *testing.T) {
 	})
 }
 
+func TestEmailVerification_Validate(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("returns an InvalidToken error if the verification token is missing", t, func() {
+		emailVerification := models.EmailVerification{}
+
+		errs := emailVerification.Validate(ctx)
+
+		So(len(errs), ShouldEqual, 1)
+		castErr := errs[0].(*models.Error)
+		So(castErr.Code, ShouldEqual, models.InvalidTokenError)
+	})
+
+	Convey("returns no errors if the verification token is present", t, func() {
+		emailVerification := models.EmailVerification{VerificationToken: "123456"}
+
+		errs := emailVerification.Validate(ctx)
+
+		So(errs, ShouldBeEmpty)
+	})
+}
+
+func TestEmailVerification_BuildVerifyEmailRequest(t *testing.T) {
+	Convey("builds a correctly populated Cognito VerifyUserAttributeInput request body", t, func() {
+		emailVerification := models.EmailVerification{VerificationToken: "123456"}
+
+		response := emailVerification.BuildVerifyEmailRequest("access-token")
+
+		So(*response.AccessToken, ShouldEqual, "access-token")
+		So(*response.AttributeName, ShouldEqual, "email")
+		So(*response.Code, ShouldEqual, emailVerification.VerificationToken)
+	})
+}
+
 func TestPasswordReset_Validate(t *testing.T) {
 	ctx := context.Background()
 
@@ -1085,7 +1272,7 @@ func TestListUserGroups_BuildListUserGroupsSuccessfulJsonResponse(t *testing.T)
 			},
 		}
 
-		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result)
+		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result, nil, nil)
 		So(err, ShouldBeNil)
 		So(reflect.TypeOf(response), ShouldEqual, reflect.TypeOf([]byte{}))
 
@@ -1102,13 +1289,57 @@ func TestListUserGroups_BuildListUserGroupsSuccessfulJsonResponse(t *testing.T)
 		So(*userGroupsJSON.Groups[1].Name, ShouldEqual, *result.Groups[1].Description)
 	})
 
+	Convey("Memberships with an expiry time are marked as temporary", t, func() {
+		ctx := context.Background()
+		input := models.ListUserGroups{}
+		expiresAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+		result := &cognitoidentityprovider.AdminListGroupsForUserOutput{
+			Groups: []types.GroupType{
+				{GroupName: aws.String("permanent-group"), Description: aws.String("Permanent group")},
+				{GroupName: aws.String("temporary-group"), Description: aws.String("Temporary group")},
+			},
+		}
+
+		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result, map[string]time.Time{"temporary-group": expiresAt}, nil)
+		So(err, ShouldBeNil)
+
+		var userGroupsJSON models.ListUserGroups
+		So(json.Unmarshal(response, &userGroupsJSON), ShouldBeNil)
+		So(userGroupsJSON.Groups[0].Temporary, ShouldBeFalse)
+		So(userGroupsJSON.Groups[0].ExpiresAt, ShouldBeNil)
+		So(userGroupsJSON.Groups[1].Temporary, ShouldBeTrue)
+		So(userGroupsJSON.Groups[1].ExpiresAt.Equal(expiresAt), ShouldBeTrue)
+	})
+
+	Convey("Groups the user is only a member of through a nested group are marked as inherited", t, func() {
+		ctx := context.Background()
+		input := models.ListUserGroups{}
+
+		result := &cognitoidentityprovider.AdminListGroupsForUserOutput{
+			Groups: []types.GroupType{
+				{GroupName: aws.String("child-group"), Description: aws.String("Child group")},
+				{GroupName: aws.String("parent-group"), Description: aws.String("Parent group")},
+			},
+		}
+
+		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result, nil, map[string]bool{"parent-group": true})
+		So(err, ShouldBeNil)
+		So(string(response), ShouldContainSubstring, `"id":"parent-group","last_modified_date":null,"precedence":null,"role_arn":null,"user_pool_id":null,"inherited":true`)
+
+		var userGroupsJSON models.ListUserGroups
+		So(json.Unmarshal(response, &userGroupsJSON), ShouldBeNil)
+		So(userGroupsJSON.Groups[0].Inherited, ShouldBeFalse)
+		So(userGroupsJSON.Groups[1].Inherited, ShouldBeTrue)
+	})
+
 	Convey("Check empty response from cognito i.e valid user with no groups", t, func() {
 		ctx := context.Background()
 		input := models.ListUserGroups{}
 
 		result := &cognitoidentityprovider.AdminListGroupsForUserOutput{}
 
-		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result)
+		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result, nil, nil)
 		So(err, ShouldBeNil)
 
 		var userGroupsJSON models.ListUserGroups
@@ -1126,7 +1357,7 @@ func TestListUserGroups_BuildListUserGroupsSuccessfulJsonResponse(t *testing.T)
 
 		result = nil
 
-		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result)
+		response, err := input.BuildListUserGroupsSuccessfulJSONResponse(ctx, result, nil, nil)
 		castErr := err.(*models.Error)
 		So(castErr.Code, ShouldEqual, models.InternalError)
 		So(response, ShouldBeNil)
@@ -3,10 +3,13 @@ package sdk
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
 
@@ -16,24 +19,38 @@ import (
 
 const (
 	service = "dp-identity-api"
+
+	// maxErrorBodySize limits how much of an error response is read when decoding the errors it describes
+	maxErrorBodySize = 1 << 20
 )
 
 type Client struct {
-	hcCli *healthcheck.Client
+	hcCli       *healthcheck.Client
+	retryPolicy RetryPolicy
+	sleep       func(ctx context.Context, d time.Duration) error
 }
 
-// New creates a new instance of Client with a given topic api url
+// New creates a new instance of Client with a given topic api url. Requests are retried by the client's retry policy
+// rather than by the underlying http client, which would also retry requests that are not safe to repeat.
 func New(identityAPIURL string) *Client {
+	hcCli := healthcheck.NewClient(service, identityAPIURL)
+	hcCli.Client.SetMaxRetries(0)
+
 	return &Client{
-		hcCli: healthcheck.NewClient(service, identityAPIURL),
+		hcCli:       hcCli,
+		retryPolicy: DefaultRetryPolicy,
+		sleep:       sleepContext,
 	}
 }
 
 // NewWithHealthClient creates a new instance of topic API Client,
-// reusing the URL and Clienter from the provided healthcheck client
+// reusing the URL and Clienter from the provided healthcheck client.
+// The Clienter is left unchanged, so any retries it makes itself happen as well as the client's retry policy.
 func NewWithHealthClient(hcCli *healthcheck.Client) *Client {
 	return &Client{
-		hcCli: healthcheck.NewClientWithClienter(service, hcCli.URL, hcCli.Client),
+		hcCli:       healthcheck.NewClientWithClienter(service, hcCli.URL, hcCli.Client),
+		retryPolicy: DefaultRetryPolicy,
+		sleep:       sleepContext,
 	}
 }
 
@@ -52,15 +69,92 @@ func (cli *Client) Checker(ctx context.Context, check *health.CheckState) error
 	return cli.hcCli.Checker(ctx, check)
 }
 
+// RequestOption changes an outgoing request to the identity API, for example to add headers
+type RequestOption func(req *http.Request)
+
+// WithAuthToken sets the Authorization header sent with the request, adding the Bearer prefix if it is missing
+func WithAuthToken(token string) RequestOption {
+	return func(req *http.Request) {
+		if token == "" {
+			return
+		}
+		if !strings.HasPrefix(token, "Bearer ") {
+			token = "Bearer " + token
+		}
+		req.Header.Set("Authorization", token)
+	}
+}
+
+// withHeader sets a header on the request
+func withHeader(name, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(name, value)
+	}
+}
+
 type ResponseInfo struct {
 	Body    []byte
 	Headers http.Header
 	Status  int
 }
 
-// callIdentityAPI calls the Identity API endpoint given by path for the provided REST method, request headers, and body payload.
+// callIdentityAPI calls the Identity API endpoint given by path for the provided REST method, request options, and body payload.
 // It returns the response body and any error that occurred.
-func (cli *Client) callIdentityAPI(ctx context.Context, path, method string, payload []byte) (*ResponseInfo, apiError.Error) {
+func (cli *Client) callIdentityAPI(ctx context.Context, path, method string, payload []byte, opts ...RequestOption) (*ResponseInfo, apiError.Error) {
+	resp, apiErr := cli.doIdentityAPI(ctx, path, method, payload, opts...)
+	if resp == nil {
+		return nil, apiErr
+	}
+	defer func() {
+		_ = closeResponseBody(resp)
+	}()
+
+	respInfo := &ResponseInfo{
+		Headers: resp.Header.Clone(),
+		Status:  resp.StatusCode,
+	}
+
+	if apiErr != nil {
+		return respInfo, apiErr
+	}
+
+	if resp.Body == nil {
+		return respInfo, nil
+	}
+
+	var err error
+	respInfo.Body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return respInfo, apiError.StatusError{
+			Err:  fmt.Errorf("failed to read response body from call to identity api, error is: %v", err),
+			Code: resp.StatusCode,
+		}
+	}
+
+	return respInfo, nil
+}
+
+// streamIdentityAPI calls the Identity API endpoint given by path like callIdentityAPI, but returns the response body
+// unread so that large responses can be streamed. The caller must close the returned body.
+func (cli *Client) streamIdentityAPI(ctx context.Context, path, method string, payload []byte, opts ...RequestOption) (io.ReadCloser, apiError.Error) {
+	resp, apiErr := cli.doIdentityAPI(ctx, path, method, payload, opts...)
+	if apiErr != nil {
+		if resp != nil {
+			_ = closeResponseBody(resp)
+		}
+		return nil, apiErr
+	}
+
+	if resp.Body == nil {
+		return http.NoBody, nil
+	}
+
+	return resp.Body, nil
+}
+
+// doIdentityAPI sends the request to the Identity API. The response is returned with an error for an unexpected status
+// code, so the caller is responsible for closing its body whenever the response is not nil.
+func (cli *Client) doIdentityAPI(ctx context.Context, path, method string, payload []byte, opts ...RequestOption) (*http.Response, apiError.Error) {
 	URL, err := url.Parse(path)
 	if err != nil {
 		return nil, apiError.StatusError{
@@ -89,42 +183,80 @@ func (cli *Client) callIdentityAPI(ctx context.Context, path, method string, pay
 		req.Header.Add("Content-type", "application/json")
 	}
 
-	resp, err := cli.hcCli.Client.Do(ctx, req)
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	resp, err := cli.send(ctx, req)
 	if err != nil {
 		return nil, apiError.StatusError{
 			Err:  fmt.Errorf("failed to call identity api, error is: %v", err),
 			Code: http.StatusInternalServerError,
 		}
 	}
-	defer func() {
-		err = closeResponseBody(resp)
-	}()
 
-	respInfo := &ResponseInfo{
-		Headers: resp.Header.Clone(),
-		Status:  resp.StatusCode,
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= 400 {
+		return resp, newStatusError(resp)
 	}
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= 400 {
-		return respInfo, apiError.StatusError{
-			Err:  fmt.Errorf("failed as unexpected code from identity api: %v", resp.StatusCode),
-			Code: resp.StatusCode,
+	return resp, nil
+}
+
+// send sends the request, retrying temporary failures as allowed by the retry policy
+func (cli *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	policy := cli.retryPolicyFor(ctx, req)
+	retryable := isRetryableRequest(req)
+
+	for retry := 0; ; retry++ {
+		resp, err := cli.hcCli.Client.Do(ctx, req)
+		if !retryable || retry >= len(policy.Backoff) || ctx.Err() != nil || !isRetryableResponse(resp, err) {
+			return resp, err
+		}
+
+		wait, ok := policy.wait(retry, resp, time.Now())
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			_ = closeResponseBody(resp)
+		}
+
+		if sleepErr := cli.sleep(ctx, wait); sleepErr != nil {
+			return nil, sleepErr
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
 		}
 	}
+}
 
-	if resp.Body == nil {
-		return respInfo, nil
+// newStatusError creates the error for an unexpected status code, decoding any errors described in the response body
+func newStatusError(resp *http.Response) apiError.StatusError {
+	var apiErrs []apiError.APIError
+	if resp.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if err == nil {
+			apiErrs = apiError.DecodeAPIErrors(body)
+		}
 	}
 
-	respInfo.Body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return respInfo, apiError.StatusError{
-			Err:  fmt.Errorf("failed to read response body from call to identity api, error is: %v", err),
-			Code: resp.StatusCode,
+	message := fmt.Sprintf("failed as unexpected code from identity api: %v", resp.StatusCode)
+	if len(apiErrs) > 0 {
+		details := make([]string, 0, len(apiErrs))
+		for _, apiErr := range apiErrs {
+			details = append(details, apiErr.Error())
 		}
+		message += ", errors are: " + strings.Join(details, "; ")
 	}
 
-	return respInfo, nil
+	return apiError.StatusError{
+		Err:    errors.New(message),
+		Code:   resp.StatusCode,
+		Errors: apiErrs,
+	}
 }
 
 // closeResponseBody closes the response body and logs an error if unsuccessful
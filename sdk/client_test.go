@@ -1,8 +1,10 @@
 package sdk
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	dphttp "github.com/ONSdigital/dp-net/v3/http"
 
 	"github.com/ONSdigital/dp-identity-api/v2/models"
+	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -46,6 +49,13 @@ func newMockHTTPClient(r *http.Response, err error) *dphttp.ClienterMock {
 	}
 }
 
+func newResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
 func newIdentityAPIClient(_ *testing.T, httpClient *dphttp.ClienterMock) *Client {
 	healthClient := healthcheck.NewClientWithClienter(service, testHost, httpClient)
 	return NewWithHealthClient(healthClient)
@@ -114,3 +124,79 @@ func TestHealthCheckerClient(t *testing.T) {
 		})
 	})
 }
+
+func TestWithAuthToken(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a request", t, func() {
+		req, err := http.NewRequest(http.MethodGet, testHost, http.NoBody)
+		So(err, ShouldBeNil)
+
+		Convey("When WithAuthToken is given a raw token", func() {
+			WithAuthToken("testToken1234")(req)
+
+			Convey("Then the Authorization header has the Bearer prefix added", func() {
+				So(req.Header.Get("Authorization"), ShouldEqual, defaultAuthorization)
+			})
+		})
+
+		Convey("When WithAuthToken is given a Bearer token", func() {
+			WithAuthToken(defaultAuthorization)(req)
+
+			Convey("Then the Authorization header is set unchanged", func() {
+				So(req.Header.Get("Authorization"), ShouldEqual, defaultAuthorization)
+			})
+		})
+
+		Convey("When WithAuthToken is given an empty token", func() {
+			WithAuthToken("")(req)
+
+			Convey("Then no Authorization header is set", func() {
+				So(req.Header.Get("Authorization"), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestCallIdentityAPIErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given the identity API returns a list of errors", t, func() {
+		body := `{"errors":[{"code":"TooManyFailedAttempts","description":"too many unsuccessful sign in attempts"}]}`
+		httpClient := newMockHTTPClient(newResponse(http.StatusForbidden, body), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When the API is called", func() {
+			_, err := identityAPIClient.GetToken(ctx, defaultCredentials)
+
+			Convey("Then the returned error carries the API's errors", func() {
+				So(err.Status(), ShouldEqual, http.StatusForbidden)
+				So(errors.Is(err, apiError.ErrTooManyFailedAttempts), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "TooManyFailedAttempts: too many unsuccessful sign in attempts")
+
+				var statusErr apiError.StatusError
+				So(errors.As(err, &statusErr), ShouldBeTrue)
+				So(statusErr.Errors, ShouldResemble, []apiError.APIError{
+					{Code: "TooManyFailedAttempts", Description: "too many unsuccessful sign in attempts"},
+				})
+			})
+		})
+	})
+
+	Convey("Given the identity API returns an error without a body", t, func() {
+		httpClient := newMockHTTPClient(&http.Response{StatusCode: http.StatusNotFound}, nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When the API is called", func() {
+			_, err := identityAPIClient.GetUser(ctx, "abcd-1234")
+
+			Convey("Then the error has the status but no API errors", func() {
+				So(err.Status(), ShouldEqual, http.StatusNotFound)
+				So(err.Error(), ShouldEqual, "failed as unexpected code from identity api: 404")
+				So(errors.Is(err, apiError.ErrNotFound), ShouldBeTrue)
+				So(errors.Is(err, apiError.ErrUserNotFound), ShouldBeFalse)
+			})
+		})
+	})
+}
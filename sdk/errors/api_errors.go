@@ -0,0 +1,121 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+// APIError is an error described by the identity API in the body of an error response.
+type APIError struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Error returns the code and description of the error.
+func (e APIError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// Sentinel is a kind of identity API error, for use with errors.Is. A StatusError matches a Sentinel when the API
+// returned one of the sentinel's error codes or, for errors without a body, one of its HTTP statuses.
+type Sentinel struct {
+	name     string
+	codes    []string
+	statuses []int
+}
+
+// Error returns the name of the kind of error.
+func (s *Sentinel) Error() string {
+	return s.name
+}
+
+func (s *Sentinel) matches(e StatusError) bool {
+	for _, apiErr := range e.Errors {
+		if slices.Contains(s.codes, apiErr.Code) {
+			return true
+		}
+	}
+	return len(e.Errors) == 0 && slices.Contains(s.statuses, e.Code)
+}
+
+// Sentinels for the kinds of error callers commonly need to handle.
+var (
+	ErrNotFound = &Sentinel{
+		name:     "not found",
+		codes:    []string{models.NotFoundError, models.UserNotFoundError, models.GroupNotFoundError},
+		statuses: []int{http.StatusNotFound},
+	}
+	ErrUserNotFound = &Sentinel{
+		name:  "user not found",
+		codes: []string{models.UserNotFoundError},
+	}
+	ErrGroupNotFound = &Sentinel{
+		name:  "group not found",
+		codes: []string{models.GroupNotFoundError},
+	}
+	ErrUserExists = &Sentinel{
+		name:  "user already exists",
+		codes: []string{models.UsernameExistsError},
+	}
+	ErrGroupExists = &Sentinel{
+		name:  "group already exists",
+		codes: []string{models.GroupExistsError},
+	}
+	ErrThrottled = &Sentinel{
+		name:     "request throttled",
+		codes:    []string{models.TooManyRequestsError, models.LimitExceededError},
+		statuses: []int{http.StatusTooManyRequests},
+	}
+	ErrTooManyFailedAttempts = &Sentinel{
+		name:  "too many failed sign in attempts",
+		codes: []string{models.TooManyFailedAttemptsError},
+	}
+	ErrNotAuthorised = &Sentinel{
+		name:     "not authorised",
+		codes:    []string{models.NotAuthorisedError},
+		statuses: []int{http.StatusUnauthorized},
+	}
+	ErrInvalidToken = &Sentinel{
+		name:  "invalid token",
+		codes: []string{models.InvalidTokenError},
+	}
+	ErrInvalidEmail = &Sentinel{
+		name:  "invalid email",
+		codes: []string{models.InvalidEmailError},
+	}
+	ErrInvalidPassword = &Sentinel{
+		name:  "invalid password",
+		codes: []string{models.InvalidPasswordError},
+	}
+	ErrPreconditionFailed = &Sentinel{
+		name:     "precondition failed",
+		codes:    []string{models.PreconditionFailedError},
+		statuses: []int{http.StatusPreconditionFailed},
+	}
+	ErrInternal = &Sentinel{
+		name:     "internal server error",
+		codes:    []string{models.InternalError},
+		statuses: []int{http.StatusInternalServerError},
+	}
+)
+
+// DecodeAPIErrors parses the body of an identity API error response, which is either a list of errors or, for
+// internal server errors, a single error. It returns nil if the body holds neither.
+func DecodeAPIErrors(body []byte) []APIError {
+	var errorList struct {
+		Errors []APIError `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &errorList); err == nil && len(errorList.Errors) > 0 {
+		return errorList.Errors
+	}
+
+	var single APIError
+	if err := json.Unmarshal(body, &single); err == nil && single.Code != "" {
+		return []APIError{single}
+	}
+
+	return nil
+}
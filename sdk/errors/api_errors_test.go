@@ -0,0 +1,107 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecodeAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an error list body", t, func() {
+		body := []byte(`{"errors":[{"code":"InvalidEmail","description":"the submitted email could not be validated"},{"code":"InvalidForename","description":"the submitted user's forename could not be validated"}]}`)
+
+		Convey("When it is decoded", func() {
+			apiErrs := DecodeAPIErrors(body)
+
+			Convey("Then every error is returned", func() {
+				So(apiErrs, ShouldHaveLength, 2)
+				So(apiErrs[0], ShouldResemble, APIError{Code: "InvalidEmail", Description: "the submitted email could not be validated"})
+				So(apiErrs[1].Code, ShouldEqual, "InvalidForename")
+			})
+		})
+	})
+
+	Convey("Given a single error body as returned for internal server errors", t, func() {
+		body := []byte(`{"code":"InternalServerError","description":"Internal Server Error"}`)
+
+		Convey("When it is decoded", func() {
+			apiErrs := DecodeAPIErrors(body)
+
+			Convey("Then the error is returned", func() {
+				So(apiErrs, ShouldResemble, []APIError{{Code: "InternalServerError", Description: "Internal Server Error"}})
+			})
+		})
+	})
+
+	Convey("Given a body that does not describe any errors", t, func() {
+		for _, body := range []string{``, `not json`, `{}`, `{"errors":[]}`} {
+			Convey(fmt.Sprintf("When %q is decoded", body), func() {
+				apiErrs := DecodeAPIErrors([]byte(body))
+
+				Convey("Then no errors are returned", func() {
+					So(apiErrs, ShouldBeNil)
+				})
+			})
+		}
+	})
+}
+
+func TestSentinels(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a status error with a UserNotFound error from the API", t, func() {
+		var err error = StatusError{
+			Code:   http.StatusNotFound,
+			Err:    errors.New("failed as unexpected code from identity api: 404"),
+			Errors: []APIError{{Code: "UserNotFound", Description: "the user could not be found"}},
+		}
+
+		Convey("Then it matches the user not found and not found sentinels only", func() {
+			So(errors.Is(err, ErrUserNotFound), ShouldBeTrue)
+			So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+			So(errors.Is(err, ErrGroupNotFound), ShouldBeFalse)
+			So(errors.Is(err, ErrThrottled), ShouldBeFalse)
+		})
+
+		Convey("Then it has the API's error code", func() {
+			var statusErr StatusError
+			So(errors.As(err, &statusErr), ShouldBeTrue)
+			So(statusErr.HasCode("UserNotFound"), ShouldBeTrue)
+			So(statusErr.HasCode("GroupNotFound"), ShouldBeFalse)
+		})
+
+		Convey("Then it still matches when wrapped", func() {
+			So(errors.Is(fmt.Errorf("getting user: %w", err), ErrUserNotFound), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a status error with a throttling error code", t, func() {
+		err := StatusError{Code: http.StatusBadRequest, Errors: []APIError{{Code: "LimitExceeded"}}}
+
+		Convey("Then it matches the throttled sentinel", func() {
+			So(errors.Is(err, ErrThrottled), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a status error without errors from the API", t, func() {
+		err := StatusError{Code: http.StatusTooManyRequests}
+
+		Convey("Then it matches the sentinels for its status", func() {
+			So(errors.Is(err, ErrThrottled), ShouldBeTrue)
+			So(errors.Is(err, ErrNotFound), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a status error whose API errors do not match a sentinel's status", t, func() {
+		err := StatusError{Code: http.StatusNotFound, Errors: []APIError{{Code: "InvalidGroupID"}}}
+
+		Convey("Then the status alone does not match", func() {
+			So(errors.Is(err, ErrNotFound), ShouldBeFalse)
+		})
+	})
+}
@@ -9,10 +9,12 @@ type Error interface {
 	Status() int
 }
 
-// StatusError represents an error with an associated HTTP status code.
+// StatusError represents an error with an associated HTTP status code, along with any errors the identity API
+// described in the response body.
 type StatusError struct {
-	Code int
-	Err  error
+	Code   int
+	Err    error
+	Errors []APIError
 }
 
 // Allows StatusError to satisfy the error interface.
@@ -29,6 +31,30 @@ func (e StatusError) Status() int {
 	return e.Code
 }
 
+// Unwrap returns the underlying error.
+func (e StatusError) Unwrap() error {
+	return e.Err
+}
+
+// HasCode reports whether the identity API returned an error with the given code.
+func (e StatusError) HasCode(code string) bool {
+	for _, apiErr := range e.Errors {
+		if apiErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Is allows the Sentinel errors to be matched with errors.Is.
+func (e StatusError) Is(target error) bool {
+	sentinel, ok := target.(*Sentinel)
+	if !ok {
+		return false
+	}
+	return sentinel.matches(e)
+}
+
 func ErrorStatus(err error) int {
 	var rerr Error
 	if errors.As(err, &rerr) {
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/ONSdigital/dp-identity-api/v2/models"
 	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
 )
 
@@ -18,6 +19,9 @@ var (
 	SortName     sortParam = "name"
 	SortNameAsc  sortParam = "name:asc"
 	SortNameDesc sortParam = "name:desc"
+
+	SortForenameAsc  sortParam = "forename:asc"
+	SortForenameDesc sortParam = "forename:desc"
 )
 
 type GroupsResponse struct {
@@ -36,14 +40,53 @@ type Group struct {
 	Created    time.Time `json:"created"`
 }
 
+// GroupParams holds the fields of a group that can be set when creating or updating it. Only the fields that are set
+// are sent, so an update leaves the others unchanged where the API allows it.
+type GroupParams struct {
+	ID           *string `json:"id,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	Precedence   *int32  `json:"precedence,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	Owner        *string `json:"owner,omitempty"`
+	ContactEmail *string `json:"contact_email,omitempty"`
+}
+
+// DeleteGroupQuery holds the optional query parameters for deleting a group
+type DeleteGroupQuery struct {
+	// Force deletes the group even if it still has members
+	Force bool
+	// TransferTo is the ID of a group to move the members to before the group is deleted
+	TransferTo string
+}
+
+// values returns the query string values for the delete group query
+func (q *DeleteGroupQuery) values() url.Values {
+	values := url.Values{}
+	if q == nil {
+		return values
+	}
+	if q.Force {
+		values.Set("force", "true")
+	}
+	if q.TransferTo != "" {
+		values.Set("transfer_to", q.TransferTo)
+	}
+	return values
+}
+
+// groupMember is the body of a request adding a user to a group
+type groupMember struct {
+	UserID string `json:"user_id"`
+}
+
 // GetGroups gets a list of groups
-func (cli *Client) GetGroups(ctx context.Context, sort *sortParam) (*GroupsResponse, apiError.Error) {
+func (cli *Client) GetGroups(ctx context.Context, sort *sortParam, opts ...RequestOption) (*GroupsResponse, apiError.Error) {
 	path := fmt.Sprintf("%s/groups", cli.hcCli.URL)
 	if sort != nil {
 		path += "?sort=" + url.QueryEscape(string(*sort))
 	}
 
-	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil)
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
 	if apiErr != nil {
 		return nil, apiErr
 	}
@@ -60,10 +103,10 @@ func (cli *Client) GetGroups(ctx context.Context, sort *sortParam) (*GroupsRespo
 }
 
 // GetGroup gets a single group by its ID
-func (cli *Client) GetGroup(ctx context.Context, id string) (*GroupsResponse, apiError.Error) {
+func (cli *Client) GetGroup(ctx context.Context, id string, opts ...RequestOption) (*GroupsResponse, apiError.Error) {
 	path := fmt.Sprintf("%s/groups/%s", cli.hcCli.URL, id)
 
-	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil)
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
 	if apiErr != nil {
 		return nil, apiErr
 	}
@@ -82,3 +125,122 @@ func (cli *Client) GetGroup(ctx context.Context, id string) (*GroupsResponse, ap
 	}
 	return groupsResponse, nil
 }
+
+// CreateGroup creates a new group, the API generates an ID for the group if none is given
+func (cli *Client) CreateGroup(ctx context.Context, group GroupParams, opts ...RequestOption) (*GroupParams, apiError.Error) {
+	path := fmt.Sprintf("%s/groups", cli.hcCli.URL)
+
+	b, _ := json.Marshal(group)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPost, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalGroupParams(respInfo.Body)
+}
+
+// UpdateGroup updates the group with the given ID
+func (cli *Client) UpdateGroup(ctx context.Context, id string, group GroupParams, opts ...RequestOption) (*GroupParams, apiError.Error) {
+	path := fmt.Sprintf("%s/groups/%s", cli.hcCli.URL, id)
+
+	b, _ := json.Marshal(group)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPut, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalGroupParams(respInfo.Body)
+}
+
+// DeleteGroup deletes the group with the given ID
+func (cli *Client) DeleteGroup(ctx context.Context, id string, query *DeleteGroupQuery, opts ...RequestOption) apiError.Error {
+	path := fmt.Sprintf("%s/groups/%s", cli.hcCli.URL, id)
+	if values := query.values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	_, apiErr := cli.callIdentityAPI(ctx, path, http.MethodDelete, nil, opts...)
+	return apiErr
+}
+
+// ListGroupMembers gets the users in the group with the given ID
+func (cli *Client) ListGroupMembers(ctx context.Context, id string, sort *sortParam, opts ...RequestOption) (*models.UsersList, apiError.Error) {
+	path := fmt.Sprintf("%s/groups/%s/members", cli.hcCli.URL, id)
+	if sort != nil {
+		path += "?sort=" + url.QueryEscape(string(*sort))
+	}
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUsersList(respInfo.Body)
+}
+
+// AddGroupMember adds the user to the group, returning the group's members
+func (cli *Client) AddGroupMember(ctx context.Context, groupID, userID string, opts ...RequestOption) (*models.UsersList, apiError.Error) {
+	path := fmt.Sprintf("%s/groups/%s/members", cli.hcCli.URL, groupID)
+
+	b, _ := json.Marshal(groupMember{UserID: userID})
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPost, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUsersList(respInfo.Body)
+}
+
+// RemoveGroupMember removes the user from the group, returning the group's remaining members
+func (cli *Client) RemoveGroupMember(ctx context.Context, groupID, userID string, opts ...RequestOption) (*models.UsersList, apiError.Error) {
+	path := fmt.Sprintf("%s/groups/%s/members/%s", cli.hcCli.URL, groupID, userID)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodDelete, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUsersList(respInfo.Body)
+}
+
+// SetGroupMembers replaces the members of the group with the given users, returning the changes that were made
+func (cli *Client) SetGroupMembers(ctx context.Context, groupID string, userIDs []string, opts ...RequestOption) (*models.GroupMembershipDiff, apiError.Error) {
+	path := fmt.Sprintf("%s/groups/%s/members", cli.hcCli.URL, groupID)
+
+	members := make([]groupMember, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, groupMember{UserID: userID})
+	}
+	b, _ := json.Marshal(members)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPut, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var membershipDiff models.GroupMembershipDiff
+
+	if err := json.Unmarshal(respInfo.Body, &membershipDiff); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal membershipDiff - error is: %v", err),
+		}
+	}
+
+	return &membershipDiff, nil
+}
+
+// unmarshalGroupParams unmarshals a created or updated group response body
+func unmarshalGroupParams(body []byte) (*GroupParams, apiError.Error) {
+	var group GroupParams
+
+	if err := json.Unmarshal(body, &group); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal group - error is: %v", err),
+		}
+	}
+
+	return &group, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
@@ -19,10 +20,10 @@ type GroupsReportItem struct {
 }
 
 // GetGroupsReport gets a list of groups-report
-func (cli *Client) GetGroupsReport(ctx context.Context) (*GroupsReportResponse, apiError.Error) {
+func (cli *Client) GetGroupsReport(ctx context.Context, opts ...RequestOption) (*GroupsReportResponse, apiError.Error) {
 	path := fmt.Sprintf("%s/groups-report", cli.hcCli.URL)
 
-	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil)
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
 	if apiErr != nil {
 		return nil, apiErr
 	}
@@ -37,3 +38,13 @@ func (cli *Client) GetGroupsReport(ctx context.Context) (*GroupsReportResponse,
 
 	return &groupsReportResponse, nil
 }
+
+// GetGroupsReportCSV gets the groups-report as CSV. The body is streamed rather than read into memory, so the caller
+// must close the returned reader.
+func (cli *Client) GetGroupsReportCSV(ctx context.Context, opts ...RequestOption) (io.ReadCloser, apiError.Error) {
+	path := fmt.Sprintf("%s/groups-report", cli.hcCli.URL)
+
+	opts = append(opts, withHeader("Accept", "text/csv"))
+
+	return cli.streamIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+}
@@ -101,3 +101,61 @@ func TestGetGroupsReport(t *testing.T) {
 		})
 	})
 }
+
+func TestGetGroupsReportCSV(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	groupsReportCSV := "Group,User\nGroup A,user1@ons.gov.uk\n"
+
+	Convey("Given GetGroupsReportCSV will return the report successfully", t, func() {
+		httpClient := newMockHTTPClient(
+			&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader([]byte(groupsReportCSV))),
+			},
+			nil)
+
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetGroupsReportCSV is called", func() {
+			body, err := identityAPIClient.GetGroupsReportCSV(ctx)
+			So(err, ShouldBeNil)
+			defer body.Close()
+
+			Convey("Then the CSV body can be read from the returned reader", func() {
+				csv, readErr := io.ReadAll(body)
+				So(readErr, ShouldBeNil)
+				So(string(csv), ShouldEqual, groupsReportCSV)
+
+				Convey("And the report is requested as CSV", func() {
+					doCalls := httpClient.DoCalls()
+					So(doCalls, ShouldHaveLength, 1)
+					So(doCalls[0].Req.URL.Path, ShouldEqual, "/groups-report")
+					So(doCalls[0].Req.Method, ShouldEqual, http.MethodGet)
+					So(doCalls[0].Req.Header.Get("Accept"), ShouldEqual, "text/csv")
+				})
+			})
+		})
+	})
+
+	Convey("Given GetGroupsReportCSV returns an error", t, func() {
+		httpClient := newMockHTTPClient(
+			&http.Response{
+				StatusCode: http.StatusForbidden,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"errors":[]}`))),
+			},
+			nil)
+
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetGroupsReportCSV is called", func() {
+			body, err := identityAPIClient.GetGroupsReportCSV(ctx)
+
+			Convey("Then the error status is returned and no body", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusForbidden)
+				So(body, ShouldBeNil)
+			})
+		})
+	})
+}
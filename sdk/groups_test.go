@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ONSdigital/dp-identity-api/v2/models"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -196,3 +197,199 @@ func TestGetGroup(t *testing.T) {
 		})
 	})
 }
+
+func TestCreateGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	name, precedence := "Group A", int32(10)
+	groupBody := `{"id":"group-a","name":"Group A","precedence":10}`
+
+	Convey("Given CreateGroup will create a group successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusCreated, groupBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When CreateGroup is called", func() {
+			group, err := identityAPIClient.CreateGroup(ctx, GroupParams{Name: &name, Precedence: &precedence}, WithAuthToken(testUserToken))
+			So(err, ShouldBeNil)
+
+			Convey("Then the created group is returned", func() {
+				So(*group.ID, ShouldEqual, "group-a")
+				So(*group.Name, ShouldEqual, name)
+				So(*group.Precedence, ShouldEqual, precedence)
+
+				Convey("And only the set fields are sent", func() {
+					doCalls := httpClient.DoCalls()
+					So(doCalls, ShouldHaveLength, 1)
+					So(doCalls[0].Req.URL.Path, ShouldEqual, "/groups")
+					So(doCalls[0].Req.Method, ShouldEqual, http.MethodPost)
+					So(doCalls[0].Req.Header.Get("Authorization"), ShouldEqual, "Bearer "+testUserToken)
+					actualBody, _ := io.ReadAll(doCalls[0].Req.Body)
+					So(string(actualBody), ShouldEqual, `{"name":"Group A","precedence":10}`)
+				})
+			})
+		})
+	})
+
+	Convey("Given CreateGroup returns a bad request", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusBadRequest, `{"errors":[]}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When CreateGroup is called", func() {
+			group, err := identityAPIClient.CreateGroup(ctx, GroupParams{Name: &name})
+
+			Convey("Then the error status is returned and no group", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusBadRequest)
+				So(group, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestUpdateGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	name := "Group B"
+
+	Convey("Given UpdateGroup will update a group successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, `{"id":"group-a","name":"Group B","precedence":10}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When UpdateGroup is called", func() {
+			group, err := identityAPIClient.UpdateGroup(ctx, "group-a", GroupParams{Name: &name})
+			So(err, ShouldBeNil)
+
+			Convey("Then the updated group is returned", func() {
+				So(*group.Name, ShouldEqual, name)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, "/groups/group-a")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPut)
+			})
+		})
+	})
+}
+
+func TestDeleteGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given DeleteGroup will delete a group successfully", t, func() {
+		httpClient := newMockHTTPClient(&http.Response{StatusCode: http.StatusNoContent}, nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When DeleteGroup is called without a query", func() {
+			err := identityAPIClient.DeleteGroup(ctx, "group-a", nil)
+
+			Convey("Then no error is returned and no query parameters are sent", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, "/groups/group-a")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodDelete)
+				So(doCalls[0].Req.URL.RawQuery, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When DeleteGroup is called with a transfer group", func() {
+			err := identityAPIClient.DeleteGroup(ctx, "group-a", &DeleteGroupQuery{TransferTo: "group-b"})
+
+			Convey("Then the transfer group is sent", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Query().Get("transfer_to"), ShouldEqual, "group-b")
+				So(doCalls[0].Req.URL.Query().Get("force"), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given DeleteGroup is refused as the group has members", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusConflict, `{"errors":[]}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When DeleteGroup is called", func() {
+			err := identityAPIClient.DeleteGroup(ctx, "group-a", nil)
+
+			Convey("Then the conflict status is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusConflict)
+			})
+		})
+	})
+}
+
+func TestGroupMembers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	membersBody := `{"count":1,"users":[` + userBody + `]}`
+	membersEndpoint := "/groups/group-a/members"
+
+	Convey("Given the group members endpoints respond successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, membersBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When ListGroupMembers is called with a sort", func() {
+			members, err := identityAPIClient.ListGroupMembers(ctx, "group-a", &SortForenameDesc)
+			So(err, ShouldBeNil)
+
+			Convey("Then the members are returned and the sort is sent", func() {
+				So(members.Users, ShouldResemble, []models.UserParams{expectedUser})
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, membersEndpoint)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodGet)
+				So(doCalls[0].Req.URL.Query().Get("sort"), ShouldEqual, "forename:desc")
+			})
+		})
+
+		Convey("When AddGroupMember is called", func() {
+			members, err := identityAPIClient.AddGroupMember(ctx, "group-a", testUserID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the members are returned and the user is sent", func() {
+				So(members.Count, ShouldEqual, 1)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, membersEndpoint)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPost)
+				actualBody, _ := io.ReadAll(doCalls[0].Req.Body)
+				So(string(actualBody), ShouldEqual, `{"user_id":"`+testUserID+`"}`)
+			})
+		})
+
+		Convey("When RemoveGroupMember is called", func() {
+			_, err := identityAPIClient.RemoveGroupMember(ctx, "group-a", testUserID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the member endpoint is called", func() {
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, membersEndpoint+"/"+testUserID)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodDelete)
+			})
+		})
+	})
+
+	Convey("Given SetGroupMembers will replace the members successfully", t, func() {
+		diffBody := `{"count":1,"users":[` + userBody + `],"dry_run":false,"to_add":[{"user_id":"abcd-1234","status":"added"}],"to_remove":[],"unchanged":[]}`
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, diffBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When SetGroupMembers is called", func() {
+			diff, err := identityAPIClient.SetGroupMembers(ctx, "group-a", []string{testUserID})
+			So(err, ShouldBeNil)
+
+			Convey("Then the membership changes are returned and the users are sent", func() {
+				So(diff.ToAdd, ShouldHaveLength, 1)
+				So(diff.UsersList.Users, ShouldResemble, []models.UserParams{expectedUser})
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, membersEndpoint)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPut)
+				actualBody, _ := io.ReadAll(doCalls[0].Req.Body)
+				So(string(actualBody), ShouldEqual, `[{"user_id":"`+testUserID+`"}]`)
+			})
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package sdk
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header that lets the identity API recognise a repeated create request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy controls how requests that fail with a temporary error are retried. GET and PUT requests are retried on
+// a 429, 502, 503 or 504 response or when the API could not be reached. POST requests are only retried when they have
+// an idempotency key, so that a retry cannot create something twice.
+type RetryPolicy struct {
+	// Backoff is how long to wait before each retry, so its length is the maximum number of retries
+	Backoff []time.Duration
+	// Jitter is the fraction by which each wait is randomly varied, so that clients do not all retry at once
+	Jitter float64
+	// MaxRetryAfter is the longest wait a Retry-After header is honoured for, longer waits are not retried
+	MaxRetryAfter time.Duration
+}
+
+var (
+	// DefaultRetryPolicy mirrors the back off schedule the identity API uses for its own calls to Cognito
+	DefaultRetryPolicy = RetryPolicy{
+		Backoff:       []time.Duration{1 * time.Second, 3 * time.Second, 10 * time.Second},
+		Jitter:        0.2,
+		MaxRetryAfter: 30 * time.Second,
+	}
+
+	// NoRetries sends every request once
+	NoRetries = RetryPolicy{}
+)
+
+// retryPolicyKey is the context key of a retry policy overriding the client's
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy returns a context that makes the client use the given retry policy for calls made with it
+func ContextWithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// WithRetryPolicy makes the client use the given retry policy for the call
+func WithRetryPolicy(policy RetryPolicy) RequestOption {
+	return func(req *http.Request) {
+		*req = *req.WithContext(ContextWithRetryPolicy(req.Context(), policy))
+	}
+}
+
+// WithIdempotencyKey sets the idempotency key of a create request, which also allows the request to be retried
+func WithIdempotencyKey(key string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// SetRetryPolicy sets the retry policy used for calls that do not override it
+func (cli *Client) SetRetryPolicy(policy RetryPolicy) {
+	cli.retryPolicy = policy
+}
+
+// retryPolicyFor returns the retry policy for the request, a policy set by a request option taking precedence over
+// one on the call's context, which in turn takes precedence over the client's
+func (cli *Client) retryPolicyFor(ctx context.Context, req *http.Request) RetryPolicy {
+	if policy, ok := req.Context().Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return cli.retryPolicy
+}
+
+// isRetryableRequest reports whether the request can safely be sent again
+func isRetryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodPut:
+		return true
+	case http.MethodPost:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	default:
+		return false
+	}
+}
+
+// isRetryableResponse reports whether the outcome of a request is a temporary failure
+func isRetryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait returns how long to wait before the given retry, preferring the response's Retry-After header to the backoff.
+// It returns false if the API asked for a longer wait than the policy allows.
+func (p RetryPolicy) wait(retry int, resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return retryAfter, retryAfter <= p.MaxRetryAfter
+		}
+	}
+
+	wait := p.Backoff[retry]
+	if p.Jitter > 0 {
+		wait += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(wait))
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or a date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the given duration, returning early with the context's error if it is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
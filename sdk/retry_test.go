@@ -0,0 +1,265 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newSequenceHTTPClient returns a mock http client that gives the responses in turn, repeating the last one
+func newSequenceHTTPClient(responses ...func() (*http.Response, error)) *dphttp.ClienterMock {
+	httpClient := newMockHTTPClient(nil, nil)
+	calls := 0
+	httpClient.DoFunc = func(_ context.Context, _ *http.Request) (*http.Response, error) {
+		next := responses[min(calls, len(responses)-1)]
+		calls++
+		return next()
+	}
+	return httpClient
+}
+
+func respondWith(statusCode int, body string, headers ...string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := newResponse(statusCode, body)
+		resp.Header = http.Header{}
+		for i := 0; i+1 < len(headers); i += 2 {
+			resp.Header.Set(headers[i], headers[i+1])
+		}
+		return resp, nil
+	}
+}
+
+func newRetryTestClient(t *testing.T, httpClient *dphttp.ClienterMock) (*Client, *[]time.Duration) {
+	identityAPIClient := newIdentityAPIClient(t, httpClient)
+	waits := &[]time.Duration{}
+	identityAPIClient.sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return identityAPIClient, waits
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	groupBody := `{"group":{"id":"1","name":"Group A","precedence":1,"created":"2024-05-13T12:00:00Z"}}`
+
+	Convey("Given the API is temporarily unavailable and then succeeds", t, func() {
+		httpClient := newSequenceHTTPClient(
+			respondWith(http.StatusServiceUnavailable, ""),
+			respondWith(http.StatusBadGateway, ""),
+			respondWith(http.StatusOK, groupBody),
+		)
+		identityAPIClient, waits := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			groups, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it is retried after jittered waits until it succeeds", func() {
+				So(err, ShouldBeNil)
+				So(groups.Groups[0].Name, ShouldEqual, "Group A")
+				So(httpClient.DoCalls(), ShouldHaveLength, 3)
+				So(*waits, ShouldHaveLength, 2)
+				So((*waits)[0], ShouldBeBetweenOrEqual, 800*time.Millisecond, 1200*time.Millisecond)
+				So((*waits)[1], ShouldBeBetweenOrEqual, 2400*time.Millisecond, 3600*time.Millisecond)
+			})
+		})
+
+		Convey("When a PUT request with a body is made", func() {
+			name := "Group A"
+			_, err := identityAPIClient.UpdateGroup(ctx, "1", GroupParams{Name: &name})
+
+			Convey("Then the body is sent again with each retry", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 3)
+				body, _ := io.ReadAll(doCalls[2].Req.Body)
+				So(string(body), ShouldEqual, `{"name":"Group A"}`)
+			})
+		})
+
+		Convey("When a POST request is made without an idempotency key", func() {
+			name := "Group A"
+			_, err := identityAPIClient.CreateGroup(ctx, GroupParams{Name: &name})
+
+			Convey("Then it is not retried", func() {
+				So(err.Status(), ShouldEqual, http.StatusServiceUnavailable)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When a POST request is made with an idempotency key", func() {
+			name := "Group A"
+			_, err := identityAPIClient.CreateGroup(ctx, GroupParams{Name: &name}, WithIdempotencyKey("key-1"))
+
+			Convey("Then it is retried with the same key", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 3)
+				So(doCalls[2].Req.Header.Get(IdempotencyKeyHeader), ShouldEqual, "key-1")
+			})
+		})
+
+		Convey("When the retry policy is overridden for the call by an option", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1", WithRetryPolicy(NoRetries))
+
+			Convey("Then it is not retried", func() {
+				So(err.Status(), ShouldEqual, http.StatusServiceUnavailable)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the retry policy is overridden by the context", func() {
+			policy := RetryPolicy{Backoff: []time.Duration{time.Millisecond}}
+			_, err := identityAPIClient.GetGroup(ContextWithRetryPolicy(ctx, policy), "1")
+
+			Convey("Then the context's policy is used", func() {
+				So(err.Status(), ShouldEqual, http.StatusBadGateway)
+				So(httpClient.DoCalls(), ShouldHaveLength, 2)
+				So(*waits, ShouldResemble, []time.Duration{time.Millisecond})
+			})
+		})
+
+		Convey("When the client's retry policy is changed", func() {
+			identityAPIClient.SetRetryPolicy(NoRetries)
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then the new policy is used", func() {
+				So(err.Status(), ShouldEqual, http.StatusServiceUnavailable)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given the API keeps returning a temporary error", t, func() {
+		httpClient := newSequenceHTTPClient(respondWith(http.StatusGatewayTimeout, ""))
+		identityAPIClient, waits := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it gives up after the retries in the backoff", func() {
+				So(err.Status(), ShouldEqual, http.StatusGatewayTimeout)
+				So(httpClient.DoCalls(), ShouldHaveLength, len(DefaultRetryPolicy.Backoff)+1)
+				So(*waits, ShouldHaveLength, len(DefaultRetryPolicy.Backoff))
+			})
+		})
+	})
+
+	Convey("Given the API is throttling requests with a Retry-After header", t, func() {
+		httpClient := newSequenceHTTPClient(
+			respondWith(http.StatusTooManyRequests, "", "Retry-After", "7"),
+			respondWith(http.StatusOK, groupBody),
+		)
+		identityAPIClient, waits := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it waits for as long as the API asked before retrying", func() {
+				So(err, ShouldBeNil)
+				So(*waits, ShouldResemble, []time.Duration{7 * time.Second})
+			})
+		})
+	})
+
+	Convey("Given the API asks for a longer wait than the policy allows", t, func() {
+		httpClient := newSequenceHTTPClient(respondWith(http.StatusTooManyRequests, "", "Retry-After", "3600"))
+		identityAPIClient, waits := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it is not retried", func() {
+				So(err.Status(), ShouldEqual, http.StatusTooManyRequests)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+				So(*waits, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a response that is not a temporary failure", t, func() {
+		httpClient := newSequenceHTTPClient(respondWith(http.StatusInternalServerError, ""))
+		identityAPIClient, _ := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it is not retried", func() {
+				So(err.Status(), ShouldEqual, http.StatusInternalServerError)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given the API cannot be reached", t, func() {
+		httpClient := newSequenceHTTPClient(
+			func() (*http.Response, error) { return nil, errors.New("connection refused") },
+			respondWith(http.StatusOK, groupBody),
+		)
+		identityAPIClient, _ := newRetryTestClient(t, httpClient)
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(ctx, "1")
+
+			Convey("Then it is retried", func() {
+				So(err, ShouldBeNil)
+				So(httpClient.DoCalls(), ShouldHaveLength, 2)
+			})
+		})
+	})
+
+	Convey("Given the context is cancelled while waiting to retry", t, func() {
+		httpClient := newSequenceHTTPClient(respondWith(http.StatusServiceUnavailable, ""))
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		Convey("When a GET request is made", func() {
+			_, err := identityAPIClient.GetGroup(cancelledCtx, "1")
+
+			Convey("Then it stops without retrying", func() {
+				So(err, ShouldNotBeNil)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 5, 13, 12, 0, 0, 0, time.UTC)
+
+	Convey("Given Retry-After headers", t, func() {
+		Convey("Then a number of seconds is parsed", func() {
+			wait, ok := parseRetryAfter("120", now)
+			So(ok, ShouldBeTrue)
+			So(wait, ShouldEqual, 2*time.Minute)
+		})
+
+		Convey("Then a date is parsed relative to now", func() {
+			wait, ok := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+			So(ok, ShouldBeTrue)
+			So(wait, ShouldEqual, 30*time.Second)
+		})
+
+		Convey("Then a date in the past means no wait", func() {
+			wait, ok := parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+			So(ok, ShouldBeTrue)
+			So(wait, ShouldEqual, 0)
+		})
+
+		Convey("Then an empty or invalid header is ignored", func() {
+			_, ok := parseRetryAfter("", now)
+			So(ok, ShouldBeFalse)
+			_, ok = parseRetryAfter("soon", now)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,165 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
+)
+
+const (
+	// DefaultRefreshMargin is how long before the access token expires that a TokenSource refreshes it
+	DefaultRefreshMargin = time.Minute
+
+	// tokenExpiryLayout is the format of the expiration times returned by the tokens endpoints
+	tokenExpiryLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+)
+
+var errTokenSourceClosed = errors.New("token source is closed")
+
+// TokenSource keeps a signed in session with the identity API alive. It signs in once, refreshes the access token
+// shortly before it expires and only signs in again if the refresh token has expired or been revoked, so long running
+// jobs do not repeatedly sign in. It is safe for concurrent use.
+type TokenSource struct {
+	client        *Client
+	credentials   models.UserSignIn
+	refreshMargin time.Duration
+	now           func() time.Time
+
+	mu            sync.Mutex
+	token         *TokenResponse
+	expiry        time.Time
+	refreshExpiry time.Time
+	closed        bool
+}
+
+// NewTokenSource creates a TokenSource that signs in with the given credentials the first time a token is needed
+func (cli *Client) NewTokenSource(credentials models.UserSignIn) *TokenSource {
+	return &TokenSource{
+		client:        cli,
+		credentials:   credentials,
+		refreshMargin: DefaultRefreshMargin,
+		now:           time.Now,
+	}
+}
+
+// Token returns the tokens of the session, signing in or refreshing the access token first if needed
+func (ts *TokenSource) Token(ctx context.Context) (*TokenResponse, apiError.Error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return nil, apiError.StatusError{Err: errTokenSourceClosed}
+	}
+
+	now := ts.now()
+	if ts.token != nil && now.Before(ts.expiry.Add(-ts.refreshMargin)) {
+		return ts.currentToken(), nil
+	}
+
+	if ts.token != nil && now.Before(ts.refreshExpiry) {
+		apiErr := ts.refresh(ctx)
+		if apiErr == nil {
+			return ts.currentToken(), nil
+		}
+		if apiErr.Status() != http.StatusForbidden {
+			// the access token can still be used until it expires, so a failed refresh is retried on the next call
+			if now.Before(ts.expiry) {
+				return ts.currentToken(), nil
+			}
+			return nil, apiErr
+		}
+		// the refresh token has been revoked, so a new session is needed
+	}
+
+	if apiErr := ts.signIn(ctx); apiErr != nil {
+		return nil, apiErr
+	}
+	return ts.currentToken(), nil
+}
+
+// AuthToken returns a request option that authorises a request with the session's access token
+func (ts *TokenSource) AuthToken(ctx context.Context) (RequestOption, apiError.Error) {
+	token, apiErr := ts.Token(ctx)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return WithAuthToken(token.Token), nil
+}
+
+// Close signs out the session. The TokenSource cannot be used after it is closed.
+func (ts *TokenSource) Close(ctx context.Context) apiError.Error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return nil
+	}
+	ts.closed = true
+
+	if ts.token == nil {
+		return nil
+	}
+	accessToken := ts.token.Token
+	ts.token = nil
+
+	return ts.client.SignOut(ctx, accessToken)
+}
+
+// signIn starts a new session, replacing any current tokens
+func (ts *TokenSource) signIn(ctx context.Context) apiError.Error {
+	token, apiErr := ts.client.GetToken(ctx, ts.credentials)
+	if apiErr != nil {
+		return apiErr
+	}
+	if token.Token == "" {
+		// a new password is required before the user can sign in
+		return apiError.StatusError{Err: errors.New("sign in did not return an access token")}
+	}
+
+	refreshExpiry, err := time.Parse(tokenExpiryLayout, token.RefreshTokenExpirationTime)
+	if err != nil {
+		return apiError.StatusError{
+			Err: fmt.Errorf("failed to parse refresh token expiration time - error is: %v", err),
+		}
+	}
+
+	if apiErr = ts.setToken(token); apiErr != nil {
+		return apiErr
+	}
+	ts.refreshExpiry = refreshExpiry
+	return nil
+}
+
+// refresh gets a new access token for the current session
+func (ts *TokenSource) refresh(ctx context.Context) apiError.Error {
+	token, apiErr := ts.client.RefreshToken(ctx, *ts.token)
+	if apiErr != nil {
+		return apiErr
+	}
+	return ts.setToken(token)
+}
+
+// setToken stores the tokens along with the expiry of the access token
+func (ts *TokenSource) setToken(token *TokenResponse) apiError.Error {
+	expiry, err := time.Parse(tokenExpiryLayout, token.ExpirationTime)
+	if err != nil {
+		return apiError.StatusError{
+			Err: fmt.Errorf("failed to parse token expiration time - error is: %v", err),
+		}
+	}
+	ts.token = token
+	ts.expiry = expiry
+	return nil
+}
+
+// currentToken returns a copy of the tokens so that callers cannot change the stored ones
+func (ts *TokenSource) currentToken() *TokenResponse {
+	token := *ts.token
+	return &token
+}
@@ -0,0 +1,215 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var tokenSourceNow = time.Date(2023, 9, 27, 16, 30, 0, 0, time.UTC)
+
+// tokenAPIStub answers the tokens endpoints, counting the calls made to each
+type tokenAPIStub struct {
+	mu            sync.Mutex
+	signIns       int
+	refreshes     int
+	signOuts      int
+	refreshStatus int
+}
+
+func (s *tokenAPIStub) do(_ context.Context, req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Method + " " + req.URL.Path {
+	case http.MethodPost + " /tokens":
+		s.signIns++
+		resp := newResponse(http.StatusCreated, `{"expirationTime":"2023-09-27 17:30:00 +0000 UTC","refreshTokenExpirationTime":"2023-09-28 16:30:00 +0000 UTC"}`)
+		resp.Header = http.Header{"Authorization": {defaultAuthorization}, "Id": {defaultID}, "Refresh": {defaultRefreshToken}}
+		return resp, nil
+	case http.MethodPut + " /tokens/self":
+		s.refreshes++
+		if s.refreshStatus != 0 {
+			return newResponse(s.refreshStatus, `{"errors":[]}`), nil
+		}
+		resp := newResponse(http.StatusCreated, `{"expirationTime":"2023-09-27 18:30:00 +0000 UTC"}`)
+		resp.Header = http.Header{"Authorization": {"Bearer refreshedToken"}, "Id": {"refreshedID"}}
+		return resp, nil
+	case http.MethodDelete + " /tokens/self":
+		s.signOuts++
+		return &http.Response{StatusCode: http.StatusNoContent}, nil
+	}
+	return newResponse(http.StatusNotFound, ""), nil
+}
+
+func newTokenSourceWithStub(t *testing.T, stub *tokenAPIStub) (*TokenSource, *dphttp.ClienterMock) {
+	httpClient := newMockHTTPClient(nil, nil)
+	httpClient.DoFunc = stub.do
+	tokenSource := newIdentityAPIClient(t, httpClient).NewTokenSource(defaultCredentials)
+	tokenSource.now = func() time.Time { return tokenSourceNow }
+	return tokenSource, httpClient
+}
+
+func TestTokenSource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given a new token source", t, func() {
+		stub := &tokenAPIStub{}
+		tokenSource, httpClient := newTokenSourceWithStub(t, stub)
+
+		Convey("When Token is called several times before the access token is due to expire", func() {
+			first, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			second, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+
+			Convey("Then it signs in once and returns the same tokens", func() {
+				So(stub.signIns, ShouldEqual, 1)
+				So(stub.refreshes, ShouldEqual, 0)
+				So(first.Token, ShouldEqual, defaultAuthorization)
+				So(first.IDToken, ShouldEqual, defaultID)
+				So(second, ShouldResemble, first)
+			})
+		})
+
+		Convey("When Token is called within the refresh margin of the access token expiring", func() {
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			tokenSource.now = func() time.Time { return tokenSourceNow.Add(time.Hour - DefaultRefreshMargin/2) }
+			token, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+
+			Convey("Then the access token is refreshed using the refresh and ID tokens", func() {
+				So(stub.signIns, ShouldEqual, 1)
+				So(stub.refreshes, ShouldEqual, 1)
+				So(token.Token, ShouldEqual, "Bearer refreshedToken")
+				So(token.IDToken, ShouldEqual, "refreshedID")
+				So(token.RefreshToken, ShouldEqual, defaultRefreshToken)
+
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 2)
+				So(doCalls[1].Req.Header.Get("Refresh"), ShouldEqual, defaultRefreshToken)
+				So(doCalls[1].Req.Header.Get("ID"), ShouldEqual, defaultID)
+			})
+		})
+
+		Convey("When the refresh token has been revoked", func() {
+			stub.refreshStatus = http.StatusForbidden
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			tokenSource.now = func() time.Time { return tokenSourceNow.Add(time.Hour) }
+			token, err := tokenSource.Token(ctx)
+
+			Convey("Then it signs in again", func() {
+				So(err, ShouldBeNil)
+				So(stub.refreshes, ShouldEqual, 1)
+				So(stub.signIns, ShouldEqual, 2)
+				So(token.Token, ShouldEqual, defaultAuthorization)
+			})
+		})
+
+		Convey("When the refresh fails but the access token has not yet expired", func() {
+			stub.refreshStatus = http.StatusInternalServerError
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			tokenSource.now = func() time.Time { return tokenSourceNow.Add(time.Hour - DefaultRefreshMargin/2) }
+			token, err := tokenSource.Token(ctx)
+
+			Convey("Then the current access token is returned without signing in again", func() {
+				So(err, ShouldBeNil)
+				So(token.Token, ShouldEqual, defaultAuthorization)
+				So(stub.signIns, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the refresh fails after the access token has expired", func() {
+			stub.refreshStatus = http.StatusInternalServerError
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			tokenSource.now = func() time.Time { return tokenSourceNow.Add(2 * time.Hour) }
+			token, err := tokenSource.Token(ctx)
+
+			Convey("Then the error is returned", func() {
+				So(token, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+
+		Convey("When the refresh token has expired", func() {
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			tokenSource.now = func() time.Time { return tokenSourceNow.Add(25 * time.Hour) }
+			_, err = tokenSource.Token(ctx)
+
+			Convey("Then it signs in again without trying to refresh", func() {
+				So(err, ShouldBeNil)
+				So(stub.refreshes, ShouldEqual, 0)
+				So(stub.signIns, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When Token is called concurrently", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _ = tokenSource.Token(ctx)
+				}()
+			}
+			wg.Wait()
+
+			Convey("Then it signs in once", func() {
+				So(stub.signIns, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When AuthToken is used to authorise a request", func() {
+			opt, err := tokenSource.AuthToken(ctx)
+			So(err, ShouldBeNil)
+			req, _ := http.NewRequest(http.MethodGet, testHost, http.NoBody)
+			opt(req)
+
+			Convey("Then the access token is sent", func() {
+				So(req.Header.Get("Authorization"), ShouldEqual, defaultAuthorization)
+			})
+		})
+
+		Convey("When it is closed after signing in", func() {
+			_, err := tokenSource.Token(ctx)
+			So(err, ShouldBeNil)
+			err = tokenSource.Close(ctx)
+
+			Convey("Then the session is signed out with the access token", func() {
+				So(err, ShouldBeNil)
+				So(stub.signOuts, ShouldEqual, 1)
+				doCalls := httpClient.DoCalls()
+				So(doCalls[len(doCalls)-1].Req.Header.Get("Authorization"), ShouldEqual, defaultAuthorization)
+
+				Convey("And it cannot be used again", func() {
+					token, err := tokenSource.Token(ctx)
+					So(token, ShouldBeNil)
+					So(err, ShouldNotBeNil)
+					So(tokenSource.Close(ctx), ShouldBeNil)
+					So(stub.signOuts, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When it is closed without signing in", func() {
+			err := tokenSource.Close(ctx)
+
+			Convey("Then no sign out is made", func() {
+				So(err, ShouldBeNil)
+				So(stub.signOuts, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -12,6 +12,7 @@ import (
 
 type TokenResponse struct {
 	Token                      string `json:"-"`
+	IDToken                    string `json:"-"`
 	RefreshToken               string `json:"-"`
 	ExpirationTime             string `json:"expirationTime"`
 	RefreshTokenExpirationTime string `json:"refreshTokenExpirationTime"`
@@ -39,7 +40,43 @@ func (cli *Client) GetToken(ctx context.Context, credentials models.UserSignIn)
 	var headers = respInfo.Headers
 
 	tokenResponse.Token = headers.Get("Authorization")
+	tokenResponse.IDToken = headers.Get("ID")
 	tokenResponse.RefreshToken = headers.Get("Refresh")
 
 	return &tokenResponse, nil
 }
+
+// RefreshToken uses the refresh and ID tokens of a signed in session to get a new access token. The refresh token is
+// not replaced, so the returned response keeps the refresh token and its expiration time from the given token.
+func (cli *Client) RefreshToken(ctx context.Context, token TokenResponse) (*TokenResponse, apiError.Error) {
+	path := fmt.Sprintf("%s/tokens/self", cli.hcCli.URL)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPut, nil,
+		withHeader("Refresh", token.RefreshToken), withHeader("ID", token.IDToken))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var tokenResponse TokenResponse
+
+	if err := json.Unmarshal(respInfo.Body, &tokenResponse); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal tokenResponse - error is: %v", err),
+		}
+	}
+
+	tokenResponse.Token = respInfo.Headers.Get("Authorization")
+	tokenResponse.IDToken = respInfo.Headers.Get("ID")
+	tokenResponse.RefreshToken = token.RefreshToken
+	tokenResponse.RefreshTokenExpirationTime = token.RefreshTokenExpirationTime
+
+	return &tokenResponse, nil
+}
+
+// SignOut signs out the session of the given access token, invalidating its tokens
+func (cli *Client) SignOut(ctx context.Context, accessToken string) apiError.Error {
+	path := fmt.Sprintf("%s/tokens/self", cli.hcCli.URL)
+
+	_, apiErr := cli.callIdentityAPI(ctx, path, http.MethodDelete, nil, WithAuthToken(accessToken))
+	return apiErr
+}
@@ -48,6 +48,7 @@ func TestGetToken(t *testing.T) {
 			Convey("Then the expected identity token is returned", func() {
 				expectedTokenResponse := TokenResponse{
 					Token:                      defaultAuthorization,
+					IDToken:                    defaultID,
 					RefreshToken:               defaultRefreshToken,
 					ExpirationTime:             defaultExpirationTime,
 					RefreshTokenExpirationTime: defaultRefreshExpirationTime,
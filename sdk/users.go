@@ -0,0 +1,184 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
+)
+
+// UsersQuery holds the optional query parameters for listing users
+type UsersQuery struct {
+	// Active filters the users by whether they are enabled, all users are returned when nil
+	Active *bool
+	// Sort is a comma separated list of fields to sort by, e.g. "forename:asc,lastname:desc"
+	Sort string
+}
+
+// values returns the query string values for the users query
+func (q *UsersQuery) values() url.Values {
+	values := url.Values{}
+	if q == nil {
+		return values
+	}
+	if q.Active != nil {
+		values.Set("active", strconv.FormatBool(*q.Active))
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	return values
+}
+
+// CreateUser creates a new user, the user is sent a temporary password by email
+func (cli *Client) CreateUser(ctx context.Context, user models.UserParams, opts ...RequestOption) (*models.UserParams, apiError.Error) {
+	path := fmt.Sprintf("%s/users", cli.hcCli.URL)
+
+	b, _ := json.Marshal(user)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPost, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUser(respInfo.Body)
+}
+
+// GetUsers gets a list of users, optionally filtered by active status and sorted
+func (cli *Client) GetUsers(ctx context.Context, query *UsersQuery, opts ...RequestOption) (*models.UsersList, apiError.Error) {
+	path := fmt.Sprintf("%s/users", cli.hcCli.URL)
+	if values := query.values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUsersList(respInfo.Body)
+}
+
+// GetUser gets a single user by their ID
+func (cli *Client) GetUser(ctx context.Context, id string, opts ...RequestOption) (*models.UserParams, apiError.Error) {
+	path := fmt.Sprintf("%s/users/%s", cli.hcCli.URL, id)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUser(respInfo.Body)
+}
+
+// UpdateUser updates the details of the user with the given ID
+func (cli *Client) UpdateUser(ctx context.Context, id string, user models.UserParams, opts ...RequestOption) (*models.UserParams, apiError.Error) {
+	path := fmt.Sprintf("%s/users/%s", cli.hcCli.URL, id)
+
+	b, _ := json.Marshal(user)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPut, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return unmarshalUser(respInfo.Body)
+}
+
+// SetPassword sets a new temporary password for a user who has not yet changed their initial password
+func (cli *Client) SetPassword(ctx context.Context, id string, opts ...RequestOption) apiError.Error {
+	path := fmt.Sprintf("%s/users/%s/password", cli.hcCli.URL, id)
+
+	_, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPost, nil, opts...)
+	return apiErr
+}
+
+// GetUserGroups gets the groups the user with the given ID is a member of
+func (cli *Client) GetUserGroups(ctx context.Context, id string, opts ...RequestOption) (*models.ListUserGroups, apiError.Error) {
+	path := fmt.Sprintf("%s/users/%s/groups", cli.hcCli.URL, id)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var userGroups models.ListUserGroups
+
+	if err := json.Unmarshal(respInfo.Body, &userGroups); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal userGroups - error is: %v", err),
+		}
+	}
+
+	return &userGroups, nil
+}
+
+// RequestPasswordReset starts the forgotten password journey, emailing a verification code to the user
+func (cli *Client) RequestPasswordReset(ctx context.Context, email string, opts ...RequestOption) apiError.Error {
+	path := fmt.Sprintf("%s/password-reset", cli.hcCli.URL)
+
+	b, _ := json.Marshal(models.PasswordReset{Email: email})
+
+	_, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPost, b, opts...)
+	return apiErr
+}
+
+// ChangePassword changes the password of the signed in user. A NewPasswordRequired change signs the user in, so the
+// returned tokens are only populated for that change type
+func (cli *Client) ChangePassword(ctx context.Context, change models.ChangePassword, opts ...RequestOption) (*TokenResponse, apiError.Error) {
+	path := fmt.Sprintf("%s/users/self/password", cli.hcCli.URL)
+
+	b, _ := json.Marshal(change)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodPut, b, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var tokenResponse TokenResponse
+
+	if len(respInfo.Body) > 0 {
+		if err := json.Unmarshal(respInfo.Body, &tokenResponse); err != nil {
+			return nil, apiError.StatusError{
+				Err: fmt.Errorf("failed to unmarshal tokenResponse - error is: %v", err),
+			}
+		}
+	}
+
+	tokenResponse.Token = respInfo.Headers.Get("Authorization")
+	tokenResponse.IDToken = respInfo.Headers.Get("ID")
+	tokenResponse.RefreshToken = respInfo.Headers.Get("Refresh")
+
+	return &tokenResponse, nil
+}
+
+// unmarshalUser unmarshals a single user response body
+func unmarshalUser(body []byte) (*models.UserParams, apiError.Error) {
+	var user models.UserParams
+
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal user - error is: %v", err),
+		}
+	}
+
+	return &user, nil
+}
+
+// unmarshalUsersList unmarshals a list of users response body
+func unmarshalUsersList(body []byte) (*models.UsersList, apiError.Error) {
+	var usersList models.UsersList
+
+	if err := json.Unmarshal(body, &usersList); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal usersList - error is: %v", err),
+		}
+	}
+
+	return &usersList, nil
+}
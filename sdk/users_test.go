@@ -0,0 +1,319 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	usersEndpoint = "/users"
+	testUserID    = "abcd-1234"
+	testUserToken = "userToken1234"
+	userBody      = `{"id":"abcd-1234","forename":"Florence","lastname":"Roundabout","email":"florence@magicroundabout.org","groups":[],"status":"CONFIRMED","active":true,"status_notes":""}`
+)
+
+var expectedUser = models.UserParams{
+	ID:       testUserID,
+	Forename: "Florence",
+	Lastname: "Roundabout",
+	Email:    "florence@magicroundabout.org",
+	Groups:   []string{},
+	Status:   "CONFIRMED",
+	Active:   true,
+}
+
+func TestCreateUser(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	newUser := models.UserParams{Forename: "Florence", Lastname: "Roundabout", Email: "florence@magicroundabout.org"}
+
+	Convey("Given CreateUser will create a user successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusCreated, userBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When CreateUser is called with an auth token", func() {
+			user, err := identityAPIClient.CreateUser(ctx, newUser, WithAuthToken(testUserToken))
+			So(err, ShouldBeNil)
+
+			Convey("Then the created user is returned", func() {
+				So(*user, ShouldResemble, expectedUser)
+
+				Convey("And client.Do should be called once with the expected parameters", func() {
+					doCalls := httpClient.DoCalls()
+					So(doCalls, ShouldHaveLength, 1)
+					So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint)
+					So(doCalls[0].Req.Method, ShouldEqual, http.MethodPost)
+					So(doCalls[0].Req.Header.Get("Authorization"), ShouldEqual, "Bearer "+testUserToken)
+					expectedBody, _ := json.Marshal(newUser)
+					actualBody, _ := io.ReadAll(doCalls[0].Req.Body)
+					So(actualBody, ShouldResemble, expectedBody)
+				})
+			})
+		})
+	})
+
+	Convey("Given CreateUser returns a bad request", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusBadRequest, `{"errors":[]}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When CreateUser is called", func() {
+			user, err := identityAPIClient.CreateUser(ctx, newUser)
+
+			Convey("Then the error status is returned and no user", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusBadRequest)
+				So(user, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestGetUsers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	usersBody := `{"count":1,"users":[` + userBody + `]}`
+
+	Convey("Given GetUsers will return users successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, usersBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetUsers is called with an active filter and sort", func() {
+			active := true
+			usersList, err := identityAPIClient.GetUsers(ctx, &UsersQuery{Active: &active, Sort: "forename:asc"})
+			So(err, ShouldBeNil)
+
+			Convey("Then the users are returned", func() {
+				So(usersList.Count, ShouldEqual, 1)
+				So(usersList.Users, ShouldResemble, []models.UserParams{expectedUser})
+
+				Convey("And the query parameters are sent", func() {
+					doCalls := httpClient.DoCalls()
+					So(doCalls, ShouldHaveLength, 1)
+					So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint)
+					So(doCalls[0].Req.Method, ShouldEqual, http.MethodGet)
+					So(doCalls[0].Req.URL.Query().Get("active"), ShouldEqual, "true")
+					So(doCalls[0].Req.URL.Query().Get("sort"), ShouldEqual, "forename:asc")
+				})
+			})
+		})
+
+		Convey("When GetUsers is called without a query", func() {
+			_, err := identityAPIClient.GetUsers(ctx, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then no query parameters are sent", func() {
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.RawQuery, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given GetUser will return a user successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, userBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetUser is called", func() {
+			user, err := identityAPIClient.GetUser(ctx, testUserID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the user is returned", func() {
+				So(*user, ShouldResemble, expectedUser)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint+"/"+testUserID)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodGet)
+			})
+		})
+	})
+
+	Convey("Given GetUser returns not found", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusNotFound, `{"errors":[]}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetUser is called", func() {
+			user, err := identityAPIClient.GetUser(ctx, testUserID)
+
+			Convey("Then the not found status is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusNotFound)
+				So(user, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given UpdateUser will update a user successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, userBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When UpdateUser is called", func() {
+			user, err := identityAPIClient.UpdateUser(ctx, testUserID, expectedUser)
+			So(err, ShouldBeNil)
+
+			Convey("Then the updated user is returned", func() {
+				So(*user, ShouldResemble, expectedUser)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint+"/"+testUserID)
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPut)
+			})
+		})
+	})
+}
+
+func TestSetPassword(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given SetPassword is accepted", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusAccepted, ""), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When SetPassword is called", func() {
+			err := identityAPIClient.SetPassword(ctx, testUserID)
+
+			Convey("Then no error is returned and the password endpoint is called", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint+"/"+testUserID+"/password")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPost)
+			})
+		})
+	})
+
+	Convey("Given SetPassword is forbidden", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusForbidden, `{"errors":[]}`), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When SetPassword is called", func() {
+			err := identityAPIClient.SetPassword(ctx, testUserID)
+
+			Convey("Then the forbidden status is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Status(), ShouldEqual, http.StatusForbidden)
+			})
+		})
+	})
+}
+
+func TestGetUserGroups(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	userGroupsBody := `{"groups":[{"id":"group-a","name":"Group A","precedence":10}],"next_token":null,"count":1}`
+
+	Convey("Given GetUserGroups will return groups successfully", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusOK, userGroupsBody), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When GetUserGroups is called", func() {
+			userGroups, err := identityAPIClient.GetUserGroups(ctx, testUserID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the user's groups are returned", func() {
+				So(userGroups.Count, ShouldEqual, 1)
+				So(userGroups.Groups, ShouldHaveLength, 1)
+				So(*userGroups.Groups[0].ID, ShouldEqual, "group-a")
+				So(*userGroups.Groups[0].Precedence, ShouldEqual, 10)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint+"/"+testUserID+"/groups")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodGet)
+			})
+		})
+	})
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	Convey("Given RequestPasswordReset is accepted", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusAccepted, ""), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When RequestPasswordReset is called", func() {
+			err := identityAPIClient.RequestPasswordReset(ctx, defaultCredentials.Email)
+
+			Convey("Then no error is returned and the email is sent", func() {
+				So(err, ShouldBeNil)
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, "/password-reset")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPost)
+				actualBody, _ := io.ReadAll(doCalls[0].Req.Body)
+				So(string(actualBody), ShouldEqual, `{"email":"`+defaultCredentials.Email+`"}`)
+			})
+		})
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	change := models.ChangePassword{
+		ChangeType:  models.NewPasswordRequiredType,
+		Session:     "session1234",
+		Email:       defaultCredentials.Email,
+		NewPassword: "aNewPassword1234!",
+	}
+
+	Convey("Given ChangePassword signs the user in", t, func() {
+		body := `{"expirationTime":"` + defaultExpirationTime + `","refreshTokenExpirationTime":"` + defaultRefreshExpirationTime + `"}`
+		resp := newResponse(http.StatusAccepted, body)
+		resp.Header = http.Header{
+			"Authorization": {defaultAuthorization},
+			"Refresh":       {defaultRefreshToken},
+		}
+		httpClient := newMockHTTPClient(resp, nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When ChangePassword is called", func() {
+			tokenResponse, err := identityAPIClient.ChangePassword(ctx, change)
+			So(err, ShouldBeNil)
+
+			Convey("Then the tokens are returned", func() {
+				So(*tokenResponse, ShouldResemble, TokenResponse{
+					Token:                      defaultAuthorization,
+					RefreshToken:               defaultRefreshToken,
+					ExpirationTime:             defaultExpirationTime,
+					RefreshTokenExpirationTime: defaultRefreshExpirationTime,
+				})
+				doCalls := httpClient.DoCalls()
+				So(doCalls, ShouldHaveLength, 1)
+				So(doCalls[0].Req.URL.Path, ShouldEqual, usersEndpoint+"/self/password")
+				So(doCalls[0].Req.Method, ShouldEqual, http.MethodPut)
+			})
+		})
+	})
+
+	Convey("Given ChangePassword is accepted without a response body", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusAccepted, ""), nil)
+		identityAPIClient := newIdentityAPIClient(t, httpClient)
+
+		Convey("When ChangePassword is called", func() {
+			tokenResponse, err := identityAPIClient.ChangePassword(ctx, change)
+
+			Convey("Then no error is returned and the tokens are empty", func() {
+				So(err, ShouldBeNil)
+				So(*tokenResponse, ShouldResemble, TokenResponse{})
+			})
+		})
+	})
+}
@@ -0,0 +1,254 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// TokenUseAccess is the token_use claim of an access token
+	TokenUseAccess = "access"
+	// TokenUseID is the token_use claim of an ID token
+	TokenUseID = "id"
+
+	// DefaultKeyCacheDuration is how long a Verifier uses the signing keys before fetching them again
+	DefaultKeyCacheDuration = time.Hour
+
+	// keyRefetchInterval limits how often the signing keys are fetched again because a token names an unknown key
+	keyRefetchInterval = time.Minute
+)
+
+// Claims are the claims of a verified access or ID token
+type Claims struct {
+	TokenUse        string   `json:"token_use"`
+	Username        string   `json:"username,omitempty"`
+	CognitoUsername string   `json:"cognito:username,omitempty"`
+	Groups          []string `json:"cognito:groups,omitempty"`
+	Email           string   `json:"email,omitempty"`
+	ClientID        string   `json:"client_id,omitempty"`
+	Scope           string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// verifiedClaimsKey is the request context key of the claims added by the Verifier middleware
+type verifiedClaimsKey struct{}
+
+// ClaimsFromContext returns the claims of the access token verified by the Verifier middleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(verifiedClaimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// GetJWTKeys gets the public keys that sign the user pool's tokens, as base64 encoded DER keys mapped by key ID
+func (cli *Client) GetJWTKeys(ctx context.Context, opts ...RequestOption) (map[string]string, apiError.Error) {
+	path := fmt.Sprintf("%s/jwt-keys", cli.hcCli.URL)
+
+	respInfo, apiErr := cli.callIdentityAPI(ctx, path, http.MethodGet, nil, opts...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var keys map[string]string
+
+	if err := json.Unmarshal(respInfo.Body, &keys); err != nil {
+		return nil, apiError.StatusError{
+			Err: fmt.Errorf("failed to unmarshal keys - error is: %v", err),
+		}
+	}
+
+	return keys, nil
+}
+
+// Verifier verifies access and ID tokens locally using the signing keys from the identity API, which are cached
+// rather than fetched for every token. It is safe for concurrent use.
+type Verifier struct {
+	client        *Client
+	issuer        string
+	cacheDuration time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// NewVerifier creates a Verifier for tokens issued by the given issuer, which is the URL of the user pool, e.g.
+// https://cognito-idp.eu-west-2.amazonaws.com/eu-west-2_example
+func (cli *Client) NewVerifier(issuer string) *Verifier {
+	return &Verifier{
+		client:        cli,
+		issuer:        issuer,
+		cacheDuration: DefaultKeyCacheDuration,
+		now:           time.Now,
+	}
+}
+
+// VerifyAccessToken verifies an access token, with or without its Bearer prefix, and returns its claims
+func (v *Verifier) VerifyAccessToken(ctx context.Context, token string) (*Claims, apiError.Error) {
+	return v.verify(ctx, token, TokenUseAccess)
+}
+
+// VerifyIDToken verifies an ID token and returns its claims
+func (v *Verifier) VerifyIDToken(ctx context.Context, token string) (*Claims, apiError.Error) {
+	return v.verify(ctx, token, TokenUseID)
+}
+
+// Middleware verifies the access token in the Authorization header of each request, adding its claims to the request
+// context for ClaimsFromContext. Requests without a valid access token are refused with a 401.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := req.Header.Get("Authorization")
+		if token == "" {
+			writeVerifierError(w, http.StatusUnauthorized, models.InvalidTokenError, models.MissingAuthorizationTokenDescription)
+			return
+		}
+
+		claims, apiErr := v.VerifyAccessToken(req.Context(), token)
+		if apiErr != nil {
+			if apiErr.Status() != http.StatusUnauthorized {
+				// the signing keys could not be fetched, so the token could not be checked either way
+				writeVerifierError(w, http.StatusInternalServerError, models.InternalError, models.InternalErrorDescription)
+				return
+			}
+			writeVerifierError(w, http.StatusUnauthorized, models.InvalidTokenError, models.InvalidTokenDescription)
+			return
+		}
+
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), verifiedClaimsKey{}, claims)))
+	})
+}
+
+// verify checks the token's signature, expiry, issuer and use
+func (v *Verifier) verify(ctx context.Context, token, tokenUse string) (*Claims, apiError.Error) {
+	token = strings.TrimPrefix(token, "Bearer ")
+
+	// the time based claims are checked below against the verifier's clock
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithoutClaimsValidation())
+
+	var keyErr apiError.Error
+	claims := &Claims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, apiErr := v.key(ctx, kid)
+		if apiErr != nil {
+			keyErr = apiErr
+			return nil, apiErr
+		}
+		return key, nil
+	})
+	if keyErr != nil && keyErr.Status() != http.StatusUnauthorized {
+		// the keys could not be fetched, which says nothing about the token itself
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, invalidToken(err)
+	}
+
+	switch {
+	case claims.ExpiresAt == nil || !v.now().Before(claims.ExpiresAt.Time):
+		return nil, invalidToken(errors.New("token has expired"))
+	case claims.Issuer != v.issuer:
+		return nil, invalidToken(fmt.Errorf("unexpected token issuer %q", claims.Issuer))
+	case claims.TokenUse != tokenUse:
+		return nil, invalidToken(fmt.Errorf("unexpected token_use %q", claims.TokenUse))
+	}
+
+	return claims, nil
+}
+
+// key returns the signing key with the given ID, fetching the keys when the cached ones are out of date or the key is
+// unknown, as the user pool may have rotated its keys
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, apiError.Error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, ok := v.keys[kid]
+	stale := v.keys == nil || now.Sub(v.fetched) >= v.cacheDuration
+	if stale || (!ok && now.Sub(v.fetched) >= keyRefetchInterval) {
+		apiErr := v.fetchKeys(ctx)
+		if apiErr != nil && !ok {
+			return nil, apiErr
+		}
+		if apiErr == nil {
+			key, ok = v.keys[kid]
+		}
+	}
+
+	if !ok {
+		return nil, invalidToken(fmt.Errorf("unknown signing key %q", kid))
+	}
+	return key, nil
+}
+
+// fetchKeys replaces the cached signing keys with the ones from the identity API
+func (v *Verifier) fetchKeys(ctx context.Context) apiError.Error {
+	encodedKeys, apiErr := v.client.GetJWTKeys(ctx)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(encodedKeys))
+	for kid, encodedKey := range encodedKeys {
+		key, err := parseRSAPublicKey(encodedKey)
+		if err != nil {
+			return apiError.StatusError{
+				Err: fmt.Errorf("failed to parse signing key %q - error is: %v", kid, err),
+			}
+		}
+		keys[kid] = key
+	}
+
+	v.keys = keys
+	v.fetched = v.now()
+	return nil
+}
+
+// parseRSAPublicKey parses a base64 encoded DER public key as returned by the jwt-keys endpoint
+func parseRSAPublicKey(encodedKey string) (*rsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaKey, nil
+}
+
+// invalidToken returns the error for a token that failed verification
+func invalidToken(err error) apiError.Error {
+	return apiError.StatusError{
+		Err:    fmt.Errorf("failed to verify token - error is: %v", err),
+		Code:   http.StatusUnauthorized,
+		Errors: []apiError.APIError{{Code: models.InvalidTokenError, Description: models.InvalidTokenDescription}},
+	}
+}
+
+// writeVerifierError writes an error response in the identity API's error format
+func writeVerifierError(w http.ResponseWriter, status int, code, description string) {
+	body, _ := json.Marshal(models.ErrorResponse{
+		Errors: []error{&models.Error{Code: code, Description: description}},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,277 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiError "github.com/ONSdigital/dp-identity-api/v2/sdk/errors"
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	testIssuer = "https://cognito-idp.eu-west-2.amazonaws.com/eu-west-2_example"
+	testKeyID  = "test-key-1"
+)
+
+var verifierNow = time.Date(2024, 5, 13, 12, 0, 0, 0, time.UTC)
+
+func newTestSigningKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keysBody, _ := json.Marshal(map[string]string{testKeyID: base64.StdEncoding.EncodeToString(der)})
+	return key, string(keysBody)
+}
+
+func newTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestClaims(tokenUse string) Claims {
+	return Claims{
+		TokenUse: tokenUse,
+		Username: "florence",
+		Groups:   []string{"role-admin", "group-a"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "abcd-1234",
+			Issuer:    testIssuer,
+			ExpiresAt: jwt.NewNumericDate(verifierNow.Add(2 * time.Hour)),
+		},
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	signingKey, keysBody := newTestSigningKey(t)
+
+	Convey("Given a verifier for the user pool", t, func() {
+		httpClient := newMockHTTPClient(nil, nil)
+		httpClient.DoFunc = func(_ context.Context, _ *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusOK, keysBody), nil
+		}
+		verifier := newIdentityAPIClient(t, httpClient).NewVerifier(testIssuer)
+		verifier.now = func() time.Time { return verifierNow }
+
+		Convey("When a valid access token is verified", func() {
+			token := newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseAccess))
+			claims, err := verifier.VerifyAccessToken(ctx, "Bearer "+token)
+
+			Convey("Then its claims are returned including the groups", func() {
+				So(err, ShouldBeNil)
+				So(claims.Subject, ShouldEqual, "abcd-1234")
+				So(claims.Username, ShouldEqual, "florence")
+				So(claims.Groups, ShouldResemble, []string{"role-admin", "group-a"})
+
+				Convey("And the keys are fetched from the jwt-keys endpoint", func() {
+					doCalls := httpClient.DoCalls()
+					So(doCalls, ShouldHaveLength, 1)
+					So(doCalls[0].Req.URL.Path, ShouldEqual, "/jwt-keys")
+				})
+			})
+
+			Convey("And another token is verified", func() {
+				_, err = verifier.VerifyAccessToken(ctx, token)
+				So(err, ShouldBeNil)
+
+				Convey("Then the cached keys are used", func() {
+					So(httpClient.DoCalls(), ShouldHaveLength, 1)
+				})
+			})
+
+			Convey("And a token is verified after the keys are due to be fetched again", func() {
+				verifier.now = func() time.Time { return verifierNow.Add(DefaultKeyCacheDuration) }
+				_, err = verifier.VerifyAccessToken(ctx, token)
+				So(err, ShouldBeNil)
+
+				Convey("Then the keys are fetched again", func() {
+					So(httpClient.DoCalls(), ShouldHaveLength, 2)
+				})
+			})
+		})
+
+		Convey("When a valid ID token is verified", func() {
+			token := newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseID))
+			claims, err := verifier.VerifyIDToken(ctx, token)
+
+			Convey("Then its claims are returned", func() {
+				So(err, ShouldBeNil)
+				So(claims.TokenUse, ShouldEqual, TokenUseID)
+			})
+		})
+
+		Convey("When an ID token is verified as an access token", func() {
+			token := newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseID))
+			claims, err := verifier.VerifyAccessToken(ctx, token)
+
+			Convey("Then it is rejected as unauthorised", func() {
+				So(claims, ShouldBeNil)
+				So(err.Status(), ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("When an expired token is verified", func() {
+			expiredClaims := newTestClaims(TokenUseAccess)
+			expiredClaims.ExpiresAt = jwt.NewNumericDate(verifierNow.Add(-time.Minute))
+			claims, err := verifier.VerifyAccessToken(ctx, newTestToken(t, signingKey, testKeyID, expiredClaims))
+
+			Convey("Then it is rejected as unauthorised", func() {
+				So(claims, ShouldBeNil)
+				So(err.Status(), ShouldEqual, http.StatusUnauthorized)
+				So(errors.Is(err, apiError.ErrInvalidToken), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a token from another issuer is verified", func() {
+			otherClaims := newTestClaims(TokenUseAccess)
+			otherClaims.Issuer = "https://cognito-idp.eu-west-2.amazonaws.com/eu-west-2_other"
+			claims, err := verifier.VerifyAccessToken(ctx, newTestToken(t, signingKey, testKeyID, otherClaims))
+
+			Convey("Then it is rejected as unauthorised", func() {
+				So(claims, ShouldBeNil)
+				So(err.Status(), ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("When a token signed by another key is verified", func() {
+			otherKey, _ := newTestSigningKey(t)
+			claims, err := verifier.VerifyAccessToken(ctx, newTestToken(t, otherKey, testKeyID, newTestClaims(TokenUseAccess)))
+
+			Convey("Then it is rejected as unauthorised", func() {
+				So(claims, ShouldBeNil)
+				So(err.Status(), ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("When tokens naming an unknown key are verified", func() {
+			token := newTestToken(t, signingKey, "unknown-key", newTestClaims(TokenUseAccess))
+			_, firstErr := verifier.VerifyAccessToken(ctx, token)
+			_, secondErr := verifier.VerifyAccessToken(ctx, token)
+
+			Convey("Then they are rejected without fetching the keys for every token", func() {
+				So(firstErr.Status(), ShouldEqual, http.StatusUnauthorized)
+				So(secondErr.Status(), ShouldEqual, http.StatusUnauthorized)
+				So(httpClient.DoCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given the jwt-keys endpoint fails", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusNotFound, `{"errors":[]}`), nil)
+		verifier := newIdentityAPIClient(t, httpClient).NewVerifier(testIssuer)
+		verifier.now = func() time.Time { return verifierNow }
+
+		Convey("When a token is verified", func() {
+			claims, err := verifier.VerifyAccessToken(ctx, newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseAccess)))
+
+			Convey("Then the error from fetching the keys is returned", func() {
+				So(claims, ShouldBeNil)
+				So(err.Status(), ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func TestVerifierMiddleware(t *testing.T) {
+	t.Parallel()
+	signingKey, keysBody := newTestSigningKey(t)
+
+	Convey("Given a handler wrapped by the verifier middleware", t, func() {
+		httpClient := newMockHTTPClient(nil, nil)
+		httpClient.DoFunc = func(_ context.Context, _ *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusOK, keysBody), nil
+		}
+		verifier := newIdentityAPIClient(t, httpClient).NewVerifier(testIssuer)
+		verifier.now = func() time.Time { return verifierNow }
+
+		var verifiedClaims *Claims
+		handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			verifiedClaims, _ = ClaimsFromContext(req.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		Convey("When a request with a valid access token is made", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseAccess)))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then the handler is called with the verified claims in the context", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(verifiedClaims, ShouldNotBeNil)
+				So(verifiedClaims.Subject, ShouldEqual, "abcd-1234")
+			})
+		})
+
+		Convey("When a request without a token is made", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then it is refused as unauthorised", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+				So(w.Body.String(), ShouldContainSubstring, `"code":"InvalidToken"`)
+				So(verifiedClaims, ShouldBeNil)
+			})
+		})
+
+		Convey("When a request with an invalid token is made", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer not.a.token")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then it is refused as unauthorised", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+				So(verifiedClaims, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestVerifierMiddlewareKeysUnavailable(t *testing.T) {
+	t.Parallel()
+	signingKey, _ := newTestSigningKey(t)
+
+	Convey("Given the jwt-keys endpoint fails", t, func() {
+		httpClient := newMockHTTPClient(newResponse(http.StatusInternalServerError, `{"errors":[]}`), nil)
+		verifier := newIdentityAPIClient(t, httpClient).NewVerifier(testIssuer)
+		verifier.now = func() time.Time { return verifierNow }
+		handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		Convey("When a request with an access token is made", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, signingKey, testKeyID, newTestClaims(TokenUseAccess)))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Convey("Then it fails with an internal server error rather than as unauthorised", func() {
+				So(w.Code, ShouldEqual, http.StatusInternalServerError)
+				So(w.Body.String(), ShouldContainSubstring, `"code":"InternalServerError"`)
+			})
+		})
+	})
+}
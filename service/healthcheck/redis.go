@@ -0,0 +1,31 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const RedisHealthy = "Redis Healthy"
+
+// RedisHealthCheck checks that the Redis instance holding the shared stores can be reached
+func RedisHealthCheck(redisClient redis.UniversalClient) health.Checker {
+	return func(ctx context.Context, state *health.CheckState) error {
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			if stateErr := state.Update(health.StatusCritical, err.Error(), http.StatusServiceUnavailable); stateErr != nil {
+				log.Error(ctx, "Error updating state during identity service healthcheck", stateErr)
+			}
+			log.Error(ctx, "Error running identity service redis healthcheck", err)
+			return err
+		}
+
+		if stateErr := state.Update(health.StatusOK, RedisHealthy, http.StatusOK); stateErr != nil {
+			log.Error(ctx, "Error updating state during identity service healthcheck", stateErr)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package healthcheck_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-identity-api/v2/service/healthcheck"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedisHealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a Redis instance", t, func() {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		defer client.Close()
+		checkState := health.NewCheckState("dp-identity-api-test")
+
+		Convey("When it can be reached the healthchecker reports healthy", func() {
+			err := healthcheck.RedisHealthCheck(client)(ctx, checkState)
+			So(err, ShouldBeNil)
+			So(checkState.StatusCode(), ShouldEqual, http.StatusOK)
+			So(checkState.Status(), ShouldEqual, health.StatusOK)
+			So(checkState.Message(), ShouldEqual, healthcheck.RedisHealthy)
+		})
+
+		Convey("When it cannot be reached the healthchecker reports critical", func() {
+			server.Close()
+
+			err := healthcheck.RedisHealthCheck(client)(ctx, checkState)
+			So(err, ShouldNotBeNil)
+			So(checkState.StatusCode(), ShouldEqual, http.StatusServiceUnavailable)
+			So(checkState.Status(), ShouldEqual, health.StatusCritical)
+		})
+	})
+}
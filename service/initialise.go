@@ -13,12 +13,14 @@ import (
 	"github.com/ONSdigital/log.go/v2/log"
 	sdkcfg "github.com/aws/aws-sdk-go-v2/config"
 	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/redis/go-redis/v9"
 )
 
 // ExternalServiceList holds the initialiser and initialisation state of external services.
 type ExternalServiceList struct {
 	AuthMiddleware bool
 	HealthCheck    bool
+	Redis          bool
 	Init           Initialiser
 }
 
@@ -27,6 +29,7 @@ func NewServiceList(initialiser Initialiser) *ExternalServiceList {
 	return &ExternalServiceList{
 		AuthMiddleware: false,
 		HealthCheck:    false,
+		Redis:          false,
 		Init:           initialiser,
 	}
 }
@@ -66,6 +69,13 @@ func (e *ExternalServiceList) GetAuthorisationMiddleware(ctx context.Context, au
 	return am, nil
 }
 
+// GetRedisClient creates a client for the Redis instance holding the shared stores and sets the Redis flag to true
+func (e *ExternalServiceList) GetRedisClient(cfg *config.Config) redis.UniversalClient {
+	client := e.Init.DoGetRedisClient(cfg)
+	e.Redis = true
+	return client
+}
+
 // DoGetHTTPServer creates an HTTP Server with the provided bind address and router
 func (e *Init) DoGetHTTPServer(bindAddr string, router http.Handler, cfg *config.Config) HTTPServer {
 	s := dphttp.NewServer(bindAddr, router)
@@ -106,3 +116,12 @@ func (e *Init) DoGetCognitoClient(ctx context.Context, awsRegion string) cognito
 func (e *Init) DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
 	return authorisation.NewFeatureFlaggedMiddleware(ctx, authorisationConfig, authorisationConfig.JWTVerificationPublicKeys)
 }
+
+// DoGetRedisClient creates a client for the configured Redis instance, which connects when first used
+func (e *Init) DoGetRedisClient(cfg *config.Config) redis.UniversalClient {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddress,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDatabase,
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/dp-identity-api/v2/config"
+	"github.com/redis/go-redis/v9"
 )
 
 //go:generate moq -out mock/initialiser.go -pkg mock . Initialiser
@@ -22,6 +23,7 @@ type Initialiser interface {
 	DoGetHealthCheck(cfg *config.Config, buildTime, gitCommit, version string) (HealthChecker, error)
 	DoGetCognitoClient(ctx context.Context, awsRegion string) cognitoclient.Client
 	DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error)
+	DoGetRedisClient(cfg *config.Config) redis.UniversalClient
 }
 
 // HTTPServer defines the required methods from the HTTP server
@@ -12,6 +12,7 @@ import (
 	cognitoClient "github.com/ONSdigital/dp-identity-api/v2/cognito"
 	"github.com/ONSdigital/dp-identity-api/v2/config"
 	"github.com/ONSdigital/dp-identity-api/v2/service"
+	"github.com/redis/go-redis/v9"
 )
 
 // Ensure, that InitialiserMock does implement service.Initialiser.
@@ -36,6 +37,9 @@ var _ service.Initialiser = &InitialiserMock{}
 //			DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 //				panic("mock out the DoGetHealthCheck method")
 //			},
+//			DoGetRedisClientFunc: func(cfg *config.Config) redis.UniversalClient {
+//				panic("mock out the DoGetRedisClient method")
+//			},
 //		}
 //
 //		// use mockedInitialiser in code that requires service.Initialiser
@@ -55,6 +59,9 @@ type InitialiserMock struct {
 	// DoGetHealthCheckFunc mocks the DoGetHealthCheck method.
 	DoGetHealthCheckFunc func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error)
 
+	// DoGetRedisClientFunc mocks the DoGetRedisClient method.
+	DoGetRedisClientFunc func(cfg *config.Config) redis.UniversalClient
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// DoGetAuthorisationMiddleware holds details about calls to the DoGetAuthorisationMiddleware method.
@@ -91,11 +98,17 @@ type InitialiserMock struct {
 			// Version is the version argument value.
 			Version string
 		}
+		// DoGetRedisClient holds details about calls to the DoGetRedisClient method.
+		DoGetRedisClient []struct {
+			// Cfg is the cfg argument value.
+			Cfg *config.Config
+		}
 	}
 	lockDoGetAuthorisationMiddleware sync.RWMutex
 	lockDoGetCognitoClient           sync.RWMutex
 	lockDoGetHTTPServer              sync.RWMutex
 	lockDoGetHealthCheck             sync.RWMutex
+	lockDoGetRedisClient             sync.RWMutex
 }
 
 // DoGetAuthorisationMiddleware calls DoGetAuthorisationMiddlewareFunc.
@@ -253,3 +266,35 @@ func (mock *InitialiserMock) DoGetHealthCheckCalls() []struct {
 	mock.lockDoGetHealthCheck.RUnlock()
 	return calls
 }
+
+// DoGetRedisClient calls DoGetRedisClientFunc.
+func (mock *InitialiserMock) DoGetRedisClient(cfg *config.Config) redis.UniversalClient {
+	if mock.DoGetRedisClientFunc == nil {
+		panic("InitialiserMock.DoGetRedisClientFunc: method is nil but Initialiser.DoGetRedisClient was just called")
+	}
+	callInfo := struct {
+		Cfg *config.Config
+	}{
+		Cfg: cfg,
+	}
+	mock.lockDoGetRedisClient.Lock()
+	mock.calls.DoGetRedisClient = append(mock.calls.DoGetRedisClient, callInfo)
+	mock.lockDoGetRedisClient.Unlock()
+	return mock.DoGetRedisClientFunc(cfg)
+}
+
+// DoGetRedisClientCalls gets all the calls that were made to DoGetRedisClient.
+// Check the length with:
+//
+//	len(mockedInitialiser.DoGetRedisClientCalls())
+func (mock *InitialiserMock) DoGetRedisClientCalls() []struct {
+	Cfg *config.Config
+} {
+	var calls []struct {
+		Cfg *config.Config
+	}
+	mock.lockDoGetRedisClient.RLock()
+	calls = mock.calls.DoGetRedisClient
+	mock.lockDoGetRedisClient.RUnlock()
+	return calls
+}
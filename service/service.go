@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"os"
+	"time"
 
 	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-identity-api/v2/api"
@@ -9,11 +11,18 @@ import (
 	"github.com/ONSdigital/dp-identity-api/v2/config"
 	"github.com/ONSdigital/dp-identity-api/v2/jwks"
 	health "github.com/ONSdigital/dp-identity-api/v2/service/healthcheck"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
+	"github.com/ONSdigital/dp-identity-api/v2/validation"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 )
 
+// idempotencyInProgressMargin is added to the HTTP write timeout to give how long an idempotency key is held for a
+// request in progress, allowing for the time taken to save its response
+const idempotencyInProgressMargin = 30 * time.Second
+
 // Service contains all the configs, server and clients to run the dp-identity-api API
 type Service struct {
 	Config                  *config.Config
@@ -23,6 +32,8 @@ type Service struct {
 	ServiceList             *ExternalServiceList
 	HealthCheck             HealthChecker
 	authorisationMiddleware authorisation.Middleware
+	redisClient             redis.UniversalClient
+	stopReconciler          context.CancelFunc
 }
 
 // Run the service
@@ -48,19 +59,81 @@ func Run(ctx context.Context, cfg *config.Config, serviceList *ExternalServiceLi
 		return nil, err
 	}
 
+	a.PasswordPolicy, err = getPasswordPolicy(cfg)
+	if err != nil {
+		log.Fatal(ctx, "could not load password policy", err)
+		return nil, err
+	}
+
+	a.PasswordHistory, err = getPasswordHistory(cfg, client)
+	if err != nil {
+		log.Fatal(ctx, "could not set up password history", err)
+		return nil, err
+	}
+
+	var redisClient redis.UniversalClient
+	if cfg.DataStore == "redis" {
+		if cfg.RedisAddress == "" {
+			err := errors.New("REDIS_ADDRESS must be set to use the redis data store")
+			log.Fatal(ctx, "could not set up data stores", err)
+			return nil, err
+		}
+		redisClient = serviceList.GetRedisClient(cfg)
+	}
+
+	if err := setDataStores(a, cfg, redisClient); err != nil {
+		log.Fatal(ctx, "could not set up data stores", err)
+		return nil, err
+	}
+
+	if cfg.RateLimitEnabled {
+		a.RateLimits = api.RateLimits{
+			IP:             store.RateLimit{Burst: cfg.RateLimitIPBurst, Interval: cfg.RateLimitIPInterval},
+			Email:          store.RateLimit{Burst: cfg.RateLimitEmailBurst, Interval: cfg.RateLimitEmailInterval},
+			TrustedProxies: cfg.RateLimitTrustedProxies,
+		}
+	}
+
+	if cfg.IdempotencyKeyWindow > 0 {
+		a.IdempotencyStore = store.NewInMemoryIdempotencyStore()
+		if redisClient != nil {
+			a.IdempotencyStore = store.NewRedisIdempotencyStore(redisClient)
+		}
+		a.IdempotencyWindow = cfg.IdempotencyKeyWindow
+		// a request in progress cannot take longer than the server allows it to write its response
+		if cfg.HTTPWriteTimeout != nil && *cfg.HTTPWriteTimeout > 0 {
+			a.IdempotencyInProgressTTL = *cfg.HTTPWriteTimeout + idempotencyInProgressMargin
+		}
+	}
+
+	if cfg.GroupsReportConcurrency > 0 {
+		a.GroupsReportConcurrency = cfg.GroupsReportConcurrency
+	}
+
+	a.AntiEnumeration = api.AntiEnumeration{
+		Enabled:         cfg.AntiEnumerationEnabled,
+		MinResponseTime: cfg.AntiEnumerationMinTime,
+	}
+
 	hc, err := serviceList.GetHealthCheck(cfg, buildTime, gitCommit, version)
 	if err != nil {
 		log.Fatal(ctx, "could not instantiate healthcheck", err)
 		return nil, err
 	}
 
-	if err := registerCheckers(ctx, hc, client, &cfg.AWSCognitoUserPoolID, authorisationMiddleware); err != nil {
+	if err := registerCheckers(ctx, hc, client, &cfg.AWSCognitoUserPoolID, authorisationMiddleware, redisClient); err != nil {
 		return nil, errors.Wrap(err, "unable to register checkers")
 	}
 
 	r.StrictSlash(true).Path("/health").HandlerFunc(hc.Handler)
 	hc.Start(ctx)
 
+	// remove temporary group memberships once they expire, until the service is closed
+	reconcilerCtx, stopReconciler := context.WithCancel(context.Background())
+	if cfg.MembershipExpiryInterval > 0 {
+		go a.ReconcileMembershipExpiry(reconcilerCtx, cfg.MembershipExpiryInterval)
+	}
+
 	// Run the http server in a new go-routine
 	go func() {
 		if err := s.ListenAndServe(); err != nil {
@@ -76,6 +149,8 @@ func Run(ctx context.Context, cfg *config.Config, serviceList *ExternalServiceLi
 		ServiceList:             serviceList,
 		Server:                  s,
 		authorisationMiddleware: authorisationMiddleware,
+		redisClient:             redisClient,
+		stopReconciler:          stopReconciler,
 	}, nil
 }
 
@@ -96,6 +171,10 @@ func (svc *Service) Close(ctx context.Context) error {
 			svc.HealthCheck.Stop()
 		}
 
+		if svc.stopReconciler != nil {
+			svc.stopReconciler()
+		}
+
 		// stop any incoming requests before closing any outbound connections
 		if err := svc.Server.Shutdown(ctx); err != nil {
 			log.Error(ctx, "failed to shutdown http server", err)
@@ -108,6 +187,13 @@ func (svc *Service) Close(ctx context.Context) error {
 				hasShutdownError = true
 			}
 		}
+
+		if svc.ServiceList.Redis {
+			if err := svc.redisClient.Close(); err != nil {
+				log.Error(ctx, "failed to close redis client", err)
+				hasShutdownError = true
+			}
+		}
 	}()
 
 	// wait for shutdown success (via cancel) or failure (timeout)
@@ -130,7 +216,7 @@ func (svc *Service) Close(ctx context.Context) error {
 	return nil
 }
 
-func registerCheckers(ctx context.Context, hc HealthChecker, client cognitoClient.Client, userPoolID *string, authorisationMiddleware authorisation.Middleware) (err error) {
+func registerCheckers(ctx context.Context, hc HealthChecker, client cognitoClient.Client, userPoolID *string, authorisationMiddleware authorisation.Middleware, redisClient redis.UniversalClient) (err error) {
 	hasErrors := false
 
 	if err := hc.AddCheck("Cognito", health.CognitoHealthCheck(ctx, client, userPoolID)); err != nil {
@@ -143,9 +229,85 @@ func registerCheckers(ctx context.Context, hc HealthChecker, client cognitoClien
 		log.Error(ctx, "error adding health checker for Permissions API", err)
 	}
 
+	if redisClient != nil {
+		if err := hc.AddCheck("Redis", health.RedisHealthCheck(redisClient)); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding health checker for Redis", err)
+		}
+	}
+
 	if hasErrors {
 		return errors.New("Error(s) registering checkers for healthcheck")
 	}
 
 	return nil
 }
+
+// getPasswordPolicy builds the password policy from config, loading the breached password list if one is configured
+func getPasswordPolicy(cfg *config.Config) (*validation.PasswordPolicy, error) {
+	policy := &validation.PasswordPolicy{
+		MinLength:        cfg.PasswordMinLength,
+		RequireUppercase: cfg.PasswordRequireUppercase,
+		RequireLowercase: cfg.PasswordRequireLowercase,
+		RequireNumber:    cfg.PasswordRequireNumber,
+		RequireSymbol:    cfg.PasswordRequireSymbol,
+		BannedWords:      cfg.PasswordBannedWords,
+	}
+
+	if cfg.PasswordBreachedListPath != "" {
+		f, err := os.Open(cfg.PasswordBreachedListPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open breached password list")
+		}
+		defer f.Close()
+
+		policy.BreachedList, err = validation.LoadBreachedPasswordList(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load breached password list")
+		}
+	}
+
+	return policy, nil
+}
+
+// getPasswordHistory returns the configured password history store, or nil if password history is disabled
+func getPasswordHistory(cfg *config.Config, client cognitoClient.Client) (*store.PasswordHistory, error) {
+	if cfg.PasswordHistorySize <= 0 {
+		return nil, nil
+	}
+
+	switch cfg.PasswordHistoryStore {
+	case "cognito":
+		if maxSize := store.MaxCognitoPasswordHistorySize(); cfg.PasswordHistorySize > maxSize {
+			return nil, errors.Errorf("password history size %d is more than the %d passwords the cognito password history store can keep", cfg.PasswordHistorySize, maxSize)
+		}
+		return store.NewPasswordHistory(store.NewCognitoPasswordHistoryStore(client, cfg.AWSCognitoUserPoolID), cfg.PasswordHistorySize), nil
+	case "memory":
+		return store.NewPasswordHistory(store.NewInMemoryPasswordHistoryStore(), cfg.PasswordHistorySize), nil
+	default:
+		return nil, errors.New("unknown password history store: " + cfg.PasswordHistoryStore)
+	}
+}
+
+// setDataStores replaces the API's in-memory stores with shared ones when the redis data store is configured
+func setDataStores(a *api.API, cfg *config.Config, redisClient redis.UniversalClient) error {
+	switch cfg.DataStore {
+	case "redis":
+		a.GroupMetadata = store.NewRedisGroupMetadataStore(redisClient)
+		a.GroupOwners = store.NewRedisGroupOwnerStore(redisClient)
+		a.MembershipExpiry = store.NewRedisMembershipExpiryStore(redisClient)
+		a.AccessRequests = store.NewRedisAccessRequestStore(redisClient)
+		a.GroupHierarchy = store.NewRedisGroupHierarchyStore(redisClient)
+		a.GroupNameHistory = store.NewRedisGroupNameHistoryStore(redisClient)
+		if cfg.RateLimitEnabled {
+			a.RateLimitStore = store.NewRedisRateLimitStore(redisClient)
+		}
+	case "memory":
+		if cfg.RateLimitEnabled {
+			a.RateLimitStore = store.NewInMemoryRateLimitStore()
+		}
+	default:
+		return errors.New("unknown data store: " + cfg.DataStore)
+	}
+	return nil
+}
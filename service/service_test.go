@@ -21,8 +21,11 @@ import (
 	"github.com/ONSdigital/dp-identity-api/v2/service"
 
 	serviceMock "github.com/ONSdigital/dp-identity-api/v2/service/mock"
+	"github.com/ONSdigital/dp-identity-api/v2/store"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -153,6 +156,104 @@ func TestRun(t *testing.T) {
 			})
 		})
 
+		Convey("Given that the redis data store is configured", func() {
+			redisServer := miniredis.RunT(t)
+			cfg.DataStore, cfg.RedisAddress = "redis", redisServer.Addr()
+			writeTimeout := 10 * time.Second
+			cfg.HTTPWriteTimeout = &writeTimeout
+			defer func() { cfg.DataStore, cfg.RedisAddress, cfg.HTTPWriteTimeout = "memory", "", nil }()
+			initMock := &serviceMock.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetCognitoClientFunc:           DoGetCognitoClient,
+				DoGetAuthorisationMiddlewareFunc: DoGetAuthorisationMiddleware,
+				DoGetRedisClientFunc: func(_ *config.Config) redis.UniversalClient {
+					return redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+				},
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+			svc, err := service.Run(ctx, cfg, svcList, jwksHandler, testBuildTime, testGitCommit, testVersion, svcErrors)
+			serverWg.Wait()
+
+			Convey("Then the shared stores are used and Redis is health checked", func() {
+				So(err, ShouldBeNil)
+				So(svcList.Redis, ShouldBeTrue)
+				So(initMock.DoGetRedisClientCalls(), ShouldHaveLength, 1)
+				So(svc.API.GroupMetadata, ShouldHaveSameTypeAs, &store.RedisGroupMetadataStore{})
+				So(svc.API.GroupOwners, ShouldHaveSameTypeAs, &store.RedisGroupOwnerStore{})
+				So(svc.API.MembershipExpiry, ShouldHaveSameTypeAs, &store.RedisMembershipExpiryStore{})
+				So(svc.API.AccessRequests, ShouldHaveSameTypeAs, &store.RedisAccessRequestStore{})
+				So(svc.API.GroupHierarchy, ShouldHaveSameTypeAs, &store.RedisGroupHierarchyStore{})
+				So(svc.API.GroupNameHistory, ShouldHaveSameTypeAs, &store.RedisGroupNameHistoryStore{})
+				So(svc.API.IdempotencyStore, ShouldHaveSameTypeAs, &store.RedisIdempotencyStore{})
+				So(svc.API.IdempotencyInProgressTTL, ShouldEqual, 40*time.Second)
+				So(svc.API.RateLimitStore, ShouldHaveSameTypeAs, &store.RedisRateLimitStore{})
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 3)
+				So(hcMock.AddCheckCalls()[2].Name, ShouldResemble, "Redis")
+			})
+		})
+
+		Convey("Given that the redis data store is configured without an address", func() {
+			cfg.DataStore = "redis"
+			defer func() { cfg.DataStore = "memory" }()
+			initMock := &serviceMock.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetCognitoClientFunc:           DoGetCognitoClient,
+				DoGetAuthorisationMiddlewareFunc: DoGetAuthorisationMiddleware,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, jwksHandler, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails before connecting to Redis", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "REDIS_ADDRESS must be set to use the redis data store")
+				So(svcList.Redis, ShouldBeFalse)
+			})
+		})
+
+		Convey("Given that an unknown data store is configured", func() {
+			cfg.DataStore = "disk"
+			defer func() { cfg.DataStore = "memory" }()
+			initMock := &serviceMock.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetCognitoClientFunc:           DoGetCognitoClient,
+				DoGetAuthorisationMiddlewareFunc: DoGetAuthorisationMiddleware,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, jwksHandler, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unknown data store: disk")
+				So(svcList.Redis, ShouldBeFalse)
+			})
+		})
+
+		Convey("Given that a password history too large for the cognito store is configured", func() {
+			cfg.PasswordHistorySize, cfg.PasswordHistoryStore = store.MaxCognitoPasswordHistorySize()+1, "cognito"
+			defer func() { cfg.PasswordHistorySize = 0 }()
+			initMock := &serviceMock.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetCognitoClientFunc:           DoGetCognitoClient,
+				DoGetAuthorisationMiddlewareFunc: DoGetAuthorisationMiddleware,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, jwksHandler, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "password history size 31 is more than the 30 passwords the cognito password history store can keep")
+			})
+		})
+
 		Convey("Given that all dependencies are successfully initialised", func() {
 			initMock := &serviceMock.InitialiserMock{
 				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
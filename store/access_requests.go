@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+var (
+	ErrAccessRequestNotFound = errors.New("access request not found")
+	ErrAccessRequestDecided  = errors.New("access request already decided")
+	ErrAccessRequestExists   = errors.New("user already has a pending access request for the group")
+)
+
+// AccessRequestStore persists users' requests to join groups and the decisions on them
+type AccessRequestStore interface {
+	// CreateAccessRequest returns ErrAccessRequestExists when the user already has a pending request to join the
+	// group, so that a user never has more than one, however many requests they make at once
+	CreateAccessRequest(ctx context.Context, request models.AccessRequest) error
+	// GetAccessRequest returns ErrAccessRequestNotFound when the group has no access request with the ID
+	GetAccessRequest(ctx context.Context, groupID, requestID string) (models.AccessRequest, error)
+	// ListAccessRequests returns the group's access requests with the status, or all of them when the status is
+	// empty, oldest first
+	ListAccessRequests(ctx context.Context, groupID, status string) ([]models.AccessRequest, error)
+	// DecideAccessRequest records the decision on a pending access request. It returns ErrAccessRequestDecided when
+	// the request has already been decided, so that only one decision is ever recorded.
+	DecideAccessRequest(ctx context.Context, groupID, requestID string, decision models.AccessRequestDecision) (models.AccessRequest, error)
+	// ReopenAccessRequest returns a decided access request to pending, so that a decision that could not be carried
+	// out can be made again
+	ReopenAccessRequest(ctx context.Context, groupID, requestID string) error
+	DeleteGroupAccessRequests(ctx context.Context, groupID string) error
+}
+
+// InMemoryAccessRequestStore is an AccessRequestStore local to a single instance of the API
+type InMemoryAccessRequestStore struct {
+	mu       sync.RWMutex
+	requests map[string]map[string]models.AccessRequest
+}
+
+// NewInMemoryAccessRequestStore returns an empty InMemoryAccessRequestStore
+func NewInMemoryAccessRequestStore() *InMemoryAccessRequestStore {
+	return &InMemoryAccessRequestStore{requests: map[string]map[string]models.AccessRequest{}}
+}
+
+// CreateAccessRequest stores a new access request, unless the user already has a pending one for the group
+func (s *InMemoryAccessRequestStore) CreateAccessRequest(_ context.Context, request models.AccessRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.requests[request.GroupID] {
+		if existing.UserID == request.UserID && existing.Status == models.AccessRequestPending {
+			return ErrAccessRequestExists
+		}
+	}
+	if s.requests[request.GroupID] == nil {
+		s.requests[request.GroupID] = map[string]models.AccessRequest{}
+	}
+	s.requests[request.GroupID][request.ID] = request
+	return nil
+}
+
+// GetAccessRequest returns the access request, or ErrAccessRequestNotFound
+func (s *InMemoryAccessRequestStore) GetAccessRequest(_ context.Context, groupID, requestID string) (models.AccessRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	request, ok := s.requests[groupID][requestID]
+	if !ok {
+		return models.AccessRequest{}, ErrAccessRequestNotFound
+	}
+	return request, nil
+}
+
+// ListAccessRequests returns the group's access requests with the status, or all of them when the status is empty,
+// oldest first
+func (s *InMemoryAccessRequestStore) ListAccessRequests(_ context.Context, groupID, status string) ([]models.AccessRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	requests := []models.AccessRequest{}
+	for _, request := range s.requests[groupID] {
+		if status == "" || request.Status == status {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].Created.Equal(requests[j].Created) {
+			return requests[i].ID < requests[j].ID
+		}
+		return requests[i].Created.Before(requests[j].Created)
+	})
+	return requests, nil
+}
+
+// DecideAccessRequest records the decision on a pending access request
+func (s *InMemoryAccessRequestStore) DecideAccessRequest(_ context.Context, groupID, requestID string, decision models.AccessRequestDecision) (models.AccessRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.requests[groupID][requestID]
+	if !ok {
+		return models.AccessRequest{}, ErrAccessRequestNotFound
+	}
+	if request.Status != models.AccessRequestPending {
+		return request, ErrAccessRequestDecided
+	}
+	request.Decide(decision)
+	s.requests[groupID][requestID] = request
+	return request, nil
+}
+
+// ReopenAccessRequest returns a decided access request to pending
+func (s *InMemoryAccessRequestStore) ReopenAccessRequest(_ context.Context, groupID, requestID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.requests[groupID][requestID]
+	if !ok {
+		return ErrAccessRequestNotFound
+	}
+	request.Reopen()
+	s.requests[groupID][requestID] = request
+	return nil
+}
+
+// DeleteGroupAccessRequests removes all of the group's access requests
+func (s *InMemoryAccessRequestStore) DeleteGroupAccessRequests(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.requests, groupID)
+	return nil
+}
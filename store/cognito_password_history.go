@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/ONSdigital/dp-identity-api/v2/cognito"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// PasswordHistoryAttribute is the Cognito custom attribute holding a user's password history. It must be created
+// in the user pool as a mutable string and should not be readable or writable by any app client.
+var PasswordHistoryAttribute = "custom:password_history"
+
+// PasswordHistoryAttributeMaxLength is the longest value Cognito allows in a custom string attribute
+const PasswordHistoryAttributeMaxLength = 2048
+
+// MaxCognitoPasswordHistorySize is how many password hashes fit in the custom attribute. Cognito refuses a longer
+// history, so the password history size must not be more than this when the history is kept in Cognito.
+func MaxCognitoPasswordHistorySize() int {
+	entryLength := base64.RawStdEncoding.EncodedLen(passwordSaltLength) + len(".") + base64.RawStdEncoding.EncodedLen(passwordHashLength)
+	// entries are separated by commas
+	return (PasswordHistoryAttributeMaxLength + 1) / (entryLength + 1)
+}
+
+// CognitoPasswordHistoryStore is a persistent PasswordHistoryStore keeping each user's password hashes in a Cognito
+// custom attribute
+type CognitoPasswordHistoryStore struct {
+	Client     cognito.Client
+	UserPoolID string
+}
+
+// NewCognitoPasswordHistoryStore returns a CognitoPasswordHistoryStore for the given user pool
+func NewCognitoPasswordHistoryStore(client cognito.Client, userPoolID string) *CognitoPasswordHistoryStore {
+	return &CognitoPasswordHistoryStore{Client: client, UserPoolID: userPoolID}
+}
+
+// GetPasswordHistory reads the password hashes kept for the user from their custom attribute
+func (s *CognitoPasswordHistoryStore) GetPasswordHistory(ctx context.Context, userID string) ([]PasswordHash, error) {
+	user, err := s.Client.AdminGetUser(ctx, &cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: &s.UserPoolID,
+		Username:   &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attr := range user.UserAttributes {
+		if attr.Name != nil && *attr.Name == PasswordHistoryAttribute && attr.Value != nil {
+			return decodePasswordHistory(*attr.Value)
+		}
+	}
+	return nil, nil
+}
+
+// SetPasswordHistory writes the password hashes kept for the user to their custom attribute
+func (s *CognitoPasswordHistoryStore) SetPasswordHistory(ctx context.Context, userID string, hashes []PasswordHash) error {
+	value := encodePasswordHistory(hashes)
+	_, err := s.Client.AdminUpdateUserAttributes(ctx, &cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserPoolId: &s.UserPoolID,
+		Username:   &userID,
+		UserAttributes: []types.AttributeType{
+			{Name: &PasswordHistoryAttribute, Value: &value},
+		},
+	})
+	return err
+}
+
+// encodePasswordHistory encodes the hashes as comma separated "salt.hash" pairs of unpadded base64
+func encodePasswordHistory(hashes []PasswordHash) string {
+	encoded := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		encoded = append(encoded, base64.RawStdEncoding.EncodeToString(hash.Salt)+"."+base64.RawStdEncoding.EncodeToString(hash.Hash))
+	}
+	return strings.Join(encoded, ",")
+}
+
+func decodePasswordHistory(value string) ([]PasswordHash, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var hashes []PasswordHash
+	for _, entry := range strings.Split(value, ",") {
+		encodedSalt, encodedHash, found := strings.Cut(entry, ".")
+		if !found {
+			return nil, errors.New("malformed password history entry")
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(encodedSalt)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := base64.RawStdEncoding.DecodeString(encodedHash)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, PasswordHash{Salt: salt, Hash: hash})
+	}
+	return hashes, nil
+}
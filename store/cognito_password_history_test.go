@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/cognito/mock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/aws/smithy-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCognitoPasswordHistoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a Cognito user pool holding password history in a custom attribute", t, func() {
+		attributes := map[string]string{}
+		m := &mock.MockCognitoIdentityProviderClient{}
+		m.AdminGetUserFunc = func(_ context.Context, input *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+			So(*input.UserPoolId, ShouldEqual, "eu-west-1_pool")
+			output := &cognitoidentityprovider.AdminGetUserOutput{Username: input.Username}
+			if value, ok := attributes[*input.Username]; ok {
+				output.UserAttributes = []types.AttributeType{{Name: aws.String(PasswordHistoryAttribute), Value: aws.String(value)}}
+			}
+			return output, nil
+		}
+		m.AdminUpdateUserAttributesFunc = func(_ context.Context, input *cognitoidentityprovider.AdminUpdateUserAttributesInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminUpdateUserAttributesOutput, error) {
+			So(len(input.UserAttributes), ShouldEqual, 1)
+			So(*input.UserAttributes[0].Name, ShouldEqual, PasswordHistoryAttribute)
+			attributes[*input.Username] = *input.UserAttributes[0].Value
+			return &cognitoidentityprovider.AdminUpdateUserAttributesOutput{}, nil
+		}
+
+		historyStore := NewCognitoPasswordHistoryStore(m, "eu-west-1_pool")
+
+		Convey("A user without the attribute has no history", func() {
+			hashes, err := historyStore.GetPasswordHistory(ctx, "abcd1234")
+			So(err, ShouldBeNil)
+			So(hashes, ShouldBeEmpty)
+		})
+
+		Convey("Hashes written for a user are read back unchanged", func() {
+			written := []PasswordHash{
+				{Salt: []byte("salt-one"), Hash: []byte("hash-one")},
+				{Salt: []byte("salt-two"), Hash: []byte("hash-two")},
+			}
+			So(historyStore.SetPasswordHistory(ctx, "abcd1234", written), ShouldBeNil)
+
+			read, err := historyStore.GetPasswordHistory(ctx, "abcd1234")
+			So(err, ShouldBeNil)
+			So(read, ShouldResemble, written)
+		})
+
+		Convey("A malformed attribute is an error", func() {
+			attributes["abcd1234"] = "not-a-history"
+
+			hashes, err := historyStore.GetPasswordHistory(ctx, "abcd1234")
+			So(err, ShouldNotBeNil)
+			So(hashes, ShouldBeNil)
+		})
+
+		Convey("A Cognito error is returned", func() {
+			m.AdminGetUserFunc = func(_ context.Context, _ *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+				return nil, &smithy.GenericAPIError{Code: "UserNotFoundException", Message: "user not found"}
+			}
+
+			hashes, err := historyStore.GetPasswordHistory(ctx, "abcd1234")
+			So(err, ShouldNotBeNil)
+			So(hashes, ShouldBeNil)
+		})
+	})
+}
+
+func TestMaxCognitoPasswordHistorySize(t *testing.T) {
+	Convey("The largest password history fits in the custom attribute and one more does not", t, func() {
+		var hashes []PasswordHash
+		for i := 0; i <= MaxCognitoPasswordHistorySize(); i++ {
+			hashes = append(hashes, PasswordHash{Salt: make([]byte, passwordSaltLength), Hash: make([]byte, passwordHashLength)})
+		}
+
+		So(len(encodePasswordHistory(hashes[1:])), ShouldBeLessThanOrEqualTo, PasswordHistoryAttributeMaxLength)
+		So(len(encodePasswordHistory(hashes)), ShouldBeGreaterThan, PasswordHistoryAttributeMaxLength)
+	})
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrGroupCycle is returned when nesting a group would make it a descendant of itself
+var ErrGroupCycle = errors.New("nesting the group would create a cycle")
+
+// GroupHierarchyStore persists the nesting of groups inside other groups. A group can be nested in several parent
+// groups, but never in itself or in any of its own descendants.
+type GroupHierarchyStore interface {
+	// ListChildGroups returns the IDs of the groups nested directly in the group, in order
+	ListChildGroups(ctx context.Context, groupID string) ([]string, error)
+	// ListAncestorGroups returns the IDs of the groups that any of the groups are nested in, directly or indirectly,
+	// in order and excluding the groups themselves
+	ListAncestorGroups(ctx context.Context, groupIDs []string) ([]string, error)
+	// ListDescendantGroups returns the IDs of the groups nested in the group, directly or indirectly, in order
+	ListDescendantGroups(ctx context.Context, groupID string) ([]string, error)
+	// AddChildGroup nests the child group in the parent group, returning ErrGroupCycle if the parent is already the
+	// child or one of its descendants
+	AddChildGroup(ctx context.Context, parentID, childID string) error
+	// RemoveChildGroup stops the child group being nested in the parent group, reporting whether it was
+	RemoveChildGroup(ctx context.Context, parentID, childID string) (bool, error)
+	// DeleteGroupHierarchy removes the group from the hierarchy, both as a parent and as a child
+	DeleteGroupHierarchy(ctx context.Context, groupID string) error
+}
+
+// InMemoryGroupHierarchyStore is a GroupHierarchyStore local to a single instance of the API
+type InMemoryGroupHierarchyStore struct {
+	mu       sync.RWMutex
+	children map[string]map[string]struct{}
+	parents  map[string]map[string]struct{}
+}
+
+// NewInMemoryGroupHierarchyStore returns an empty InMemoryGroupHierarchyStore
+func NewInMemoryGroupHierarchyStore() *InMemoryGroupHierarchyStore {
+	return &InMemoryGroupHierarchyStore{
+		children: map[string]map[string]struct{}{},
+		parents:  map[string]map[string]struct{}{},
+	}
+}
+
+// ListChildGroups returns the IDs of the groups nested directly in the group, in order
+func (s *InMemoryGroupHierarchyStore) ListChildGroups(_ context.Context, groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(s.children[groupID]), nil
+}
+
+// ListAncestorGroups returns the IDs of the groups that any of the groups are nested in, directly or indirectly
+func (s *InMemoryGroupHierarchyStore) ListAncestorGroups(_ context.Context, groupIDs []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ancestors := reachable(s.parents, groupIDs...)
+	for _, groupID := range groupIDs {
+		delete(ancestors, groupID)
+	}
+	return sortedKeys(ancestors), nil
+}
+
+// ListDescendantGroups returns the IDs of the groups nested in the group, directly or indirectly
+func (s *InMemoryGroupHierarchyStore) ListDescendantGroups(_ context.Context, groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(reachable(s.children, groupID)), nil
+}
+
+// AddChildGroup nests the child group in the parent group unless that would create a cycle
+func (s *InMemoryGroupHierarchyStore) AddChildGroup(_ context.Context, parentID, childID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parentID == childID {
+		return ErrGroupCycle
+	}
+	if _, ok := reachable(s.children, childID)[parentID]; ok {
+		return ErrGroupCycle
+	}
+
+	addEdge(s.children, parentID, childID)
+	addEdge(s.parents, childID, parentID)
+	return nil
+}
+
+// RemoveChildGroup stops the child group being nested in the parent group, reporting whether it was
+func (s *InMemoryGroupHierarchyStore) RemoveChildGroup(_ context.Context, parentID, childID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.children[parentID][childID]; !ok {
+		return false, nil
+	}
+	removeEdge(s.children, parentID, childID)
+	removeEdge(s.parents, childID, parentID)
+	return true, nil
+}
+
+// DeleteGroupHierarchy removes the group from the hierarchy, both as a parent and as a child
+func (s *InMemoryGroupHierarchyStore) DeleteGroupHierarchy(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for childID := range s.children[groupID] {
+		removeEdge(s.parents, childID, groupID)
+	}
+	for parentID := range s.parents[groupID] {
+		removeEdge(s.children, parentID, groupID)
+	}
+	delete(s.children, groupID)
+	delete(s.parents, groupID)
+	return nil
+}
+
+// reachable returns the groups that can be reached from the starting groups by following the edges, excluding the
+// starting groups unless an edge leads back to them
+func reachable(edges map[string]map[string]struct{}, from ...string) map[string]struct{} {
+	found := map[string]struct{}{}
+	queue := append([]string{}, from...)
+	for len(queue) > 0 {
+		groupID := queue[0]
+		queue = queue[1:]
+		for next := range edges[groupID] {
+			if _, ok := found[next]; !ok {
+				found[next] = struct{}{}
+				queue = append(queue, next)
+			}
+		}
+	}
+	return found
+}
+
+func addEdge(edges map[string]map[string]struct{}, from, to string) {
+	if edges[from] == nil {
+		edges[from] = map[string]struct{}{}
+	}
+	edges[from][to] = struct{}{}
+}
+
+func removeEdge(edges map[string]map[string]struct{}, from, to string) {
+	delete(edges[from], to)
+	if len(edges[from]) == 0 {
+		delete(edges, from)
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+// GroupMetadataStore persists the metadata of each group, keyed by group ID
+type GroupMetadataStore interface {
+	// GetGroupMetadata returns the group's metadata, which is empty if none has been set
+	GetGroupMetadata(ctx context.Context, groupID string) (models.GroupMetadata, error)
+	// ListGroupMetadata returns the metadata of every group that has some, keyed by group ID
+	ListGroupMetadata(ctx context.Context) (map[string]models.GroupMetadata, error)
+	SetGroupMetadata(ctx context.Context, groupID string, metadata models.GroupMetadata) error
+	DeleteGroupMetadata(ctx context.Context, groupID string) error
+}
+
+// InMemoryGroupMetadataStore is a GroupMetadataStore local to a single instance of the API
+type InMemoryGroupMetadataStore struct {
+	mu       sync.RWMutex
+	metadata map[string]models.GroupMetadata
+}
+
+// NewInMemoryGroupMetadataStore returns an empty InMemoryGroupMetadataStore
+func NewInMemoryGroupMetadataStore() *InMemoryGroupMetadataStore {
+	return &InMemoryGroupMetadataStore{metadata: map[string]models.GroupMetadata{}}
+}
+
+// GetGroupMetadata returns the group's metadata, which is empty if none has been set
+func (s *InMemoryGroupMetadataStore) GetGroupMetadata(_ context.Context, groupID string) (models.GroupMetadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.metadata[groupID], nil
+}
+
+// ListGroupMetadata returns a copy of the metadata of every group that has some
+func (s *InMemoryGroupMetadataStore) ListGroupMetadata(_ context.Context) (map[string]models.GroupMetadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadata := make(map[string]models.GroupMetadata, len(s.metadata))
+	for groupID, groupMetadata := range s.metadata {
+		metadata[groupID] = groupMetadata
+	}
+	return metadata, nil
+}
+
+// SetGroupMetadata replaces the group's metadata, removing it when it is empty
+func (s *InMemoryGroupMetadataStore) SetGroupMetadata(_ context.Context, groupID string, metadata models.GroupMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if metadata == (models.GroupMetadata{}) {
+		delete(s.metadata, groupID)
+		return nil
+	}
+	s.metadata[groupID] = metadata
+	return nil
+}
+
+// DeleteGroupMetadata removes the group's metadata
+func (s *InMemoryGroupMetadataStore) DeleteGroupMetadata(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.metadata, groupID)
+	return nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryGroupMetadataStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an in-memory group metadata store", t, func() {
+		metadataStore := NewInMemoryGroupMetadataStore()
+		metadata := models.GroupMetadata{Description: "Publishing team", Owner: "Digital Publishing", ContactEmail: "publishing@ons.gov.uk"}
+
+		Convey("A group without metadata has empty metadata", func() {
+			groupMetadata, err := metadataStore.GetGroupMetadata(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupMetadata, ShouldResemble, models.GroupMetadata{})
+		})
+
+		Convey("Metadata that has been set is returned for the group and listed", func() {
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", metadata), ShouldBeNil)
+
+			groupMetadata, err := metadataStore.GetGroupMetadata(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupMetadata, ShouldResemble, metadata)
+
+			listed, err := metadataStore.ListGroupMetadata(ctx)
+			So(err, ShouldBeNil)
+			So(listed, ShouldResemble, map[string]models.GroupMetadata{"group-1": metadata})
+
+			Convey("And changing the listed copy does not change the store", func() {
+				delete(listed, "group-1")
+				groupMetadata, _ := metadataStore.GetGroupMetadata(ctx, "group-1")
+				So(groupMetadata, ShouldResemble, metadata)
+			})
+		})
+
+		Convey("Setting empty metadata or deleting it removes the group from the list", func() {
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", metadata), ShouldBeNil)
+			So(metadataStore.SetGroupMetadata(ctx, "group-2", metadata), ShouldBeNil)
+
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", models.GroupMetadata{}), ShouldBeNil)
+			So(metadataStore.DeleteGroupMetadata(ctx, "group-2"), ShouldBeNil)
+
+			listed, err := metadataStore.ListGroupMetadata(ctx)
+			So(err, ShouldBeNil)
+			So(listed, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+// GroupNameHistoryStore persists the former names of each group, keyed by group ID
+type GroupNameHistoryStore interface {
+	AddGroupRename(ctx context.Context, rename models.GroupRename) error
+	// RemoveGroupRename forgets a rename that was added, for a rename that could not be made
+	RemoveGroupRename(ctx context.Context, rename models.GroupRename) error
+	// ListGroupRenames returns the group's former names, oldest first
+	ListGroupRenames(ctx context.Context, groupID string) ([]models.GroupRename, error)
+	// FindGroupRename returns the most recent rename away from a name matching the given one once both are cleaned
+	// with models.CleanString, or nil if no group has had that name
+	FindGroupRename(ctx context.Context, name string) (*models.GroupRename, error)
+	DeleteGroupRenames(ctx context.Context, groupID string) error
+}
+
+// InMemoryGroupNameHistoryStore is a GroupNameHistoryStore local to a single instance of the API
+type InMemoryGroupNameHistoryStore struct {
+	mu      sync.RWMutex
+	renames map[string][]models.GroupRename
+}
+
+// NewInMemoryGroupNameHistoryStore returns an empty InMemoryGroupNameHistoryStore
+func NewInMemoryGroupNameHistoryStore() *InMemoryGroupNameHistoryStore {
+	return &InMemoryGroupNameHistoryStore{renames: map[string][]models.GroupRename{}}
+}
+
+// AddGroupRename records a former name of a group
+func (s *InMemoryGroupNameHistoryStore) AddGroupRename(_ context.Context, rename models.GroupRename) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.renames[rename.GroupID] = append(s.renames[rename.GroupID], rename)
+	return nil
+}
+
+// RemoveGroupRename forgets the latest rename of the group matching the given one
+func (s *InMemoryGroupNameHistoryStore) RemoveGroupRename(_ context.Context, rename models.GroupRename) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	renames := s.renames[rename.GroupID]
+	for i := len(renames) - 1; i >= 0; i-- {
+		if renames[i].Name == rename.Name && renames[i].Renamed.Equal(rename.Renamed) {
+			s.renames[rename.GroupID] = append(renames[:i:i], renames[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// ListGroupRenames returns a copy of the group's former names, oldest first
+func (s *InMemoryGroupNameHistoryStore) ListGroupRenames(_ context.Context, groupID string) ([]models.GroupRename, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.GroupRename{}, s.renames[groupID]...), nil
+}
+
+// FindGroupRename returns the most recent rename away from a name matching the given one
+func (s *InMemoryGroupNameHistoryStore) FindGroupRename(_ context.Context, name string) (*models.GroupRename, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cleanName := models.CleanString(name)
+	var found *models.GroupRename
+	for _, renames := range s.renames {
+		for i := range renames {
+			if models.CleanString(renames[i].Name) != cleanName {
+				continue
+			}
+			if found == nil || renames[i].Renamed.After(found.Renamed) {
+				rename := renames[i]
+				found = &rename
+			}
+		}
+	}
+	return found, nil
+}
+
+// DeleteGroupRenames removes the group's former names
+func (s *InMemoryGroupNameHistoryStore) DeleteGroupRenames(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.renames, groupID)
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryGroupNameHistoryStore(t *testing.T) {
+	ctx := context.Background()
+	renamed := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	Convey("Given an in-memory group name history store", t, func() {
+		historyStore := NewInMemoryGroupNameHistoryStore()
+		So(historyStore.AddGroupRename(ctx, models.GroupRename{GroupID: "group-1", Name: "Prices Team", Renamed: renamed}), ShouldBeNil)
+		So(historyStore.AddGroupRename(ctx, models.GroupRename{GroupID: "group-1", Name: "Inflation", Renamed: renamed.Add(time.Hour)}), ShouldBeNil)
+
+		Convey("A group's former names are listed oldest first", func() {
+			renames, err := historyStore.ListGroupRenames(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(renames, ShouldHaveLength, 2)
+			So(renames[0].Name, ShouldEqual, "Prices Team")
+
+			renames, err = historyStore.ListGroupRenames(ctx, "group-2")
+			So(err, ShouldBeNil)
+			So(renames, ShouldNotBeNil)
+			So(renames, ShouldBeEmpty)
+		})
+
+		Convey("A former name is found whatever its case and punctuation", func() {
+			rename, err := historyStore.FindGroupRename(ctx, "prices-team!")
+			So(err, ShouldBeNil)
+			So(rename.GroupID, ShouldEqual, "group-1")
+			So(rename.Name, ShouldEqual, "Prices Team")
+
+			rename, err = historyStore.FindGroupRename(ctx, "Trade")
+			So(err, ShouldBeNil)
+			So(rename, ShouldBeNil)
+		})
+
+		Convey("The most recent group to give up a name is found", func() {
+			So(historyStore.AddGroupRename(ctx, models.GroupRename{GroupID: "group-2", Name: "Prices team", Renamed: renamed.Add(2 * time.Hour)}), ShouldBeNil)
+
+			rename, err := historyStore.FindGroupRename(ctx, "Prices Team")
+			So(err, ShouldBeNil)
+			So(rename.GroupID, ShouldEqual, "group-2")
+		})
+
+		Convey("A removed rename is forgotten and the name found by the group's earlier rename away from it", func() {
+			latest := models.GroupRename{GroupID: "group-1", Name: "Prices team", Renamed: renamed.Add(2 * time.Hour)}
+			So(historyStore.AddGroupRename(ctx, latest), ShouldBeNil)
+			So(historyStore.RemoveGroupRename(ctx, latest), ShouldBeNil)
+
+			renames, err := historyStore.ListGroupRenames(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(renames, ShouldResemble, []models.GroupRename{
+				{GroupID: "group-1", Name: "Prices Team", Renamed: renamed},
+				{GroupID: "group-1", Name: "Inflation", Renamed: renamed.Add(time.Hour)},
+			})
+
+			rename, err := historyStore.FindGroupRename(ctx, "Prices Team")
+			So(err, ShouldBeNil)
+			So(*rename, ShouldResemble, renames[0])
+
+			So(historyStore.RemoveGroupRename(ctx, renames[0]), ShouldBeNil)
+			rename, err = historyStore.FindGroupRename(ctx, "Prices Team")
+			So(err, ShouldBeNil)
+			So(rename, ShouldBeNil)
+		})
+
+		Convey("Deleting a group's history removes its former names", func() {
+			So(historyStore.DeleteGroupRenames(ctx, "group-1"), ShouldBeNil)
+
+			rename, err := historyStore.FindGroupRename(ctx, "Prices Team")
+			So(err, ShouldBeNil)
+			So(rename, ShouldBeNil)
+		})
+	})
+}
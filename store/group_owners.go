@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// GroupOwnerStore persists the users who own each group, keyed by group ID
+type GroupOwnerStore interface {
+	// ListGroupOwners returns the IDs of the group's owners in order
+	ListGroupOwners(ctx context.Context, groupID string) ([]string, error)
+	IsGroupOwner(ctx context.Context, groupID, userID string) (bool, error)
+	AddGroupOwner(ctx context.Context, groupID, userID string) error
+	// RemoveGroupOwner removes the user from the group's owners, reporting whether they were one
+	RemoveGroupOwner(ctx context.Context, groupID, userID string) (bool, error)
+	DeleteGroupOwners(ctx context.Context, groupID string) error
+}
+
+// InMemoryGroupOwnerStore is a GroupOwnerStore local to a single instance of the API
+type InMemoryGroupOwnerStore struct {
+	mu     sync.RWMutex
+	owners map[string]map[string]struct{}
+}
+
+// NewInMemoryGroupOwnerStore returns an empty InMemoryGroupOwnerStore
+func NewInMemoryGroupOwnerStore() *InMemoryGroupOwnerStore {
+	return &InMemoryGroupOwnerStore{owners: map[string]map[string]struct{}{}}
+}
+
+// ListGroupOwners returns the IDs of the group's owners in order
+func (s *InMemoryGroupOwnerStore) ListGroupOwners(_ context.Context, groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owners := make([]string, 0, len(s.owners[groupID]))
+	for userID := range s.owners[groupID] {
+		owners = append(owners, userID)
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+// IsGroupOwner reports whether the user owns the group
+func (s *InMemoryGroupOwnerStore) IsGroupOwner(_ context.Context, groupID, userID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.owners[groupID][userID]
+	return ok, nil
+}
+
+// AddGroupOwner makes the user an owner of the group
+func (s *InMemoryGroupOwnerStore) AddGroupOwner(_ context.Context, groupID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.owners[groupID] == nil {
+		s.owners[groupID] = map[string]struct{}{}
+	}
+	s.owners[groupID][userID] = struct{}{}
+	return nil
+}
+
+// RemoveGroupOwner removes the user from the group's owners, reporting whether they were one
+func (s *InMemoryGroupOwnerStore) RemoveGroupOwner(_ context.Context, groupID, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.owners[groupID][userID]; !ok {
+		return false, nil
+	}
+	delete(s.owners[groupID], userID)
+	if len(s.owners[groupID]) == 0 {
+		delete(s.owners, groupID)
+	}
+	return true, nil
+}
+
+// DeleteGroupOwners removes all of the group's owners
+func (s *InMemoryGroupOwnerStore) DeleteGroupOwners(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.owners, groupID)
+	return nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryGroupOwnerStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an in-memory group owner store", t, func() {
+		ownerStore := NewInMemoryGroupOwnerStore()
+
+		Convey("A group without owners has an empty list of owners", func() {
+			owners, err := ownerStore.ListGroupOwners(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(owners, ShouldNotBeNil)
+			So(owners, ShouldBeEmpty)
+		})
+
+		Convey("Owners that have been added are listed in order for their group only", func() {
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-2"), ShouldBeNil)
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-1"), ShouldBeNil)
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-1"), ShouldBeNil)
+
+			owners, err := ownerStore.ListGroupOwners(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(owners, ShouldResemble, []string{"user-1", "user-2"})
+
+			isOwner, err := ownerStore.IsGroupOwner(ctx, "group-1", "user-1")
+			So(err, ShouldBeNil)
+			So(isOwner, ShouldBeTrue)
+
+			isOwner, err = ownerStore.IsGroupOwner(ctx, "group-2", "user-1")
+			So(err, ShouldBeNil)
+			So(isOwner, ShouldBeFalse)
+		})
+
+		Convey("Removing an owner reports whether they were one", func() {
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-1"), ShouldBeNil)
+
+			removed, err := ownerStore.RemoveGroupOwner(ctx, "group-1", "user-1")
+			So(err, ShouldBeNil)
+			So(removed, ShouldBeTrue)
+
+			removed, err = ownerStore.RemoveGroupOwner(ctx, "group-1", "user-1")
+			So(err, ShouldBeNil)
+			So(removed, ShouldBeFalse)
+		})
+
+		Convey("Deleting a group's owners removes them all", func() {
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-1"), ShouldBeNil)
+			So(ownerStore.AddGroupOwner(ctx, "group-1", "user-2"), ShouldBeNil)
+			So(ownerStore.DeleteGroupOwners(ctx, "group-1"), ShouldBeNil)
+
+			owners, err := ownerStore.ListGroupOwners(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(owners, ShouldBeEmpty)
+		})
+	})
+}
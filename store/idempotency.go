@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+)
+
+var (
+	// idempotencySweepInterval is how many keys the in-memory store starts between removing expired keys
+	idempotencySweepInterval = 1000
+)
+
+// IdempotencyRecord is what is known about the first request made with an idempotency key
+type IdempotencyRecord struct {
+	// RequestHash identifies the request, so that a different request reusing the key can be refused
+	RequestHash string
+	// Response is the response to the request, or nil while it is still in progress
+	Response *models.SuccessResponse
+}
+
+// IdempotencyStore remembers the requests made with idempotency keys, so that a repeated request gets the original
+// response rather than being carried out again
+type IdempotencyStore interface {
+	// Start records that a request with the key is in progress, keeping the key for inProgressTTL so that a key whose
+	// request never completes, such as when an instance of the API stops, can soon be used again. If the key is
+	// already in use, started is false and the record of the first request is returned.
+	Start(ctx context.Context, key, requestHash string, inProgressTTL time.Duration) (record IdempotencyRecord, started bool, err error)
+	// Complete saves the response to the request started with the key, keeping the key for the window
+	Complete(ctx context.Context, key string, response models.SuccessResponse, window time.Duration) error
+	// Release forgets a key whose request failed, so that the request can be tried again
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore local to a single instance of the API
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	starts  int
+	now     func() time.Time
+}
+
+// NewInMemoryIdempotencyStore returns an empty InMemoryIdempotencyStore
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{entries: map[string]*idempotencyEntry{}, now: time.Now}
+}
+
+// Start records that a request with the key is in progress, unless the key is in use and has not expired
+func (s *InMemoryIdempotencyStore) Start(_ context.Context, key, requestHash string, inProgressTTL time.Duration) (record IdempotencyRecord, started bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.starts++
+	if s.starts%idempotencySweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		return entry.record, false, nil
+	}
+
+	s.entries[key] = &idempotencyEntry{record: IdempotencyRecord{RequestHash: requestHash}, expires: now.Add(inProgressTTL)}
+	return IdempotencyRecord{}, true, nil
+}
+
+// Complete saves the response to the request started with the key, unless the key has expired
+func (s *InMemoryIdempotencyStore) Complete(_ context.Context, key string, response models.SuccessResponse, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		entry.record.Response = &response
+		entry.expires = now.Add(window)
+	}
+	return nil
+}
+
+// Release forgets the key
+func (s *InMemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep removes the keys whose window has passed
+func (s *InMemoryIdempotencyStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	inProgressTTL, window := time.Minute, time.Hour
+
+	Convey("Given an in-memory idempotency store with a controllable clock", t, func() {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		idempotencyStore := NewInMemoryIdempotencyStore()
+		idempotencyStore.now = func() time.Time { return now }
+
+		_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-1", inProgressTTL)
+		So(err, ShouldBeNil)
+		So(started, ShouldBeTrue)
+
+		Convey("A key in progress cannot be started again", func() {
+			record, started, err := idempotencyStore.Start(ctx, "key-1", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeFalse)
+			So(record, ShouldResemble, IdempotencyRecord{RequestHash: "hash-1"})
+
+			Convey("Until its request has had time to complete", func() {
+				now = now.Add(inProgressTTL)
+				_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+				So(err, ShouldBeNil)
+				So(started, ShouldBeTrue)
+			})
+		})
+
+		Convey("A completed key returns the saved response", func() {
+			response := models.SuccessResponse{Body: []byte(`{"id":"1"}`), Status: http.StatusCreated}
+			So(idempotencyStore.Complete(ctx, "key-1", response, window), ShouldBeNil)
+
+			now = now.Add(inProgressTTL)
+			record, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeFalse)
+			So(record.RequestHash, ShouldEqual, "hash-1")
+			So(*record.Response, ShouldResemble, response)
+
+			Convey("Until the window has passed", func() {
+				now = now.Add(window)
+				_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+				So(err, ShouldBeNil)
+				So(started, ShouldBeTrue)
+			})
+		})
+
+		Convey("Completing a key that has expired does not bring it back", func() {
+			now = now.Add(inProgressTTL)
+			So(idempotencyStore.Complete(ctx, "key-1", models.SuccessResponse{Status: http.StatusCreated}, window), ShouldBeNil)
+			_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("A released key can be started again", func() {
+			So(idempotencyStore.Release(ctx, "key-1"), ShouldBeNil)
+			_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("Other keys are not affected", func() {
+			_, started, err := idempotencyStore.Start(ctx, "key-2", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("Expired keys are removed", func() {
+			idempotencyStore.starts = idempotencySweepInterval - 1
+			now = now.Add(inProgressTTL)
+			_, _, err := idempotencyStore.Start(ctx, "key-2", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(idempotencyStore.entries, ShouldNotContainKey, "key-1")
+			So(idempotencyStore.entries, ShouldContainKey, "key-2")
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MembershipExpiry is the time a user's membership of a group ends
+type MembershipExpiry struct {
+	GroupID   string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// MembershipExpiryStore persists the expiry times of temporary group memberships
+type MembershipExpiryStore interface {
+	SetMembershipExpiry(ctx context.Context, groupID, userID string, expiresAt time.Time) error
+	ClearMembershipExpiry(ctx context.Context, groupID, userID string) error
+	// ClearExpiredMembership clears the membership's expiry only if it is still the one it was listed with. Renewed
+	// reports that the membership has been given another expiry since, which is kept.
+	ClearExpiredMembership(ctx context.Context, membership MembershipExpiry) (renewed bool, err error)
+	// ListGroupMembershipExpiries returns the expiry times of the group's temporary members, keyed by user ID
+	ListGroupMembershipExpiries(ctx context.Context, groupID string) (map[string]time.Time, error)
+	// ListUserMembershipExpiries returns the expiry times of the user's temporary memberships, keyed by group ID
+	ListUserMembershipExpiries(ctx context.Context, userID string) (map[string]time.Time, error)
+	// ListExpiredMemberships returns the memberships that expired before now, earliest first
+	ListExpiredMemberships(ctx context.Context, now time.Time) ([]MembershipExpiry, error)
+	DeleteGroupMembershipExpiries(ctx context.Context, groupID string) error
+}
+
+type membershipKey struct {
+	groupID string
+	userID  string
+}
+
+// InMemoryMembershipExpiryStore is a MembershipExpiryStore local to a single instance of the API
+type InMemoryMembershipExpiryStore struct {
+	mu       sync.RWMutex
+	expiries map[membershipKey]time.Time
+}
+
+// NewInMemoryMembershipExpiryStore returns an empty InMemoryMembershipExpiryStore
+func NewInMemoryMembershipExpiryStore() *InMemoryMembershipExpiryStore {
+	return &InMemoryMembershipExpiryStore{expiries: map[membershipKey]time.Time{}}
+}
+
+// SetMembershipExpiry sets the time the user's membership of the group ends
+func (s *InMemoryMembershipExpiryStore) SetMembershipExpiry(_ context.Context, groupID, userID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expiries[membershipKey{groupID, userID}] = expiresAt
+	return nil
+}
+
+// ClearMembershipExpiry makes the user's membership of the group permanent
+func (s *InMemoryMembershipExpiryStore) ClearMembershipExpiry(_ context.Context, groupID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.expiries, membershipKey{groupID, userID})
+	return nil
+}
+
+// ClearExpiredMembership clears the membership's expiry only if it is still the one it was listed with
+func (s *InMemoryMembershipExpiryStore) ClearExpiredMembership(_ context.Context, membership MembershipExpiry) (renewed bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := membershipKey{membership.GroupID, membership.UserID}
+	expiresAt, ok := s.expiries[key]
+	if !ok {
+		return false, nil
+	}
+	if !expiresAt.Equal(membership.ExpiresAt) {
+		return true, nil
+	}
+	delete(s.expiries, key)
+	return false, nil
+}
+
+// ListGroupMembershipExpiries returns the expiry times of the group's temporary members, keyed by user ID
+func (s *InMemoryMembershipExpiryStore) ListGroupMembershipExpiries(_ context.Context, groupID string) (map[string]time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiries := map[string]time.Time{}
+	for key, expiresAt := range s.expiries {
+		if key.groupID == groupID {
+			expiries[key.userID] = expiresAt
+		}
+	}
+	return expiries, nil
+}
+
+// ListUserMembershipExpiries returns the expiry times of the user's temporary memberships, keyed by group ID
+func (s *InMemoryMembershipExpiryStore) ListUserMembershipExpiries(_ context.Context, userID string) (map[string]time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiries := map[string]time.Time{}
+	for key, expiresAt := range s.expiries {
+		if key.userID == userID {
+			expiries[key.groupID] = expiresAt
+		}
+	}
+	return expiries, nil
+}
+
+// ListExpiredMemberships returns the memberships that expired before now, earliest first
+func (s *InMemoryMembershipExpiryStore) ListExpiredMemberships(_ context.Context, now time.Time) ([]MembershipExpiry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var expired []MembershipExpiry
+	for key, expiresAt := range s.expiries {
+		if expiresAt.Before(now) {
+			expired = append(expired, MembershipExpiry{GroupID: key.groupID, UserID: key.userID, ExpiresAt: expiresAt})
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	return expired, nil
+}
+
+// DeleteGroupMembershipExpiries removes the expiry times of all of the group's memberships
+func (s *InMemoryMembershipExpiryStore) DeleteGroupMembershipExpiries(_ context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.expiries {
+		if key.groupID == groupID {
+			delete(s.expiries, key)
+		}
+	}
+	return nil
+}
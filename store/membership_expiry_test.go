@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryMembershipExpiryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	Convey("Given temporary memberships of two groups", t, func() {
+		expiryStore := NewInMemoryMembershipExpiryStore()
+		So(expiryStore.SetMembershipExpiry(ctx, "group-1", "user-1", now.Add(-time.Minute)), ShouldBeNil)
+		So(expiryStore.SetMembershipExpiry(ctx, "group-1", "user-2", now.Add(time.Hour)), ShouldBeNil)
+		So(expiryStore.SetMembershipExpiry(ctx, "group-2", "user-1", now.Add(-time.Hour)), ShouldBeNil)
+
+		Convey("The expiries are listed by group and by user", func() {
+			groupExpiries, err := expiryStore.ListGroupMembershipExpiries(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupExpiries, ShouldResemble, map[string]time.Time{"user-1": now.Add(-time.Minute), "user-2": now.Add(time.Hour)})
+
+			userExpiries, err := expiryStore.ListUserMembershipExpiries(ctx, "user-1")
+			So(err, ShouldBeNil)
+			So(userExpiries, ShouldResemble, map[string]time.Time{"group-1": now.Add(-time.Minute), "group-2": now.Add(-time.Hour)})
+		})
+
+		Convey("The expired memberships are listed earliest first", func() {
+			expired, err := expiryStore.ListExpiredMemberships(ctx, now)
+			So(err, ShouldBeNil)
+			So(expired, ShouldResemble, []MembershipExpiry{
+				{GroupID: "group-2", UserID: "user-1", ExpiresAt: now.Add(-time.Hour)},
+				{GroupID: "group-1", UserID: "user-1", ExpiresAt: now.Add(-time.Minute)},
+			})
+		})
+
+		Convey("A cleared membership no longer expires", func() {
+			So(expiryStore.ClearMembershipExpiry(ctx, "group-2", "user-1"), ShouldBeNil)
+
+			expired, err := expiryStore.ListExpiredMemberships(ctx, now)
+			So(err, ShouldBeNil)
+			So(len(expired), ShouldEqual, 1)
+			So(expired[0].GroupID, ShouldEqual, "group-1")
+		})
+
+		Convey("An expired membership is only cleared while it has the expiry it was listed with", func() {
+			expired, err := expiryStore.ListExpiredMemberships(ctx, now)
+			So(err, ShouldBeNil)
+			So(expiryStore.SetMembershipExpiry(ctx, "group-1", "user-1", now.Add(time.Hour)), ShouldBeNil)
+
+			renewed, err := expiryStore.ClearExpiredMembership(ctx, expired[1])
+			So(err, ShouldBeNil)
+			So(renewed, ShouldBeTrue)
+
+			renewed, err = expiryStore.ClearExpiredMembership(ctx, expired[0])
+			So(err, ShouldBeNil)
+			So(renewed, ShouldBeFalse)
+
+			userExpiries, err := expiryStore.ListUserMembershipExpiries(ctx, "user-1")
+			So(err, ShouldBeNil)
+			So(userExpiries, ShouldResemble, map[string]time.Time{"group-1": now.Add(time.Hour)})
+
+			Convey("And clearing it again does nothing", func() {
+				renewed, err := expiryStore.ClearExpiredMembership(ctx, expired[0])
+				So(err, ShouldBeNil)
+				So(renewed, ShouldBeFalse)
+			})
+		})
+
+		Convey("Deleting a group's expiries leaves other groups alone", func() {
+			So(expiryStore.DeleteGroupMembershipExpiries(ctx, "group-1"), ShouldBeNil)
+
+			groupExpiries, err := expiryStore.ListGroupMembershipExpiries(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupExpiries, ShouldBeEmpty)
+
+			userExpiries, err := expiryStore.ListUserMembershipExpiries(ctx, "user-1")
+			So(err, ShouldBeNil)
+			So(userExpiries, ShouldResemble, map[string]time.Time{"group-2": now.Add(-time.Hour)})
+		})
+	})
+}
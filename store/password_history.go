@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"sync"
+)
+
+var (
+	// passwordHashIterations follows the OWASP recommendation for PBKDF2-HMAC-SHA512
+	passwordHashIterations = 210000
+	passwordSaltLength     = 16
+	passwordHashLength     = 32
+)
+
+// PasswordHash is a salted hash of a previously used password
+type PasswordHash struct {
+	Salt []byte
+	Hash []byte
+}
+
+// HashPassword hashes the password with a new random salt
+func HashPassword(password string) (PasswordHash, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return PasswordHash{}, err
+	}
+
+	hash, err := pbkdf2.Key(sha512.New, password, salt, passwordHashIterations, passwordHashLength)
+	if err != nil {
+		return PasswordHash{}, err
+	}
+
+	return PasswordHash{Salt: salt, Hash: hash}, nil
+}
+
+// Matches reports whether the password produces this hash
+func (h PasswordHash) Matches(password string) (bool, error) {
+	hash, err := pbkdf2.Key(sha512.New, password, h.Salt, passwordHashIterations, len(h.Hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(hash, h.Hash) == 1, nil
+}
+
+// PasswordHistoryStore persists the password hashes kept for each user, most recent first
+type PasswordHistoryStore interface {
+	GetPasswordHistory(ctx context.Context, userID string) ([]PasswordHash, error)
+	SetPasswordHistory(ctx context.Context, userID string, hashes []PasswordHash) error
+}
+
+// PasswordHistory keeps salted hashes of each user's last few passwords so that they cannot be reused
+type PasswordHistory struct {
+	Store PasswordHistoryStore
+	Size  int
+}
+
+// NewPasswordHistory returns a PasswordHistory keeping the last size passwords of each user in the given store
+func NewPasswordHistory(historyStore PasswordHistoryStore, size int) *PasswordHistory {
+	return &PasswordHistory{Store: historyStore, Size: size}
+}
+
+// Contains reports whether the password is one of the user's recent passwords
+func (p *PasswordHistory) Contains(ctx context.Context, userID, password string) (bool, error) {
+	hashes, err := p.Store.GetPasswordHistory(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		matches, err := hash.Matches(password)
+		if err != nil {
+			return false, err
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Add records the password as the user's most recent, dropping the oldest once the history is full
+func (p *PasswordHistory) Add(ctx context.Context, userID, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	hashes, err := p.Store.GetPasswordHistory(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	hashes = append([]PasswordHash{hash}, hashes...)
+	if len(hashes) > p.Size {
+		hashes = hashes[:p.Size]
+	}
+
+	return p.Store.SetPasswordHistory(ctx, userID, hashes)
+}
+
+// InMemoryPasswordHistoryStore is a PasswordHistoryStore for tests and local development, its contents are lost on restart
+type InMemoryPasswordHistoryStore struct {
+	mu        sync.RWMutex
+	histories map[string][]PasswordHash
+}
+
+// NewInMemoryPasswordHistoryStore returns an empty InMemoryPasswordHistoryStore
+func NewInMemoryPasswordHistoryStore() *InMemoryPasswordHistoryStore {
+	return &InMemoryPasswordHistoryStore{histories: map[string][]PasswordHash{}}
+}
+
+// GetPasswordHistory returns the password hashes kept for the user
+func (s *InMemoryPasswordHistoryStore) GetPasswordHistory(_ context.Context, userID string) ([]PasswordHash, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]PasswordHash(nil), s.histories[userID]...), nil
+}
+
+// SetPasswordHistory replaces the password hashes kept for the user
+func (s *InMemoryPasswordHistoryStore) SetPasswordHistory(_ context.Context, userID string, hashes []PasswordHash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.histories[userID] = append([]PasswordHash(nil), hashes...)
+	return nil
+}
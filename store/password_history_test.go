@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPasswordHash(t *testing.T) {
+	Convey("A password hash matches only the password it was made from", t, func() {
+		hash, err := HashPassword("Passw0rd!")
+		So(err, ShouldBeNil)
+		So(len(hash.Salt), ShouldEqual, passwordSaltLength)
+		So(len(hash.Hash), ShouldEqual, passwordHashLength)
+
+		matches, err := hash.Matches("Passw0rd!")
+		So(err, ShouldBeNil)
+		So(matches, ShouldBeTrue)
+
+		matches, err = hash.Matches("Passw0rd?")
+		So(err, ShouldBeNil)
+		So(matches, ShouldBeFalse)
+	})
+
+	Convey("Hashing the same password twice uses different salts", t, func() {
+		first, err := HashPassword("Passw0rd!")
+		So(err, ShouldBeNil)
+		second, err := HashPassword("Passw0rd!")
+		So(err, ShouldBeNil)
+
+		So(first.Salt, ShouldNotResemble, second.Salt)
+		So(first.Hash, ShouldNotResemble, second.Hash)
+	})
+}
+
+func TestPasswordHistory(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a password history keeping the last two passwords", t, func() {
+		history := NewPasswordHistory(NewInMemoryPasswordHistoryStore(), 2)
+
+		Convey("An unknown user has no previous passwords", func() {
+			contains, err := history.Contains(ctx, "abcd1234", "first")
+			So(err, ShouldBeNil)
+			So(contains, ShouldBeFalse)
+		})
+
+		Convey("When three passwords are added for a user", func() {
+			for _, password := range []string{"first", "second", "third"} {
+				So(history.Add(ctx, "abcd1234", password), ShouldBeNil)
+			}
+
+			Convey("Then only the last two are kept", func() {
+				hashes, err := history.Store.GetPasswordHistory(ctx, "abcd1234")
+				So(err, ShouldBeNil)
+				So(len(hashes), ShouldEqual, 2)
+
+				for password, expected := range map[string]bool{"first": false, "second": true, "third": true} {
+					contains, err := history.Contains(ctx, "abcd1234", password)
+					So(err, ShouldBeNil)
+					So(contains, ShouldEqual, expected)
+				}
+			})
+
+			Convey("Then other users are not affected", func() {
+				contains, err := history.Contains(ctx, "efgh5678", "third")
+				So(err, ShouldBeNil)
+				So(contains, ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// rateLimitSweepInterval is how many requests the in-memory store handles between removing idle buckets
+	rateLimitSweepInterval = 1000
+)
+
+// RateLimit describes a token bucket: up to Burst requests at once, refilling one token every Interval
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// RateLimitStore holds token buckets by key, so that the limits can be shared between instances of the API
+type RateLimitStore interface {
+	// Take removes a token from the key's bucket. If the bucket is empty the request is not allowed and retryAfter
+	// is how long until a token will be available.
+	Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+// InMemoryRateLimitStore is a RateLimitStore local to a single instance of the API
+type InMemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+	now     func() time.Time
+}
+
+// NewInMemoryRateLimitStore returns an empty InMemoryRateLimitStore
+func NewInMemoryRateLimitStore() *InMemoryRateLimitStore {
+	return &InMemoryRateLimitStore{buckets: map[string]*tokenBucket{}, now: time.Now}
+}
+
+// Take removes a token from the key's bucket, refilling it for the time passed since it was last used
+func (s *InMemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.takes++
+	if s.takes%rateLimitSweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+float64(now.Sub(bucket.updated))/float64(limit.Interval))
+	bucket.updated = now
+	bucket.limit = limit
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(limit.Interval)), nil
+	}
+	bucket.tokens--
+	return true, 0, nil
+}
+
+// sweep removes buckets that have been idle long enough to refill, as they are no different to a new bucket
+func (s *InMemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= time.Duration(bucket.limit.Burst)*bucket.limit.Interval {
+			delete(s.buckets, key)
+		}
+	}
+}
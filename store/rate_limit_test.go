@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	limit := RateLimit{Burst: 2, Interval: 10 * time.Second}
+
+	Convey("Given an in-memory rate limit store with a controllable clock", t, func() {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		rateLimitStore := NewInMemoryRateLimitStore()
+		rateLimitStore.now = func() time.Time { return now }
+
+		Convey("A key may make up to the burst of requests at once", func() {
+			for i := 0; i < limit.Burst; i++ {
+				allowed, retryAfter, err := rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+				So(retryAfter, ShouldEqual, 0)
+			}
+
+			Convey("Then further requests are refused until a token is refilled", func() {
+				now = now.Add(4 * time.Second)
+				allowed, retryAfter, err := rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeFalse)
+				So(retryAfter, ShouldEqual, 6*time.Second)
+
+				now = now.Add(6 * time.Second)
+				allowed, _, err = rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+			})
+
+			Convey("Then other keys are not affected", func() {
+				allowed, _, err := rateLimitStore.Take(ctx, "ip:10.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+			})
+		})
+
+		Convey("Idle buckets that have refilled are removed", func() {
+			rateLimitStore.takes = rateLimitSweepInterval - 3
+			_, _, err := rateLimitStore.Take(ctx, "idle", limit)
+			So(err, ShouldBeNil)
+			_, _, err = rateLimitStore.Take(ctx, "busy", RateLimit{Burst: 1, Interval: time.Hour})
+			So(err, ShouldBeNil)
+
+			now = now.Add(limit.Interval * time.Duration(limit.Burst))
+			_, _, err = rateLimitStore.Take(ctx, "other", limit)
+			So(err, ShouldBeNil)
+
+			So(rateLimitStore.buckets, ShouldNotContainKey, "idle")
+			So(rateLimitStore.buckets, ShouldContainKey, "busy")
+		})
+	})
+}
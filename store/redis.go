@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisKeyPrefix namespaces every key written by the Redis stores, which are shared by every instance of the API
+const RedisKeyPrefix = "dp-identity-api:"
+
+// maxRedisTransactionAttempts is how many times a transaction is tried when other instances of the API keep changing
+// the keys it watches
+const maxRedisTransactionAttempts = 10
+
+// watchAndRetry runs the transaction with the keys watched, so that it only commits if none of them change while it
+// runs, trying it again when one does
+func watchAndRetry(ctx context.Context, client redis.UniversalClient, transaction func(*redis.Tx) error, keys ...string) error {
+	for attempt := 0; attempt < maxRedisTransactionAttempts; attempt++ {
+		err := client.Watch(ctx, transaction, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const accessRequestsKeyPrefix = RedisKeyPrefix + "access-requests:"
+
+// RedisAccessRequestStore is an AccessRequestStore shared by every instance of the API, holding each group's access
+// requests as JSON fields of a hash
+type RedisAccessRequestStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisAccessRequestStore returns a RedisAccessRequestStore using the given client
+func NewRedisAccessRequestStore(client redis.UniversalClient) *RedisAccessRequestStore {
+	return &RedisAccessRequestStore{client: client}
+}
+
+// CreateAccessRequest stores a new access request, unless the user already has a pending one for the group. The
+// group's requests are watched while they are checked, so a request made at the same time by another instance is seen.
+func (s *RedisAccessRequestStore) CreateAccessRequest(ctx context.Context, request models.AccessRequest) error {
+	key := accessRequestsKeyPrefix + request.GroupID
+	value, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		pending, err := listAccessRequests(ctx, tx, request.GroupID, models.AccessRequestPending)
+		if err != nil {
+			return err
+		}
+		for i := range pending {
+			if pending[i].UserID == request.UserID {
+				return ErrAccessRequestExists
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, request.ID, value)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// GetAccessRequest returns the access request, or ErrAccessRequestNotFound
+func (s *RedisAccessRequestStore) GetAccessRequest(ctx context.Context, groupID, requestID string) (models.AccessRequest, error) {
+	return getAccessRequest(ctx, s.client, groupID, requestID)
+}
+
+func getAccessRequest(ctx context.Context, client redis.Cmdable, groupID, requestID string) (models.AccessRequest, error) {
+	var request models.AccessRequest
+
+	value, err := client.HGet(ctx, accessRequestsKeyPrefix+groupID, requestID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return request, ErrAccessRequestNotFound
+	}
+	if err != nil {
+		return request, err
+	}
+
+	err = json.Unmarshal(value, &request)
+	return request, err
+}
+
+// ListAccessRequests returns the group's access requests with the status, or all of them when the status is empty,
+// oldest first
+func (s *RedisAccessRequestStore) ListAccessRequests(ctx context.Context, groupID, status string) ([]models.AccessRequest, error) {
+	return listAccessRequests(ctx, s.client, groupID, status)
+}
+
+func listAccessRequests(ctx context.Context, client redis.Cmdable, groupID, status string) ([]models.AccessRequest, error) {
+	values, err := client.HVals(ctx, accessRequestsKeyPrefix+groupID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []models.AccessRequest{}
+	for _, value := range values {
+		var request models.AccessRequest
+		if err := json.Unmarshal([]byte(value), &request); err != nil {
+			return nil, err
+		}
+		if status == "" || request.Status == status {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].Created.Equal(requests[j].Created) {
+			return requests[i].ID < requests[j].ID
+		}
+		return requests[i].Created.Before(requests[j].Created)
+	})
+	return requests, nil
+}
+
+// DecideAccessRequest records the decision on a pending access request. The group's requests are watched while the
+// request is checked, so the decision is only written if the request was still pending.
+func (s *RedisAccessRequestStore) DecideAccessRequest(ctx context.Context, groupID, requestID string, decision models.AccessRequestDecision) (models.AccessRequest, error) {
+	var request models.AccessRequest
+	err := s.updateAccessRequest(ctx, groupID, requestID, func(stored *models.AccessRequest) error {
+		request = *stored
+		if stored.Status != models.AccessRequestPending {
+			return ErrAccessRequestDecided
+		}
+		stored.Decide(decision)
+		request = *stored
+		return nil
+	})
+	return request, err
+}
+
+// ReopenAccessRequest returns a decided access request to pending
+func (s *RedisAccessRequestStore) ReopenAccessRequest(ctx context.Context, groupID, requestID string) error {
+	return s.updateAccessRequest(ctx, groupID, requestID, func(stored *models.AccessRequest) error {
+		stored.Reopen()
+		return nil
+	})
+}
+
+// updateAccessRequest applies the update to the stored access request, trying again if the group's access requests
+// change before it is written
+func (s *RedisAccessRequestStore) updateAccessRequest(ctx context.Context, groupID, requestID string, update func(*models.AccessRequest) error) error {
+	key := accessRequestsKeyPrefix + groupID
+
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		request, err := getAccessRequest(ctx, tx, groupID, requestID)
+		if err != nil {
+			return err
+		}
+		if err = update(&request); err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, requestID, value)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// DeleteGroupAccessRequests removes all of the group's access requests
+func (s *RedisAccessRequestStore) DeleteGroupAccessRequests(ctx context.Context, groupID string) error {
+	return s.client.Del(ctx, accessRequestsKeyPrefix+groupID).Err()
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedisAccessRequestStore(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	Convey("Given a group with two access requests in a Redis store", t, func() {
+		server, client := newTestRedisClient(t)
+		requestStore := NewRedisAccessRequestStore(client)
+		first := models.AccessRequest{ID: "request-1", GroupID: "group-1", UserID: "user-1", Status: models.AccessRequestPending, Created: created}
+		second := models.AccessRequest{ID: "request-2", GroupID: "group-1", UserID: "user-2", Status: models.AccessRequestPending, Created: created.Add(time.Minute)}
+		So(requestStore.CreateAccessRequest(ctx, second), ShouldBeNil)
+		So(requestStore.CreateAccessRequest(ctx, first), ShouldBeNil)
+
+		Convey("They are listed oldest first", func() {
+			requests, err := requestStore.ListAccessRequests(ctx, "group-1", "")
+			So(err, ShouldBeNil)
+			So(requests, ShouldResemble, []models.AccessRequest{first, second})
+
+			requests, err = requestStore.ListAccessRequests(ctx, "group-2", "")
+			So(err, ShouldBeNil)
+			So(requests, ShouldNotBeNil)
+			So(requests, ShouldBeEmpty)
+		})
+
+		Convey("A decision is recorded only once", func() {
+			decision := models.AccessRequestDecision{Status: models.AccessRequestApproved, DecidedBy: "owner-1", Decided: created.Add(time.Hour)}
+			decided, err := requestStore.DecideAccessRequest(ctx, "group-1", "request-1", decision)
+			So(err, ShouldBeNil)
+			So(decided.Status, ShouldEqual, models.AccessRequestApproved)
+			So(decided.DecidedBy, ShouldEqual, "owner-1")
+
+			stored, err := requestStore.GetAccessRequest(ctx, "group-1", "request-1")
+			So(err, ShouldBeNil)
+			So(stored, ShouldResemble, decided)
+
+			decision.Status = models.AccessRequestRejected
+			current, err := requestStore.DecideAccessRequest(ctx, "group-1", "request-1", decision)
+			So(err, ShouldEqual, ErrAccessRequestDecided)
+			So(current.Status, ShouldEqual, models.AccessRequestApproved)
+
+			pending, err := requestStore.ListAccessRequests(ctx, "group-1", models.AccessRequestPending)
+			So(err, ShouldBeNil)
+			So(pending, ShouldResemble, []models.AccessRequest{second})
+		})
+
+		Convey("Only one of many decisions made at the same time by different instances is recorded", func() {
+			var wg sync.WaitGroup
+			results := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					decision := models.AccessRequestDecision{Status: models.AccessRequestRejected, DecidedBy: "owner-1", Decided: created.Add(time.Hour)}
+					_, err := NewRedisAccessRequestStore(client).DecideAccessRequest(ctx, "group-1", "request-1", decision)
+					results <- err
+				}()
+			}
+			wg.Wait()
+			close(results)
+
+			recorded := 0
+			for err := range results {
+				if err == nil {
+					recorded++
+				} else {
+					So(err, ShouldEqual, ErrAccessRequestDecided)
+				}
+			}
+			So(recorded, ShouldEqual, 1)
+		})
+
+		Convey("A user with a pending request cannot make another until it is decided", func() {
+			another := models.AccessRequest{ID: "request-3", GroupID: "group-1", UserID: "user-1", Status: models.AccessRequestPending, Created: created.Add(time.Hour)}
+			So(requestStore.CreateAccessRequest(ctx, another), ShouldEqual, ErrAccessRequestExists)
+
+			decision := models.AccessRequestDecision{Status: models.AccessRequestRejected, DecidedBy: "owner-1", Decided: created.Add(time.Hour)}
+			_, err := requestStore.DecideAccessRequest(ctx, "group-1", "request-1", decision)
+			So(err, ShouldBeNil)
+			So(requestStore.CreateAccessRequest(ctx, another), ShouldBeNil)
+		})
+
+		Convey("Only one of many requests made at the same time by a user through different instances is stored", func() {
+			var wg sync.WaitGroup
+			results := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					request := models.AccessRequest{ID: fmt.Sprintf("request-user-3-%d", i), GroupID: "group-1", UserID: "user-3", Status: models.AccessRequestPending, Created: created}
+					results <- NewRedisAccessRequestStore(client).CreateAccessRequest(ctx, request)
+				}(i)
+			}
+			wg.Wait()
+			close(results)
+
+			stored := 0
+			for err := range results {
+				if err == nil {
+					stored++
+				} else {
+					So(err, ShouldEqual, ErrAccessRequestExists)
+				}
+			}
+			So(stored, ShouldEqual, 1)
+		})
+
+		Convey("A reopened request can be decided again", func() {
+			decision := models.AccessRequestDecision{Status: models.AccessRequestApproved, DecidedBy: "owner-1", Decided: created.Add(time.Hour), Note: "welcome"}
+			_, err := requestStore.DecideAccessRequest(ctx, "group-1", "request-1", decision)
+			So(err, ShouldBeNil)
+
+			So(requestStore.ReopenAccessRequest(ctx, "group-1", "request-1"), ShouldBeNil)
+			stored, err := requestStore.GetAccessRequest(ctx, "group-1", "request-1")
+			So(err, ShouldBeNil)
+			So(stored, ShouldResemble, first)
+
+			_, err = requestStore.DecideAccessRequest(ctx, "group-1", "request-1", decision)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Unknown requests are not found", func() {
+			_, err := requestStore.GetAccessRequest(ctx, "group-2", "request-1")
+			So(err, ShouldEqual, ErrAccessRequestNotFound)
+
+			_, err = requestStore.DecideAccessRequest(ctx, "group-1", "request-3", models.AccessRequestDecision{Status: models.AccessRequestRejected})
+			So(err, ShouldEqual, ErrAccessRequestNotFound)
+
+			So(requestStore.ReopenAccessRequest(ctx, "group-1", "request-3"), ShouldEqual, ErrAccessRequestNotFound)
+		})
+
+		Convey("Deleting the group's requests removes them", func() {
+			So(requestStore.DeleteGroupAccessRequests(ctx, "group-1"), ShouldBeNil)
+			_, err := requestStore.GetAccessRequest(ctx, "group-1", "request-1")
+			So(err, ShouldEqual, ErrAccessRequestNotFound)
+		})
+
+		Convey("An error is returned when Redis cannot be reached", func() {
+			server.Close()
+
+			_, err := requestStore.DecideAccessRequest(ctx, "group-1", "request-1", models.AccessRequestDecision{Status: models.AccessRequestRejected})
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, ErrAccessRequestNotFound)
+		})
+	})
+}
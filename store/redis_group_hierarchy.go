@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	groupChildrenKeyPrefix = RedisKeyPrefix + "group-children:"
+	groupParentsKeyPrefix  = RedisKeyPrefix + "group-parents:"
+
+	// groupHierarchyVersionKey is incremented by every change to the hierarchy, so that a change checked against the
+	// whole hierarchy is only made if nothing else has changed it in the meantime
+	groupHierarchyVersionKey = RedisKeyPrefix + "group-hierarchy-version"
+)
+
+// RedisGroupHierarchyStore is a GroupHierarchyStore shared by every instance of the API, holding each group's children
+// and parents in sets
+type RedisGroupHierarchyStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisGroupHierarchyStore returns a RedisGroupHierarchyStore using the given client
+func NewRedisGroupHierarchyStore(client redis.UniversalClient) *RedisGroupHierarchyStore {
+	return &RedisGroupHierarchyStore{client: client}
+}
+
+// ListChildGroups returns the IDs of the groups nested directly in the group, in order
+func (s *RedisGroupHierarchyStore) ListChildGroups(ctx context.Context, groupID string) ([]string, error) {
+	children, err := s.client.SMembers(ctx, groupChildrenKeyPrefix+groupID).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+// ListAncestorGroups returns the IDs of the groups that any of the groups are nested in, directly or indirectly
+func (s *RedisGroupHierarchyStore) ListAncestorGroups(ctx context.Context, groupIDs []string) ([]string, error) {
+	ancestors, err := reachableInRedis(ctx, s.client, groupParentsKeyPrefix, groupIDs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupID := range groupIDs {
+		delete(ancestors, groupID)
+	}
+	return sortedKeys(ancestors), nil
+}
+
+// ListDescendantGroups returns the IDs of the groups nested in the group, directly or indirectly
+func (s *RedisGroupHierarchyStore) ListDescendantGroups(ctx context.Context, groupID string) ([]string, error) {
+	descendants, err := reachableInRedis(ctx, s.client, groupChildrenKeyPrefix, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(descendants), nil
+}
+
+// AddChildGroup nests the child group in the parent group unless that would create a cycle. The hierarchy's version is
+// watched while the child's descendants are checked, so two changes that together would create a cycle cannot both
+// be made.
+func (s *RedisGroupHierarchyStore) AddChildGroup(ctx context.Context, parentID, childID string) error {
+	if parentID == childID {
+		return ErrGroupCycle
+	}
+
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		descendants, err := reachableInRedis(ctx, tx, groupChildrenKeyPrefix, childID)
+		if err != nil {
+			return err
+		}
+		if _, ok := descendants[parentID]; ok {
+			return ErrGroupCycle
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SAdd(ctx, groupChildrenKeyPrefix+parentID, childID)
+			pipe.SAdd(ctx, groupParentsKeyPrefix+childID, parentID)
+			pipe.Incr(ctx, groupHierarchyVersionKey)
+			return nil
+		})
+		return err
+	}, groupHierarchyVersionKey)
+}
+
+// RemoveChildGroup stops the child group being nested in the parent group, reporting whether it was
+func (s *RedisGroupHierarchyStore) RemoveChildGroup(ctx context.Context, parentID, childID string) (bool, error) {
+	var removed *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.SRem(ctx, groupChildrenKeyPrefix+parentID, childID)
+		pipe.SRem(ctx, groupParentsKeyPrefix+childID, parentID)
+		pipe.Incr(ctx, groupHierarchyVersionKey)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return removed.Val() > 0, nil
+}
+
+// DeleteGroupHierarchy removes the group from the hierarchy, both as a parent and as a child
+func (s *RedisGroupHierarchyStore) DeleteGroupHierarchy(ctx context.Context, groupID string) error {
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		children, err := tx.SMembers(ctx, groupChildrenKeyPrefix+groupID).Result()
+		if err != nil {
+			return err
+		}
+		parents, err := tx.SMembers(ctx, groupParentsKeyPrefix+groupID).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, childID := range children {
+				pipe.SRem(ctx, groupParentsKeyPrefix+childID, groupID)
+			}
+			for _, parentID := range parents {
+				pipe.SRem(ctx, groupChildrenKeyPrefix+parentID, groupID)
+			}
+			pipe.Del(ctx, groupChildrenKeyPrefix+groupID, groupParentsKeyPrefix+groupID)
+			pipe.Incr(ctx, groupHierarchyVersionKey)
+			return nil
+		})
+		return err
+	}, groupHierarchyVersionKey)
+}
+
+// reachableInRedis returns the groups that can be reached from the starting groups by following the edges held in
+// the sets with the key prefix, fetching each level of the hierarchy at once
+func reachableInRedis(ctx context.Context, client redis.Cmdable, keyPrefix string, from ...string) (map[string]struct{}, error) {
+	found := map[string]struct{}{}
+	level := from
+	for len(level) > 0 {
+		cmds := make([]*redis.StringSliceCmd, len(level))
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, groupID := range level {
+				cmds[i] = pipe.SMembers(ctx, keyPrefix+groupID)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var next []string
+		for _, cmd := range cmds {
+			for _, groupID := range cmd.Val() {
+				if _, ok := found[groupID]; !ok {
+					found[groupID] = struct{}{}
+					next = append(next, groupID)
+				}
+			}
+		}
+		level = next
+	}
+	return found, nil
+}
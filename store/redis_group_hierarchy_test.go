@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedisGroupHierarchyStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given groups nested as economics > prices > inflation and economics > trade in a Redis store", t, func() {
+		server, client := newTestRedisClient(t)
+		hierarchyStore := NewRedisGroupHierarchyStore(client)
+		So(hierarchyStore.AddChildGroup(ctx, "economics", "prices"), ShouldBeNil)
+		So(hierarchyStore.AddChildGroup(ctx, "prices", "inflation"), ShouldBeNil)
+		So(hierarchyStore.AddChildGroup(ctx, "economics", "trade"), ShouldBeNil)
+
+		Convey("A group's direct children are listed in order", func() {
+			children, err := hierarchyStore.ListChildGroups(ctx, "economics")
+			So(err, ShouldBeNil)
+			So(children, ShouldResemble, []string{"prices", "trade"})
+
+			children, err = hierarchyStore.ListChildGroups(ctx, "trade")
+			So(err, ShouldBeNil)
+			So(children, ShouldNotBeNil)
+			So(children, ShouldBeEmpty)
+		})
+
+		Convey("A group's descendants include indirectly nested groups", func() {
+			descendants, err := hierarchyStore.ListDescendantGroups(ctx, "economics")
+			So(err, ShouldBeNil)
+			So(descendants, ShouldResemble, []string{"inflation", "prices", "trade"})
+		})
+
+		Convey("The ancestors of several groups are combined without the groups themselves", func() {
+			ancestors, err := hierarchyStore.ListAncestorGroups(ctx, []string{"inflation", "prices"})
+			So(err, ShouldBeNil)
+			So(ancestors, ShouldResemble, []string{"economics"})
+
+			ancestors, err = hierarchyStore.ListAncestorGroups(ctx, []string{"economics"})
+			So(err, ShouldBeNil)
+			So(ancestors, ShouldBeEmpty)
+		})
+
+		Convey("Nesting a group in itself or in one of its descendants is rejected", func() {
+			So(errors.Is(hierarchyStore.AddChildGroup(ctx, "prices", "prices"), ErrGroupCycle), ShouldBeTrue)
+			So(errors.Is(hierarchyStore.AddChildGroup(ctx, "prices", "economics"), ErrGroupCycle), ShouldBeTrue)
+			So(errors.Is(hierarchyStore.AddChildGroup(ctx, "inflation", "economics"), ErrGroupCycle), ShouldBeTrue)
+		})
+
+		Convey("A group can be nested in more than one parent", func() {
+			So(hierarchyStore.AddChildGroup(ctx, "trade", "inflation"), ShouldBeNil)
+
+			ancestors, err := hierarchyStore.ListAncestorGroups(ctx, []string{"inflation"})
+			So(err, ShouldBeNil)
+			So(ancestors, ShouldResemble, []string{"economics", "prices", "trade"})
+		})
+
+		Convey("Removing a child reports whether it was nested in the parent", func() {
+			removed, err := hierarchyStore.RemoveChildGroup(ctx, "economics", "prices")
+			So(err, ShouldBeNil)
+			So(removed, ShouldBeTrue)
+
+			removed, err = hierarchyStore.RemoveChildGroup(ctx, "economics", "prices")
+			So(err, ShouldBeNil)
+			So(removed, ShouldBeFalse)
+
+			So(hierarchyStore.AddChildGroup(ctx, "inflation", "economics"), ShouldBeNil)
+		})
+
+		Convey("Deleting a group removes it as both a parent and a child", func() {
+			So(hierarchyStore.DeleteGroupHierarchy(ctx, "prices"), ShouldBeNil)
+
+			children, err := hierarchyStore.ListChildGroups(ctx, "economics")
+			So(err, ShouldBeNil)
+			So(children, ShouldResemble, []string{"trade"})
+
+			ancestors, err := hierarchyStore.ListAncestorGroups(ctx, []string{"inflation"})
+			So(err, ShouldBeNil)
+			So(ancestors, ShouldBeEmpty)
+		})
+		Convey("Of two nestings made at the same time that together would create a cycle, only one is made", func() {
+			var wg sync.WaitGroup
+			results := make(chan error, 2)
+			for _, nesting := range [][2]string{{"trade", "inflation"}, {"inflation", "trade"}} {
+				wg.Add(1)
+				go func(parentID, childID string) {
+					defer wg.Done()
+					results <- NewRedisGroupHierarchyStore(client).AddChildGroup(ctx, parentID, childID)
+				}(nesting[0], nesting[1])
+			}
+			wg.Wait()
+			close(results)
+
+			cycles := 0
+			for err := range results {
+				if errors.Is(err, ErrGroupCycle) {
+					cycles++
+				}
+			}
+			So(cycles, ShouldEqual, 1)
+		})
+
+		Convey("An error is returned when Redis cannot be reached", func() {
+			server.Close()
+
+			_, err := hierarchyStore.ListAncestorGroups(ctx, []string{"inflation"})
+			So(err, ShouldNotBeNil)
+			So(hierarchyStore.AddChildGroup(ctx, "trade", "inflation"), ShouldNotBeNil)
+		})
+	})
+}
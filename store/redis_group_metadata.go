@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const groupMetadataKey = RedisKeyPrefix + "group-metadata"
+
+// RedisGroupMetadataStore is a GroupMetadataStore shared by every instance of the API, holding each group's
+// metadata as a JSON field of a single hash
+type RedisGroupMetadataStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisGroupMetadataStore returns a RedisGroupMetadataStore using the given client
+func NewRedisGroupMetadataStore(client redis.UniversalClient) *RedisGroupMetadataStore {
+	return &RedisGroupMetadataStore{client: client}
+}
+
+// GetGroupMetadata returns the group's metadata, which is empty if none has been set
+func (s *RedisGroupMetadataStore) GetGroupMetadata(ctx context.Context, groupID string) (models.GroupMetadata, error) {
+	var metadata models.GroupMetadata
+
+	value, err := s.client.HGet(ctx, groupMetadataKey, groupID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, err
+	}
+
+	err = json.Unmarshal(value, &metadata)
+	return metadata, err
+}
+
+// ListGroupMetadata returns the metadata of every group that has some
+func (s *RedisGroupMetadataStore) ListGroupMetadata(ctx context.Context) (map[string]models.GroupMetadata, error) {
+	values, err := s.client.HGetAll(ctx, groupMetadataKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]models.GroupMetadata, len(values))
+	for groupID, value := range values {
+		var groupMetadata models.GroupMetadata
+		if err := json.Unmarshal([]byte(value), &groupMetadata); err != nil {
+			return nil, err
+		}
+		metadata[groupID] = groupMetadata
+	}
+	return metadata, nil
+}
+
+// SetGroupMetadata replaces the group's metadata, removing it when it is empty
+func (s *RedisGroupMetadataStore) SetGroupMetadata(ctx context.Context, groupID string, metadata models.GroupMetadata) error {
+	if metadata == (models.GroupMetadata{}) {
+		return s.DeleteGroupMetadata(ctx, groupID)
+	}
+
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, groupMetadataKey, groupID, value).Err()
+}
+
+// DeleteGroupMetadata removes the group's metadata
+func (s *RedisGroupMetadataStore) DeleteGroupMetadata(ctx context.Context, groupID string) error {
+	return s.client.HDel(ctx, groupMetadataKey, groupID).Err()
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestRedisClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+func TestRedisGroupMetadataStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a Redis group metadata store", t, func() {
+		server, client := newTestRedisClient(t)
+		metadataStore := NewRedisGroupMetadataStore(client)
+		metadata := models.GroupMetadata{Description: "Publishing team", Owner: "Digital Publishing", ContactEmail: "publishing@ons.gov.uk"}
+
+		Convey("A group without metadata has empty metadata", func() {
+			groupMetadata, err := metadataStore.GetGroupMetadata(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupMetadata, ShouldResemble, models.GroupMetadata{})
+		})
+
+		Convey("Metadata that has been set is returned for the group and listed", func() {
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", metadata), ShouldBeNil)
+
+			groupMetadata, err := metadataStore.GetGroupMetadata(ctx, "group-1")
+			So(err, ShouldBeNil)
+			So(groupMetadata, ShouldResemble, metadata)
+
+			listed, err := metadataStore.ListGroupMetadata(ctx)
+			So(err, ShouldBeNil)
+			So(listed, ShouldResemble, map[string]models.GroupMetadata{"group-1": metadata})
+
+			Convey("And it is seen by another store sharing the instance", func() {
+				groupMetadata, err := NewRedisGroupMetadataStore(client).GetGroupMetadata(ctx, "group-1")
+				So(err, ShouldBeNil)
+				So(groupMetadata, ShouldResemble, metadata)
+			})
+		})
+
+		Convey("Setting empty metadata or deleting it removes the group from the list", func() {
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", metadata), ShouldBeNil)
+			So(metadataStore.SetGroupMetadata(ctx, "group-2", metadata), ShouldBeNil)
+
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", models.GroupMetadata{}), ShouldBeNil)
+			So(metadataStore.DeleteGroupMetadata(ctx, "group-2"), ShouldBeNil)
+
+			listed, err := metadataStore.ListGroupMetadata(ctx)
+			So(err, ShouldBeNil)
+			So(listed, ShouldBeEmpty)
+		})
+
+		Convey("An error is returned when Redis cannot be reached", func() {
+			server.Close()
+
+			_, err := metadataStore.GetGroupMetadata(ctx, "group-1")
+			So(err, ShouldNotBeNil)
+			So(metadataStore.SetGroupMetadata(ctx, "group-1", metadata), ShouldNotBeNil)
+		})
+	})
+}
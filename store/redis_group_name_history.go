@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	groupRenamesKeyPrefix       = RedisKeyPrefix + "group-renames:"
+	groupRenamesByNameKeyPrefix = RedisKeyPrefix + "group-renames-by-name:"
+
+	// removedGroupRename marks a rename in a group's list while it is removed
+	removedGroupRename = "removed"
+)
+
+// RedisGroupNameHistoryStore is a GroupNameHistoryStore shared by every instance of the API. Each group's renames are
+// held in a list, and the latest rename of each group away from a name is indexed by the cleaned name.
+type RedisGroupNameHistoryStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisGroupNameHistoryStore returns a RedisGroupNameHistoryStore using the given client
+func NewRedisGroupNameHistoryStore(client redis.UniversalClient) *RedisGroupNameHistoryStore {
+	return &RedisGroupNameHistoryStore{client: client}
+}
+
+// AddGroupRename records a former name of a group
+func (s *RedisGroupNameHistoryStore) AddGroupRename(ctx context.Context, rename models.GroupRename) error {
+	value, err := json.Marshal(rename)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, groupRenamesKeyPrefix+rename.GroupID, value)
+		pipe.HSet(ctx, groupRenamesByNameKeyPrefix+models.CleanString(rename.Name), rename.GroupID, value)
+		return nil
+	})
+	return err
+}
+
+// RemoveGroupRename forgets the latest rename of the group matching the given one. The name's index is pointed back at
+// the group's remaining latest rename away from the same name, if it has one.
+func (s *RedisGroupNameHistoryStore) RemoveGroupRename(ctx context.Context, rename models.GroupRename) error {
+	key := groupRenamesKeyPrefix + rename.GroupID
+	byNameKey := groupRenamesByNameKeyPrefix + models.CleanString(rename.Name)
+
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		values, err := tx.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		renames := make([]models.GroupRename, len(values))
+		for i, value := range values {
+			if renames[i], err = decodeGroupRename(rename.GroupID, value); err != nil {
+				return err
+			}
+		}
+
+		removed := -1
+		for i := len(renames) - 1; i >= 0; i-- {
+			if renames[i].Name == rename.Name && renames[i].Renamed.Equal(rename.Renamed) {
+				removed = i
+				break
+			}
+		}
+		if removed < 0 {
+			return nil
+		}
+
+		// the name's index holds the group's latest rename away from the name
+		latest := ""
+		for i := range renames {
+			if i != removed && models.CleanString(renames[i].Name) == models.CleanString(rename.Name) {
+				latest = values[i]
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// the rename is removed by its position, as an identical rename may have been added more than once
+			pipe.LSet(ctx, key, int64(removed), removedGroupRename)
+			pipe.LRem(ctx, key, 1, removedGroupRename)
+			if latest != "" {
+				pipe.HSet(ctx, byNameKey, rename.GroupID, latest)
+			} else {
+				pipe.HDel(ctx, byNameKey, rename.GroupID)
+			}
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// ListGroupRenames returns the group's former names, oldest first
+func (s *RedisGroupNameHistoryStore) ListGroupRenames(ctx context.Context, groupID string) ([]models.GroupRename, error) {
+	return listGroupRenames(ctx, s.client, groupID)
+}
+
+func listGroupRenames(ctx context.Context, client redis.Cmdable, groupID string) ([]models.GroupRename, error) {
+	values, err := client.LRange(ctx, groupRenamesKeyPrefix+groupID, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	renames := make([]models.GroupRename, 0, len(values))
+	for _, value := range values {
+		rename, err := decodeGroupRename(groupID, value)
+		if err != nil {
+			return nil, err
+		}
+		renames = append(renames, rename)
+	}
+	return renames, nil
+}
+
+// FindGroupRename returns the most recent rename away from a name matching the given one
+func (s *RedisGroupNameHistoryStore) FindGroupRename(ctx context.Context, name string) (*models.GroupRename, error) {
+	values, err := s.client.HGetAll(ctx, groupRenamesByNameKeyPrefix+models.CleanString(name)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.GroupRename
+	for groupID, value := range values {
+		rename, err := decodeGroupRename(groupID, value)
+		if err != nil {
+			return nil, err
+		}
+		if found == nil || rename.Renamed.After(found.Renamed) {
+			found = &rename
+		}
+	}
+	return found, nil
+}
+
+// DeleteGroupRenames removes the group's former names
+func (s *RedisGroupNameHistoryStore) DeleteGroupRenames(ctx context.Context, groupID string) error {
+	key := groupRenamesKeyPrefix + groupID
+
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		renames, err := listGroupRenames(ctx, tx, groupID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := range renames {
+				pipe.HDel(ctx, groupRenamesByNameKeyPrefix+models.CleanString(renames[i].Name), groupID)
+			}
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func decodeGroupRename(groupID, value string) (models.GroupRename, error) {
+	rename := models.GroupRename{}
+	err := json.Unmarshal([]byte(value), &rename)
+	rename.GroupID = groupID
+	return rename, err
+}
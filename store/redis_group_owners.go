@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const groupOwnersKeyPrefix = RedisKeyPrefix + "group-owners:"
+
+// RedisGroupOwnerStore is a GroupOwnerStore shared by every instance of the API, holding each group's owners in a set
+type RedisGroupOwnerStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisGroupOwnerStore returns a RedisGroupOwnerStore using the given client
+func NewRedisGroupOwnerStore(client redis.UniversalClient) *RedisGroupOwnerStore {
+	return &RedisGroupOwnerStore{client: client}
+}
+
+// ListGroupOwners returns the IDs of the group's owners in order
+func (s *RedisGroupOwnerStore) ListGroupOwners(ctx context.Context, groupID string) ([]string, error) {
+	owners, err := s.client.SMembers(ctx, groupOwnersKeyPrefix+groupID).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+// IsGroupOwner reports whether the user owns the group
+func (s *RedisGroupOwnerStore) IsGroupOwner(ctx context.Context, groupID, userID string) (bool, error) {
+	return s.client.SIsMember(ctx, groupOwnersKeyPrefix+groupID, userID).Result()
+}
+
+// AddGroupOwner makes the user an owner of the group
+func (s *RedisGroupOwnerStore) AddGroupOwner(ctx context.Context, groupID, userID string) error {
+	return s.client.SAdd(ctx, groupOwnersKeyPrefix+groupID, userID).Err()
+}
+
+// RemoveGroupOwner removes the user from the group's owners, reporting whether they were one
+func (s *RedisGroupOwnerStore) RemoveGroupOwner(ctx context.Context, groupID, userID string) (bool, error) {
+	removed, err := s.client.SRem(ctx, groupOwnersKeyPrefix+groupID, userID).Result()
+	return removed > 0, err
+}
+
+// DeleteGroupOwners removes all of the group's owners
+func (s *RedisGroupOwnerStore) DeleteGroupOwners(ctx context.Context, groupID string) error {
+	return s.client.Del(ctx, groupOwnersKeyPrefix+groupID).Err()
+}
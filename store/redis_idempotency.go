@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyKeyPrefix = RedisKeyPrefix + "idempotency:"
+
+// RedisIdempotencyStore is an IdempotencyStore shared by every instance of the API, so that a repeated request is
+// recognised whichever instance it reaches. Each key is held as a JSON record that Redis expires, soon while its request
+// is in progress and after the window once it has completed.
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisIdempotencyStore returns a RedisIdempotencyStore using the given client
+func NewRedisIdempotencyStore(client redis.UniversalClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+// redisIdempotencyRecord is how an IdempotencyRecord is held in Redis
+type redisIdempotencyRecord struct {
+	RequestHash string                   `json:"request_hash"`
+	Response    *redisIdempotentResponse `json:"response,omitempty"`
+}
+
+type redisIdempotentResponse struct {
+	Status  int               `json:"status"`
+	Body    []byte            `json:"body"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Start records that a request with the key is in progress, unless the key is in use and has not expired
+func (s *RedisIdempotencyStore) Start(ctx context.Context, key, requestHash string, inProgressTTL time.Duration) (record IdempotencyRecord, started bool, err error) {
+	value, err := json.Marshal(redisIdempotencyRecord{RequestHash: requestHash})
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+
+	for attempt := 0; attempt < maxRedisTransactionAttempts; attempt++ {
+		started, err := s.client.SetNX(ctx, idempotencyKeyPrefix+key, value, inProgressTTL).Result()
+		if err != nil || started {
+			return IdempotencyRecord{}, started, err
+		}
+
+		record, err := s.get(ctx, key)
+		if errors.Is(err, redis.Nil) {
+			// the key expired or was released since it was found in use, so it can be started again
+			continue
+		}
+		return record, false, err
+	}
+	return IdempotencyRecord{}, false, redis.TxFailedErr
+}
+
+// Complete saves the response to the request started with the key, keeping the key for the window
+func (s *RedisIdempotencyStore) Complete(ctx context.Context, key string, response models.SuccessResponse, window time.Duration) error {
+	record, err := s.get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(redisIdempotencyRecord{
+		RequestHash: record.RequestHash,
+		Response:    &redisIdempotentResponse{Status: response.Status, Body: response.Body, Headers: response.Headers},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.client.SetArgs(ctx, idempotencyKeyPrefix+key, value, redis.SetArgs{Mode: "XX", TTL: window}).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// Release forgets the key
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, idempotencyKeyPrefix+key).Err()
+}
+
+// get returns the record held for the key, or redis.Nil when there is none
+func (s *RedisIdempotencyStore) get(ctx context.Context, key string) (IdempotencyRecord, error) {
+	value, err := s.client.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if err != nil {
+		return IdempotencyRecord{}, err
+	}
+
+	var stored redisIdempotencyRecord
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return IdempotencyRecord{}, err
+	}
+
+	record := IdempotencyRecord{RequestHash: stored.RequestHash}
+	if stored.Response != nil {
+		record.Response = &models.SuccessResponse{
+			Status:  stored.Response.Status,
+			Body:    stored.Response.Body,
+			Headers: stored.Response.Headers,
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-identity-api/v2/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	inProgressTTL, window := time.Minute, time.Hour
+
+	Convey("Given a Redis idempotency store with a key in progress", t, func() {
+		server, client := newTestRedisClient(t)
+		idempotencyStore := NewRedisIdempotencyStore(client)
+
+		_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-1", inProgressTTL)
+		So(err, ShouldBeNil)
+		So(started, ShouldBeTrue)
+
+		Convey("The key cannot be started again, including by another instance of the API", func() {
+			record, started, err := NewRedisIdempotencyStore(client).Start(ctx, "key-1", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeFalse)
+			So(record, ShouldResemble, IdempotencyRecord{RequestHash: "hash-1"})
+
+			Convey("Until its request has had time to complete", func() {
+				server.FastForward(inProgressTTL)
+				_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+				So(err, ShouldBeNil)
+				So(started, ShouldBeTrue)
+			})
+		})
+
+		Convey("A completed key returns the saved response", func() {
+			response := models.SuccessResponse{Body: []byte(`{"id":"1"}`), Status: http.StatusCreated, Headers: map[string]string{"ETag": "abc"}}
+			So(idempotencyStore.Complete(ctx, "key-1", response, window), ShouldBeNil)
+			So(server.TTL(idempotencyKeyPrefix+"key-1"), ShouldEqual, window)
+
+			server.FastForward(inProgressTTL)
+			record, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeFalse)
+			So(record.RequestHash, ShouldEqual, "hash-1")
+			So(*record.Response, ShouldResemble, response)
+
+			Convey("Until the window has passed", func() {
+				server.FastForward(window)
+				_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+				So(err, ShouldBeNil)
+				So(started, ShouldBeTrue)
+			})
+		})
+
+		Convey("Completing a key that has expired does not bring it back", func() {
+			server.FastForward(inProgressTTL)
+			So(idempotencyStore.Complete(ctx, "key-1", models.SuccessResponse{Status: http.StatusCreated}, window), ShouldBeNil)
+			_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-2", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("A released key can be started again", func() {
+			So(idempotencyStore.Release(ctx, "key-1"), ShouldBeNil)
+			_, started, err := idempotencyStore.Start(ctx, "key-1", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("Other keys are not affected", func() {
+			_, started, err := idempotencyStore.Start(ctx, "key-2", "hash-1", inProgressTTL)
+			So(err, ShouldBeNil)
+			So(started, ShouldBeTrue)
+		})
+
+		Convey("An error is returned when Redis cannot be reached", func() {
+			server.Close()
+			_, _, err := idempotencyStore.Start(ctx, "key-2", "hash-1", inProgressTTL)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
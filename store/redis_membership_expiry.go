@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	membershipExpiriesKey            = RedisKeyPrefix + "membership-expiries"
+	groupMembershipExpiriesKeyPrefix = RedisKeyPrefix + "membership-expiries:group:"
+	userMembershipExpiriesKeyPrefix  = RedisKeyPrefix + "membership-expiries:user:"
+)
+
+// RedisMembershipExpiryStore is a MembershipExpiryStore shared by every instance of the API. Each expiry time is held
+// in a hash for its group and a hash for its user, and the memberships are indexed by when they expire in a sorted set.
+type RedisMembershipExpiryStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisMembershipExpiryStore returns a RedisMembershipExpiryStore using the given client
+func NewRedisMembershipExpiryStore(client redis.UniversalClient) *RedisMembershipExpiryStore {
+	return &RedisMembershipExpiryStore{client: client}
+}
+
+// membershipMember identifies a membership in the sorted set of expiry times
+type membershipMember struct {
+	GroupID string `json:"group_id"`
+	UserID  string `json:"user_id"`
+}
+
+func encodeMembershipMember(groupID, userID string) string {
+	member, _ := json.Marshal(membershipMember{GroupID: groupID, UserID: userID})
+	return string(member)
+}
+
+// SetMembershipExpiry sets the time the user's membership of the group ends
+func (s *RedisMembershipExpiryStore) SetMembershipExpiry(ctx context.Context, groupID, userID string, expiresAt time.Time) error {
+	value := expiresAt.UTC().Format(time.RFC3339Nano)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, groupMembershipExpiriesKeyPrefix+groupID, userID, value)
+		pipe.HSet(ctx, userMembershipExpiriesKeyPrefix+userID, groupID, value)
+		pipe.ZAdd(ctx, membershipExpiriesKey, redis.Z{Score: float64(expiresAt.UnixMilli()), Member: encodeMembershipMember(groupID, userID)})
+		return nil
+	})
+	return err
+}
+
+// ClearMembershipExpiry makes the user's membership of the group permanent
+func (s *RedisMembershipExpiryStore) ClearMembershipExpiry(ctx context.Context, groupID, userID string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, groupMembershipExpiriesKeyPrefix+groupID, userID)
+		pipe.HDel(ctx, userMembershipExpiriesKeyPrefix+userID, groupID)
+		pipe.ZRem(ctx, membershipExpiriesKey, encodeMembershipMember(groupID, userID))
+		return nil
+	})
+	return err
+}
+
+// ClearExpiredMembership clears the membership's expiry only if it is still the one it was listed with. The group's
+// hash is watched so that an expiry set by another instance while it is compared is not cleared.
+func (s *RedisMembershipExpiryStore) ClearExpiredMembership(ctx context.Context, membership MembershipExpiry) (renewed bool, err error) {
+	groupKey := groupMembershipExpiriesKeyPrefix + membership.GroupID
+
+	err = watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		renewed = false
+		value, err := tx.HGet(ctx, groupKey, membership.UserID).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		expiresAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+		if !expiresAt.Equal(membership.ExpiresAt) {
+			renewed = true
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, groupKey, membership.UserID)
+			pipe.HDel(ctx, userMembershipExpiriesKeyPrefix+membership.UserID, membership.GroupID)
+			pipe.ZRem(ctx, membershipExpiriesKey, encodeMembershipMember(membership.GroupID, membership.UserID))
+			return nil
+		})
+		return err
+	}, groupKey)
+	return renewed, err
+}
+
+// ListGroupMembershipExpiries returns the expiry times of the group's temporary members, keyed by user ID
+func (s *RedisMembershipExpiryStore) ListGroupMembershipExpiries(ctx context.Context, groupID string) (map[string]time.Time, error) {
+	return s.listExpiries(ctx, groupMembershipExpiriesKeyPrefix+groupID)
+}
+
+// ListUserMembershipExpiries returns the expiry times of the user's temporary memberships, keyed by group ID
+func (s *RedisMembershipExpiryStore) ListUserMembershipExpiries(ctx context.Context, userID string) (map[string]time.Time, error) {
+	return s.listExpiries(ctx, userMembershipExpiriesKeyPrefix+userID)
+}
+
+func (s *RedisMembershipExpiryStore) listExpiries(ctx context.Context, key string) (map[string]time.Time, error) {
+	values, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	expiries := make(map[string]time.Time, len(values))
+	for id, value := range values {
+		expiresAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+		expiries[id] = expiresAt
+	}
+	return expiries, nil
+}
+
+// ListExpiredMemberships returns the memberships that expired before now, earliest first
+func (s *RedisMembershipExpiryStore) ListExpiredMemberships(ctx context.Context, now time.Time) ([]MembershipExpiry, error) {
+	members, err := s.client.ZRangeByScore(ctx, membershipExpiriesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []MembershipExpiry
+	for _, member := range members {
+		var membership membershipMember
+		if err := json.Unmarshal([]byte(member), &membership); err != nil {
+			return nil, err
+		}
+
+		// the sorted set only holds milliseconds, so the exact time is checked against the group's expiries
+		value, err := s.client.HGet(ctx, groupMembershipExpiriesKeyPrefix+membership.GroupID, membership.UserID).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		expiresAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt.Before(now) {
+			expired = append(expired, MembershipExpiry{GroupID: membership.GroupID, UserID: membership.UserID, ExpiresAt: expiresAt})
+		}
+	}
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	return expired, nil
+}
+
+// DeleteGroupMembershipExpiries removes the expiry times of all of the group's memberships
+func (s *RedisMembershipExpiryStore) DeleteGroupMembershipExpiries(ctx context.Context, groupID string) error {
+	groupKey := groupMembershipExpiriesKeyPrefix + groupID
+
+	// the group's hash is watched so that an expiry set while its members are read is not left behind
+	return watchAndRetry(ctx, s.client, func(tx *redis.Tx) error {
+		userIDs, err := tx.HKeys(ctx, groupKey).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, groupKey)
+			for _, userID := range userIDs {
+				pipe.HDel(ctx, userMembershipExpiriesKeyPrefix+userID, groupID)
+				pipe.ZRem(ctx, membershipExpiriesKey, encodeMembershipMember(groupID, userID))
+			}
+			return nil
+		})
+		return err
+	}, groupKey)
+}
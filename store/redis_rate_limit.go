@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = RedisKeyPrefix + "rate-limit:"
+
+// takeTokenScript refills a token bucket for the time passed since it was last used and takes a token from it, in one
+// step so that instances of the API taking from the same bucket at once cannot both have the last token. The bucket
+// expires once it would have refilled, as it is then no different to a new bucket.
+var takeTokenScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) / interval)
+
+local allowed = 0
+local retryAfter = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retryAfter = math.ceil((1 - tokens) * interval)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * interval))
+return {allowed, retryAfter}
+`)
+
+// RedisRateLimitStore is a RateLimitStore shared by every instance of the API, so that the limits hold however many
+// instances there are. Each bucket is a hash of its tokens and when it was last used, in milliseconds by the clocks
+// of the instances.
+type RedisRateLimitStore struct {
+	client redis.UniversalClient
+	now    func() time.Time
+}
+
+// NewRedisRateLimitStore returns a RedisRateLimitStore using the given client
+func NewRedisRateLimitStore(client redis.UniversalClient) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, now: time.Now}
+}
+
+// Take removes a token from the key's bucket, refilling it for the time passed since it was last used
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error) {
+	result, err := takeTokenScript.Run(ctx, s.client, []string{rateLimitKeyPrefix + key},
+		limit.Burst, limit.Interval.Milliseconds(), s.now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedisRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	limit := RateLimit{Burst: 2, Interval: 10 * time.Second}
+
+	Convey("Given a Redis rate limit store with a controllable clock", t, func() {
+		server, client := newTestRedisClient(t)
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		rateLimitStore := NewRedisRateLimitStore(client)
+		rateLimitStore.now = func() time.Time { return now }
+
+		Convey("A key may make up to the burst of requests at once", func() {
+			for i := 0; i < limit.Burst; i++ {
+				allowed, retryAfter, err := rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+				So(retryAfter, ShouldEqual, 0)
+			}
+
+			Convey("Then further requests are refused until a token is refilled, including by another instance of the API", func() {
+				otherInstance := NewRedisRateLimitStore(client)
+				otherInstance.now = func() time.Time { return now }
+
+				now = now.Add(4 * time.Second)
+				allowed, retryAfter, err := otherInstance.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeFalse)
+				So(retryAfter, ShouldEqual, 6*time.Second)
+
+				now = now.Add(6 * time.Second)
+				allowed, _, err = rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+			})
+
+			Convey("Then other keys are not affected", func() {
+				allowed, _, err := rateLimitStore.Take(ctx, "ip:10.0.0.1", limit)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+			})
+
+			Convey("Then the bucket expires once it would have refilled", func() {
+				So(server.TTL(rateLimitKeyPrefix+"ip:127.0.0.1"), ShouldEqual, limit.Interval*time.Duration(limit.Burst))
+			})
+		})
+
+		Convey("An error is returned when Redis cannot be reached", func() {
+			server.Close()
+			_, _, err := rateLimitStore.Take(ctx, "ip:127.0.0.1", limit)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
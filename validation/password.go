@@ -0,0 +1,201 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the hash used by published breached password lists, not for storage
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// PasswordViolation identifies a password policy rule that a password breaks
+type PasswordViolation string
+
+const (
+	PasswordTooShort         PasswordViolation = "TooShort"
+	PasswordMissingUppercase PasswordViolation = "MissingUppercase"
+	PasswordMissingLowercase PasswordViolation = "MissingLowercase"
+	PasswordMissingNumber    PasswordViolation = "MissingNumber"
+	PasswordMissingSymbol    PasswordViolation = "MissingSymbol"
+	PasswordBannedWord       PasswordViolation = "BannedWord"
+	PasswordPersonalInfo     PasswordViolation = "PersonalInfo"
+	PasswordBreached         PasswordViolation = "Breached"
+)
+
+var (
+	// minimumPersonalInfoLength stops short fragments of a name or email, such as initials, from rejecting passwords
+	minimumPersonalInfoLength = 3
+	breachedHashPrefixLength  = 5
+)
+
+// BreachedPasswordRanges looks up breached password hashes by range, k-anonymity style, so that only the first
+// five characters of a password's SHA-1 hash are ever given to the source
+type BreachedPasswordRanges interface {
+	HashSuffixes(prefix string) (map[string]struct{}, error)
+}
+
+// PasswordPolicy holds the rules a new password must meet, over and above the user pool's own policy
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireNumber    bool
+	RequireSymbol    bool
+	BannedWords      []string
+	BreachedList     BreachedPasswordRanges
+}
+
+// DefaultPasswordPolicy returns a policy matching the minimum length of Cognito's default password policy
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: 8}
+}
+
+// Check returns each rule the password breaks. personalInfo holds the user's own details, such as their name and
+// email address, none of which may appear in the password. An error is only returned if the breached password
+// list could not be searched.
+func (p *PasswordPolicy) Check(password string, personalInfo ...string) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, PasswordTooShort)
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasNumber = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, PasswordMissingUppercase)
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, PasswordMissingLowercase)
+	}
+	if p.RequireNumber && !hasNumber {
+		violations = append(violations, PasswordMissingNumber)
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordMissingSymbol)
+	}
+
+	lowerPassword := strings.ToLower(password)
+	for _, word := range p.BannedWords {
+		if word != "" && strings.Contains(lowerPassword, strings.ToLower(word)) {
+			violations = append(violations, PasswordBannedWord)
+			break
+		}
+	}
+
+	violations = append(violations, p.CheckPersonalInfo(password, personalInfo...)...)
+
+	if p.BreachedList != nil {
+		breached, err := IsPasswordBreached(p.BreachedList, password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, PasswordBreached)
+		}
+	}
+
+	return violations, nil
+}
+
+// CheckPersonalInfo returns PasswordPersonalInfo if the password contains any of the user's details. It is the part of
+// Check that needs the user's own details, for when those are only known once the user has been identified.
+func (p *PasswordPolicy) CheckPersonalInfo(password string, personalInfo ...string) []PasswordViolation {
+	lowerPassword := strings.ToLower(password)
+	for _, info := range personalInfoFragments(personalInfo) {
+		if strings.Contains(lowerPassword, info) {
+			return []PasswordViolation{PasswordPersonalInfo}
+		}
+	}
+	return nil
+}
+
+// personalInfoFragments lower cases the user's details and splits email addresses into the parts of the local
+// part, so that "jane.smith@ons.gov.uk" rejects passwords containing "jane" or "smith"
+func personalInfoFragments(personalInfo []string) []string {
+	var fragments []string
+	add := func(s string) {
+		if len([]rune(s)) >= minimumPersonalInfoLength {
+			fragments = append(fragments, s)
+		}
+	}
+
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		add(info)
+		localPart, _, isEmail := strings.Cut(info, "@")
+		if !isEmail {
+			continue
+		}
+		add(localPart)
+		for _, part := range strings.FieldsFunc(localPart, func(r rune) bool { return r == '.' || r == '_' || r == '-' || r == '+' }) {
+			add(part)
+		}
+	}
+	return fragments
+}
+
+// IsPasswordBreached reports whether the password's SHA-1 hash is in the breached password ranges
+func IsPasswordBreached(ranges BreachedPasswordRanges, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password)) //nolint:gosec // see import
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := ranges.HashSuffixes(hash[:breachedHashPrefixLength])
+	if err != nil {
+		return false, err
+	}
+	_, found := suffixes[hash[breachedHashPrefixLength:]]
+	return found, nil
+}
+
+// BreachedPasswordList is a local breached password list, held as SHA-1 hash suffixes grouped by their prefix
+type BreachedPasswordList struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswordList reads a breached password list with one hex encoded SHA-1 hash per line. Anything after
+// a colon, such as the occurrence counts in published lists, and blank lines are ignored.
+func LoadBreachedPasswordList(r io.Reader) (*BreachedPasswordList, error) {
+	list := &BreachedPasswordList{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(scanner.Text(), ":")
+		hash = strings.ToUpper(strings.TrimSpace(hash))
+		if hash == "" {
+			continue
+		}
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, errors.New("breached password list contains an invalid SHA-1 hash: " + hash)
+		}
+
+		prefix, suffix := hash[:breachedHashPrefixLength], hash[breachedHashPrefixLength:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = map[string]struct{}{}
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// HashSuffixes returns the hash suffixes in the list that share the given prefix
+func (l *BreachedPasswordList) HashSuffixes(prefix string) (map[string]struct{}, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// SHA-1 of "Password1!"
+const breachedPasswordHash = "32CA9FC1A0F5B6330E3F4C8C1BBECDE9BEDB9573"
+
+type failingRanges struct{}
+
+func (failingRanges) HashSuffixes(_ string) (map[string]struct{}, error) {
+	return nil, errors.New("range lookup failed")
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	Convey("The default policy only enforces the minimum length", t, func() {
+		policy := DefaultPasswordPolicy()
+
+		violations, err := policy.Check("password")
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+
+		violations, err = policy.Check("pass")
+		So(err, ShouldBeNil)
+		So(violations, ShouldResemble, []PasswordViolation{PasswordTooShort})
+	})
+
+	Convey("A policy requiring character classes reports each missing class", t, func() {
+		policy := &PasswordPolicy{
+			MinLength:        8,
+			RequireUppercase: true,
+			RequireLowercase: true,
+			RequireNumber:    true,
+			RequireSymbol:    true,
+		}
+
+		violations, err := policy.Check("aaaaaaaa")
+		So(err, ShouldBeNil)
+		So(violations, ShouldResemble, []PasswordViolation{PasswordMissingUppercase, PasswordMissingNumber, PasswordMissingSymbol})
+
+		violations, err = policy.Check("AAAA1111")
+		So(err, ShouldBeNil)
+		So(violations, ShouldResemble, []PasswordViolation{PasswordMissingLowercase, PasswordMissingSymbol})
+
+		violations, err = policy.Check("Aa1!Aa1!")
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+	})
+
+	Convey("A password containing a banned word, in any case, is rejected", t, func() {
+		policy := &PasswordPolicy{MinLength: 8, BannedWords: []string{"", "ons", "statistics"}}
+
+		violations, err := policy.Check("MyStatistics123")
+		So(err, ShouldBeNil)
+		So(violations, ShouldResemble, []PasswordViolation{PasswordBannedWord})
+
+		violations, err = policy.Check("correct-horse")
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+	})
+
+	Convey("A password containing the user's own details is rejected", t, func() {
+		policy := DefaultPasswordPolicy()
+		personalInfo := []string{"Jane", "Smith", "jane.smith-jones@ons.gov.uk"}
+
+		for _, password := range []string{"JANE-password", "password-smith", "jones2024!!", "jane.smith-jones@ons.gov.uk"} {
+			violations, err := policy.Check(password, personalInfo...)
+			So(err, ShouldBeNil)
+			So(violations, ShouldResemble, []PasswordViolation{PasswordPersonalInfo})
+		}
+
+		Convey("but fragments shorter than three characters are ignored", func() {
+			violations, err := policy.Check("carrot-cake", "Al", "al.x@ons.gov.uk")
+			So(err, ShouldBeNil)
+			So(violations, ShouldBeEmpty)
+		})
+	})
+
+	Convey("A password found in the breached password list is rejected", t, func() {
+		list, err := LoadBreachedPasswordList(strings.NewReader(breachedPasswordHash + ":12\n"))
+		So(err, ShouldBeNil)
+		policy := &PasswordPolicy{MinLength: 8, BreachedList: list}
+
+		violations, err := policy.Check("Password1!")
+		So(err, ShouldBeNil)
+		So(violations, ShouldResemble, []PasswordViolation{PasswordBreached})
+
+		violations, err = policy.Check("Password2!")
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+	})
+
+	Convey("An error searching the breached password list is returned", t, func() {
+		policy := &PasswordPolicy{MinLength: 8, BreachedList: failingRanges{}}
+
+		violations, err := policy.Check("Password1!")
+		So(err, ShouldNotBeNil)
+		So(violations, ShouldBeNil)
+	})
+}
+
+func TestPasswordPolicy_CheckPersonalInfo(t *testing.T) {
+	Convey("Only the user's own details are checked, not the rest of the policy", t, func() {
+		policy := &PasswordPolicy{MinLength: 20, RequireSymbol: true, BreachedList: failingRanges{}}
+
+		So(policy.CheckPersonalInfo("smith1", "Jane", "Smith"), ShouldResemble, []PasswordViolation{PasswordPersonalInfo})
+		So(policy.CheckPersonalInfo("Password1!", "Jane", "Smith", "jane.smith@ons.gov.uk"), ShouldBeEmpty)
+	})
+}
+
+func TestLoadBreachedPasswordList(t *testing.T) {
+	Convey("A list of hashes with counts and blank lines is grouped by hash prefix", t, func() {
+		list, err := LoadBreachedPasswordList(strings.NewReader("\n" + strings.ToLower(breachedPasswordHash) + "\n\n"))
+		So(err, ShouldBeNil)
+
+		suffixes, err := list.HashSuffixes(breachedPasswordHash[:5])
+		So(err, ShouldBeNil)
+		So(suffixes, ShouldContainKey, breachedPasswordHash[5:])
+
+		suffixes, err = list.HashSuffixes("00000")
+		So(err, ShouldBeNil)
+		So(suffixes, ShouldBeEmpty)
+	})
+
+	Convey("A line that is not a SHA-1 hash is an error", t, func() {
+		list, err := LoadBreachedPasswordList(strings.NewReader("not-a-hash\n"))
+		So(err, ShouldNotBeNil)
+		So(list, ShouldBeNil)
+	})
+}